@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/version"
+)
+
+// buildInfo exports the build of the exporter together with the version of the
+// target cluster, received as a clusterinfo consumer. It replaces the build info
+// of the version package, which has the same name, and keeps its labels.
+type buildInfo struct {
+	clusterInfoCh   chan *clusterinfo.Response
+	mu              sync.Mutex
+	lastClusterInfo *clusterinfo.Response
+
+	desc *prometheus.Desc
+}
+
+func newBuildInfo() *buildInfo {
+	b := &buildInfo{
+		clusterInfoCh: make(chan *clusterinfo.Response),
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(Name, "", "build_info"),
+			"A metric with a constant '1' value labeled by version, revision, branch, and goversion from which "+Name+" was built, and the es_version and es_distribution of the target cluster, empty until the cluster info is retrieved.",
+			[]string{"version", "revision", "branch", "goversion", "es_version", "es_distribution"}, nil,
+		),
+	}
+
+	// start go routine to fetch clusterinfo updates and save them to lastClusterInfo,
+	// it ends once the retriever of the scrape closes the channel
+	go func() {
+		for ci := range b.clusterInfoCh {
+			b.update(ci)
+		}
+	}()
+	return b
+}
+
+func (b *buildInfo) update(ci *clusterinfo.Response) {
+	if ci == nil {
+		return
+	}
+	b.mu.Lock()
+	b.lastClusterInfo = ci
+	b.mu.Unlock()
+}
+
+// ClusterLabelUpdates returns a pointer to a channel to receive cluster info updates. It implements the
+// (not exported) clusterinfo.consumer interface
+func (b *buildInfo) ClusterLabelUpdates() *chan *clusterinfo.Response {
+	return &b.clusterInfoCh
+}
+
+// String implements the stringer interface. It is part of the clusterinfo.consumer interface
+func (b *buildInfo) String() string {
+	return Name + "buildinfo"
+}
+
+// Describe implements the prometheus.Collector interface
+func (b *buildInfo) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.desc
+}
+
+// Collect implements the prometheus.Collector interface
+func (b *buildInfo) Collect(ch chan<- prometheus.Metric) {
+	var esVersion, esDistribution string
+	b.mu.Lock()
+	if b.lastClusterInfo != nil {
+		esVersion = b.lastClusterInfo.Version.Number.String()
+		esDistribution = distribution(b.lastClusterInfo)
+	}
+	b.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(
+		b.desc,
+		prometheus.GaugeValue,
+		1,
+		version.Version, version.Revision, version.Branch, version.GoVersion, esVersion, esDistribution,
+	)
+}
+
+// distribution returns the distribution of the cluster, forks like opensearch
+// report theirs and Elasticsearch none
+func distribution(ci *clusterinfo.Response) string {
+	if ci.Version.Distribution == "" {
+		return "elasticsearch"
+	}
+	return ci.Version.Distribution
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/version"
+)
+
+func TestBuildInfo(t *testing.T) {
+	defer func(v, r, b string) {
+		version.Version, version.Revision, version.Branch = v, r, b
+	}(version.Version, version.Revision, version.Branch)
+	version.Version, version.Revision, version.Branch = "1.2.0", "4a1d2c9", "master"
+
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 IMAGE:VERSION
+	//  curl http://localhost:9200/
+	tcs := map[string]struct {
+		out            string
+		esVersion      string
+		esDistribution string
+	}{
+		"elasticsearch 7.6.2": {`{"name":"es01","cluster_name":"elasticsearch","cluster_uuid":"3qps7bcWTqyzV49ApmPVfw","version":{"number":"7.6.2","build_flavor":"default","build_type":"docker","build_hash":"ef48eb35cf30adf4db14086e8aabd07ef6fb113f","build_date":"2020-03-26T06:34:37.794943Z","build_snapshot":false,"lucene_version":"8.4.0","minimum_wire_compatibility_version":"6.8.0","minimum_index_compatibility_version":"6.0.0-beta1"},"tagline":"You Know, for Search"}`, "7.6.2", "elasticsearch"},
+		"opensearch 1.2.4":    {`{"name":"opensearch-node1","cluster_name":"opensearch-cluster","cluster_uuid":"Vd7g0QQDSvOLiHcnN6yW6Q","version":{"distribution":"opensearch","number":"1.2.4","build_type":"tar","build_hash":"e505b10357c03ae8d26d675172402f2f2144ef0f","build_date":"2022-01-14T03:38:06.881862Z","build_snapshot":false,"lucene_version":"8.10.1","minimum_wire_compatibility_version":"6.8.0","minimum_index_compatibility_version":"6.0.0-beta1"},"tagline":"The OpenSearch Project: https://opensearch.org/"}`, "1.2.4", "opensearch"},
+	}
+	for name, tc := range tcs {
+		var ci clusterinfo.Response
+		if err := json.Unmarshal([]byte(tc.out), &ci); err != nil {
+			t.Fatalf("[%s] Failed to decode cluster info: %s", name, err)
+		}
+		b := newBuildInfo()
+
+		// until the cluster info is retrieved only the exporter build is known
+		expected := `
+# HELP elasticsearch_exporter_build_info A metric with a constant '1' value labeled by version, revision, branch, and goversion from which elasticsearch_exporter was built, and the es_version and es_distribution of the target cluster, empty until the cluster info is retrieved.
+# TYPE elasticsearch_exporter_build_info gauge
+elasticsearch_exporter_build_info{branch="master",es_distribution="%s",es_version="%s",goversion="%s",revision="4a1d2c9",version="1.2.0"} 1
+`
+		if err := testutil.CollectAndCompare(b, strings.NewReader(fmt.Sprintf(expected, "", "", runtime.Version()))); err != nil {
+			t.Errorf("[%s] Unexpected build info before the cluster info: %s", name, err)
+		}
+
+		// the second update is only received once the first one is saved
+		*b.ClusterLabelUpdates() <- &ci
+		*b.ClusterLabelUpdates() <- nil
+		if err := testutil.CollectAndCompare(b, strings.NewReader(fmt.Sprintf(expected, tc.esDistribution, tc.esVersion, runtime.Version()))); err != nil {
+			t.Errorf("[%s] Unexpected build info: %s", name, err)
+		}
+	}
+}
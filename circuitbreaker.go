@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var targetCircuitOpen = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName("elasticsearch", "exporter", "target_circuit_open"),
+		Help: "Whether scrapes of the target are skipped after repeated failures",
+	},
+	[]string{"target"},
+)
+
+// maxCircuitTargets is the number of failing targets tracked at the same time.
+// The target of a scrape is chosen by the client, so further targets aren't
+// tracked instead of growing the state and the metric without bounds.
+const maxCircuitTargets = 1000
+
+// circuitTargetTTL is the time after its last failed scrape a target is
+// forgotten, so targets which aren't scraped anymore don't pile up
+const circuitTargetTTL = time.Hour
+
+// circuitBreaker stops scraping a target for cooldown after threshold
+// consecutive failed scrapes, so the exporter doesn't add load to a cluster
+// during an outage. After the cooldown a single scrape probes the target
+// again, the others are still skipped. It's shared by all scrapes, like
+// seedURIs.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	open      *prometheus.GaugeVec
+	now       func() time.Time
+
+	mu      sync.Mutex
+	targets map[string]*circuitState
+}
+
+type circuitState struct {
+	failures    int
+	lastFailure time.Time
+	openUntil   time.Time
+	// probing is true while the scrape probing the target after the cooldown
+	// is in flight
+	probing bool
+}
+
+// newCircuitBreaker creates a circuitBreaker. A threshold of zero disables it.
+func newCircuitBreaker(threshold int, cooldown time.Duration, open *prometheus.GaugeVec) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		open:      open,
+		now:       time.Now,
+		targets:   make(map[string]*circuitState),
+	}
+}
+
+// allow returns whether the target may be scraped
+func (cb *circuitBreaker) allow(target string) bool {
+	if cb.threshold <= 0 {
+		return true
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	s, ok := cb.targets[target]
+	if !ok || s.failures < cb.threshold {
+		return true
+	}
+	if s.probing || cb.now().Before(s.openUntil) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// record counts the result of a scrape of the target and opens the circuit if
+// the target failed threshold times in a row. A failed probe after the cooldown
+// opens it again right away.
+func (cb *circuitBreaker) record(target string, failed bool) {
+	if cb.threshold <= 0 {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.now()
+	cb.expire(now)
+	if !failed {
+		cb.forget(target)
+		return
+	}
+	s, ok := cb.targets[target]
+	if !ok {
+		if len(cb.targets) >= maxCircuitTargets {
+			return
+		}
+		s = &circuitState{}
+		cb.targets[target] = s
+	}
+	s.failures++
+	s.lastFailure = now
+	s.probing = false
+	if s.failures >= cb.threshold {
+		s.openUntil = now.Add(cb.cooldown)
+		cb.open.WithLabelValues(target).Set(1)
+	}
+}
+
+// expire forgets the targets which didn't fail within circuitTargetTTL and
+// whose circuit isn't open, cb.mu must be held
+func (cb *circuitBreaker) expire(now time.Time) {
+	for target, s := range cb.targets {
+		if now.Sub(s.lastFailure) > circuitTargetTTL && !now.Before(s.openUntil) {
+			cb.forget(target)
+		}
+	}
+}
+
+// forget removes the state and the metric of target, cb.mu must be held
+func (cb *circuitBreaker) forget(target string) {
+	delete(cb.targets, target)
+	cb.open.DeleteLabelValues(target)
+}
+
+// circuitTarget returns the target label of u without credentials
+func circuitTarget(u *url.URL) string {
+	target := *u
+	target.User = nil
+	return target.String()
+}
+
+// targetDown returns whether a scrape failed completely, i.e. there's no up
+// metric reporting a successful scrape. A single failed collector doesn't
+// count, as the target is still reachable.
+func targetDown(mfs []*dto.MetricFamily) bool {
+	for _, mf := range mfs {
+		if !strings.HasSuffix(mf.GetName(), "_up") || mf.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() == 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
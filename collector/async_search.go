@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// searchAction is the task action of a search. The submit task of an async
+// search returns after wait_for_completion_timeout, while the search itself
+// keeps running as a search task.
+const searchAction = "indices:data/read/search"
+
+// asyncSearchDescriptionPrefix starts the description of the search tasks of
+// async searches
+const asyncSearchDescriptionPrefix = "async_search"
+
+// AsyncSearch information struct
+type AsyncSearch struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	up                              prometheus.Gauge
+	running                         prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+}
+
+// NewAsyncSearch defines Async Search Prometheus metrics
+func NewAsyncSearch(logger log.Logger, client *http.Client, url *url.URL) *AsyncSearch {
+	return &AsyncSearch{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "async_search", "up"),
+			Help: "Was the last scrape of the ElasticSearch tasks endpoint for async searches successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "async_search", "total_scrapes"),
+			Help: "Current total ElasticSearch async search scrapes.",
+		}),
+		running: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "async_search", "running_total"),
+			Help: "Current number of in-progress async searches",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "async_search", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+	}
+}
+
+// Describe add Async Search metrics descriptions
+func (as *AsyncSearch) Describe(ch chan<- *prometheus.Desc) {
+	ch <- as.up.Desc()
+	ch <- as.totalScrapes.Desc()
+	ch <- as.running.Desc()
+	ch <- as.jsonParseFailures.Desc()
+}
+
+func (as *AsyncSearch) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := as.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(as.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		as.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+func (as *AsyncSearch) fetchAndDecodeAsyncSearchTasks() (asyncSearchTasksResponse, error) {
+	u := *as.url
+	u.Path = path.Join(u.Path, "/_tasks")
+	q := u.Query()
+	q.Set("actions", searchAction)
+	q.Set("detailed", "true")
+	u.RawQuery = q.Encode()
+	var astr asyncSearchTasksResponse
+	err := as.getAndParseURL(&u, &astr)
+	return astr, err
+}
+
+// countRunningAsyncSearches counts the search tasks of async searches of all
+// nodes, which are told apart from other searches by their description. The
+// action is checked again in case the filter is ignored.
+func countRunningAsyncSearches(astr asyncSearchTasksResponse) int {
+	var c int
+	for _, node := range astr.Nodes {
+		for _, task := range node.Tasks {
+			if task.Action == searchAction && strings.HasPrefix(task.Description, asyncSearchDescriptionPrefix) {
+				c++
+			}
+		}
+	}
+	return c
+}
+
+// Collect gets Async Search metric values
+func (as *AsyncSearch) Collect(ch chan<- prometheus.Metric) {
+	as.totalScrapes.Inc()
+	defer func() {
+		ch <- as.up
+		ch <- as.totalScrapes
+		ch <- as.jsonParseFailures
+		ch <- as.running
+	}()
+
+	astr, err := as.fetchAndDecodeAsyncSearchTasks()
+	if err != nil {
+		as.running.Set(0)
+		as.up.Set(0)
+		_ = level.Warn(as.logger).Log(
+			"msg", "failed to fetch and decode async search tasks",
+			"err", err,
+		)
+		return
+	}
+	as.up.Set(1)
+
+	as.running.Set(float64(countRunningAsyncSearches(astr)))
+}
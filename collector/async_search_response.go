@@ -0,0 +1,22 @@
+package collector
+
+// asyncSearchTasksResponse is a representation of the Elasticsearch tasks API
+// filtered to search actions
+type asyncSearchTasksResponse struct {
+	Nodes map[string]asyncSearchTasksNodeResponse `json:"nodes"`
+}
+
+// asyncSearchTasksNodeResponse defines the tasks running on a single node
+type asyncSearchTasksNodeResponse struct {
+	Name  string                                  `json:"name"`
+	Tasks map[string]asyncSearchTasksTaskResponse `json:"tasks"`
+}
+
+// asyncSearchTasksTaskResponse defines a single task
+type asyncSearchTasksTaskResponse struct {
+	Node        string `json:"node"`
+	ID          int64  `json:"id"`
+	Type        string `json:"type"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestAsyncSearch(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:VERSION
+	//  curl -XPOST "http://localhost:9200/_async_search?wait_for_completion_timeout=0s&keep_on_completion=true" -d '{"query":{"match_all":{}}}'
+	//  curl -XPOST "http://localhost:9200/logs-1/_search" -d '{"query":{"match_all":{}}}' # a regular search, not counted
+	//  curl "http://localhost:9200/_tasks?actions=indices:data/read/search&detailed=true"
+	tcs := map[string]struct {
+		out  string
+		want int
+	}{
+		"7.7.0": {
+			out:  `{"nodes":{"oxTbPpPWRfWtKZaIy-ndgw":{"name":"es01","transport_address":"172.18.0.2:9300","host":"172.18.0.2","ip":"172.18.0.2:9300","roles":["data","ingest","master","ml","remote_cluster_client","transform"],"tasks":{"oxTbPpPWRfWtKZaIy-ndgw:7411":{"node":"oxTbPpPWRfWtKZaIy-ndgw","id":7411,"type":"transport","action":"indices:data/read/search","description":"async_search{indices[logs-*], search_type[QUERY_THEN_FETCH], source[{\"query\":{\"match_all\":{\"boost\":1.0}}}]}","start_time_in_millis":1590059580000,"running_time_in_nanos":2831427519,"cancellable":true,"headers":{}},"oxTbPpPWRfWtKZaIy-ndgw:7434":{"node":"oxTbPpPWRfWtKZaIy-ndgw","id":7434,"type":"transport","action":"indices:data/read/search","description":"async_search{indices[logs-*], search_type[QUERY_THEN_FETCH], source[{\"query\":{\"match_all\":{\"boost\":1.0}}}]}","start_time_in_millis":1590059581000,"running_time_in_nanos":1831427519,"cancellable":true,"headers":{}},"oxTbPpPWRfWtKZaIy-ndgw:7502":{"node":"oxTbPpPWRfWtKZaIy-ndgw","id":7502,"type":"transport","action":"indices:data/read/search","description":"indices[logs-*], search_type[QUERY_THEN_FETCH], source[{\"query\":{\"match_all\":{\"boost\":1.0}}}]","start_time_in_millis":1590059583000,"running_time_in_nanos":31427519,"cancellable":true,"headers":{}}}},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","transport_address":"172.18.0.3:9300","host":"172.18.0.3","ip":"172.18.0.3:9300","roles":["data","ingest","master","ml","remote_cluster_client","transform"],"tasks":{"Xn1qcbFcQdShCM3GNQoKFw:918":{"node":"Xn1qcbFcQdShCM3GNQoKFw","id":918,"type":"transport","action":"indices:data/read/search","description":"async_search{indices[logs-*], search_type[QUERY_THEN_FETCH], source[{\"query\":{\"match_all\":{\"boost\":1.0}}}]}","start_time_in_millis":1590059582000,"running_time_in_nanos":831427519,"cancellable":true,"headers":{}}}}}}`,
+			want: 3,
+		},
+		"7.7.0-idle": {
+			out:  `{"nodes":{}}`,
+			want: 0,
+		},
+	}
+	for ver, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("actions") != searchAction || r.URL.Query().Get("detailed") != "true" {
+				t.Errorf("Wrong tasks filter: %s", r.URL.RawQuery)
+			}
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		as := NewAsyncSearch(log.NewNopLogger(), http.DefaultClient, u)
+		astr, err := as.fetchAndDecodeAsyncSearchTasks()
+		if err != nil {
+			t.Fatalf("Failed to fetch or decode async search tasks: %s", err)
+		}
+		t.Logf("[%s] Async Search Tasks Response: %+v", ver, astr)
+		if got := countRunningAsyncSearches(astr); got != tc.want {
+			t.Errorf("[%s] Wrong number of running async searches: got %d, want %d", ver, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unassignedNode is the name of the pseudo node _cat/allocation lists unassigned shards under
+const unassignedNode = "UNASSIGNED"
+
+type catAllocationMetric struct {
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(node catAllocationNodeResponse) string
+}
+
+var (
+	defaultCatAllocationLabels = []string{"node"}
+)
+
+// CatAllocation information struct
+type CatAllocation struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	up                              prometheus.Gauge
+	unassignedShards                prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	metrics []*catAllocationMetric
+}
+
+// NewCatAllocation defines Cat Allocation Prometheus metrics
+func NewCatAllocation(logger log.Logger, client *http.Client, url *url.URL) *CatAllocation {
+	return &CatAllocation{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "cat_allocation", "up"),
+			Help: "Was the last scrape of the ElasticSearch cat allocation endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cat_allocation", "total_scrapes"),
+			Help: "Current total ElasticSearch cat allocation scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cat_allocation", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+		unassignedShards: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "cat_allocation", "unassigned_shards"),
+			Help: "Number of shards not allocated to any node",
+		}),
+		metrics: []*catAllocationMetric{
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "cat_allocation", "shards"),
+					"Number of shards allocated to the node",
+					defaultCatAllocationLabels, nil,
+				),
+				Value: func(node catAllocationNodeResponse) string {
+					return node.Shards
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "cat_allocation", "disk_used_bytes"),
+					"Disk space used on the node in bytes",
+					defaultCatAllocationLabels, nil,
+				),
+				Value: func(node catAllocationNodeResponse) string {
+					return node.DiskUsed
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "cat_allocation", "disk_avail_bytes"),
+					"Disk space available on the node in bytes",
+					defaultCatAllocationLabels, nil,
+				),
+				Value: func(node catAllocationNodeResponse) string {
+					return node.DiskAvail
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "cat_allocation", "disk_total_bytes"),
+					"Total disk space of the node in bytes",
+					defaultCatAllocationLabels, nil,
+				),
+				Value: func(node catAllocationNodeResponse) string {
+					return node.DiskTotal
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "cat_allocation", "disk_percent"),
+					"Percentage of the disk space used on the node",
+					defaultCatAllocationLabels, nil,
+				),
+				Value: func(node catAllocationNodeResponse) string {
+					return node.DiskPercent
+				},
+			},
+		},
+	}
+}
+
+// Describe add Cat Allocation metrics descriptions
+func (c *CatAllocation) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.Desc
+	}
+	ch <- c.unassignedShards.Desc()
+	ch <- c.up.Desc()
+	ch <- c.totalScrapes.Desc()
+	ch <- c.jsonParseFailures.Desc()
+}
+
+func (c *CatAllocation) fetchAndDecodeCatAllocation() (catAllocationResponse, error) {
+	var car catAllocationResponse
+
+	u := *c.url
+	u.Path = path.Join(u.Path, "/_cat/allocation")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("bytes", "b")
+	u.RawQuery = q.Encode()
+
+	res, err := c.client.Get(u.String())
+	if err != nil {
+		return car, fmt.Errorf("failed to get cat allocation from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(c.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return car, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&car); err != nil {
+		c.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return car, err
+	}
+	return car, nil
+}
+
+// Collect gets Cat Allocation metric values
+func (c *CatAllocation) Collect(ch chan<- prometheus.Metric) {
+	c.totalScrapes.Inc()
+	defer func() {
+		ch <- c.up
+		ch <- c.totalScrapes
+		ch <- c.jsonParseFailures
+	}()
+
+	car, err := c.fetchAndDecodeCatAllocation()
+	if err != nil {
+		c.up.Set(0)
+		_ = level.Warn(c.logger).Log(
+			"msg", "failed to fetch and decode cat allocation",
+			"err", err,
+		)
+		return
+	}
+	c.up.Set(1)
+
+	var unassigned float64
+	for _, node := range car {
+		// the UNASSIGNED pseudo node has no disk values and would
+		// otherwise be mistaken for a real node
+		if node.Node == unassignedNode {
+			shards, err := strconv.ParseFloat(node.Shards, 64)
+			if err == nil {
+				unassigned += shards
+			}
+			continue
+		}
+		for _, metric := range c.metrics {
+			value, err := strconv.ParseFloat(metric.Value(node), 64)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				value,
+				node.Node,
+			)
+		}
+	}
+	c.unassignedShards.Set(unassigned)
+	ch <- c.unassignedShards
+}
@@ -0,0 +1,18 @@
+package collector
+
+// catAllocationResponse is a representation of the _cat/allocation API with bytes=b
+type catAllocationResponse []catAllocationNodeResponse
+
+// catAllocationNodeResponse defines the disk allocation of a single node. Unassigned
+// shards are listed as a node named UNASSIGNED without any disk values.
+type catAllocationNodeResponse struct {
+	Shards      string `json:"shards"`
+	DiskIndices string `json:"disk.indices"`
+	DiskUsed    string `json:"disk.used"`
+	DiskAvail   string `json:"disk.avail"`
+	DiskTotal   string `json:"disk.total"`
+	DiskPercent string `json:"disk.percent"`
+	Host        string `json:"host"`
+	IP          string `json:"ip"`
+	Node        string `json:"node"`
+}
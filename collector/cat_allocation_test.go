@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCatAllocation(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_cat/allocation?format=json&bytes=b"
+	tcs := map[string]string{
+		"7.3.0": `[{"shards":"2","disk.indices":"1000000000","disk.used":"2000000000","disk.avail":"8000000000","disk.total":"10000000000","disk.percent":"20","host":"172.17.0.2","ip":"172.17.0.2","node":"es01"},{"shards":"3","disk.indices":"1500000000","disk.used":"5000000000","disk.avail":"5000000000","disk.total":"10000000000","disk.percent":"50","host":"172.17.0.3","ip":"172.17.0.3","node":"es02"},{"shards":"1","disk.indices":null,"disk.used":null,"disk.avail":null,"disk.total":null,"disk.percent":null,"host":null,"ip":null,"node":"UNASSIGNED"}]`,
+	}
+	expected := `
+# HELP elasticsearch_cat_allocation_disk_percent Percentage of the disk space used on the node
+# TYPE elasticsearch_cat_allocation_disk_percent gauge
+elasticsearch_cat_allocation_disk_percent{node="es01"} 20
+elasticsearch_cat_allocation_disk_percent{node="es02"} 50
+# HELP elasticsearch_cat_allocation_disk_total_bytes Total disk space of the node in bytes
+# TYPE elasticsearch_cat_allocation_disk_total_bytes gauge
+elasticsearch_cat_allocation_disk_total_bytes{node="es01"} 1e+10
+elasticsearch_cat_allocation_disk_total_bytes{node="es02"} 1e+10
+# HELP elasticsearch_cat_allocation_shards Number of shards allocated to the node
+# TYPE elasticsearch_cat_allocation_shards gauge
+elasticsearch_cat_allocation_shards{node="es01"} 2
+elasticsearch_cat_allocation_shards{node="es02"} 3
+# HELP elasticsearch_cat_allocation_unassigned_shards Number of shards not allocated to any node
+# TYPE elasticsearch_cat_allocation_unassigned_shards gauge
+elasticsearch_cat_allocation_unassigned_shards 1
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewCatAllocation(log.NewNopLogger(), http.DefaultClient, u)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+			"elasticsearch_cat_allocation_disk_percent",
+			"elasticsearch_cat_allocation_disk_total_bytes",
+			"elasticsearch_cat_allocation_shards",
+			"elasticsearch_cat_allocation_unassigned_shards",
+		); err != nil {
+			t.Errorf("[%s] Unexpected cat allocation metrics: %s", ver, err)
+		}
+	}
+}
@@ -16,6 +16,17 @@ const (
 	namespace = "elasticsearch"
 )
 
+// Levels of the cluster health
+const (
+	// ClusterHealthLevelCluster only reports the health of the cluster
+	ClusterHealthLevelCluster = "cluster"
+	// ClusterHealthLevelIndices additionally reports the health of every index
+	ClusterHealthLevelIndices = "indices"
+	// ClusterHealthLevelShards additionally reports the health of every shard.
+	// Only the health of the indices is exported.
+	ClusterHealthLevelShards = "shards"
+)
+
 var (
 	colors                     = []string{"green", "yellow", "red"}
 	defaultClusterHealthLabels = []string{"cluster"}
@@ -27,6 +38,12 @@ type clusterHealthMetric struct {
 	Value func(clusterHealth clusterHealthResponse) float64
 }
 
+type clusterHealthIndexMetric struct {
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(indexHealth clusterHealthIndexResponse) float64
+}
+
 type clusterHealthStatusMetric struct {
 	Type   prometheus.ValueType
 	Desc   *prometheus.Desc
@@ -39,22 +56,27 @@ type ClusterHealth struct {
 	logger log.Logger
 	client *http.Client
 	url    *url.URL
+	level  string
 
 	up                              prometheus.Gauge
 	totalScrapes, jsonParseFailures prometheus.Counter
 
-	metrics      []*clusterHealthMetric
-	statusMetric *clusterHealthStatusMetric
+	metrics           []*clusterHealthMetric
+	statusMetric      *clusterHealthStatusMetric
+	indexMetrics      []*clusterHealthIndexMetric
+	indexStatusMetric *prometheus.Desc
 }
 
-// NewClusterHealth returns a new Collector exposing ClusterHealth stats.
-func NewClusterHealth(logger log.Logger, client *http.Client, url *url.URL) *ClusterHealth {
+// NewClusterHealth returns a new Collector exposing ClusterHealth stats. With
+// any level but ClusterHealthLevelCluster, the health of every index is exported.
+func NewClusterHealth(logger log.Logger, client *http.Client, url *url.URL, level string) *ClusterHealth {
 	subsystem := "cluster_health"
 
 	return &ClusterHealth{
 		logger: logger,
 		client: client,
 		url:    url,
+		level:  level,
 
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: prometheus.BuildFQName(namespace, subsystem, "up"),
@@ -206,6 +228,68 @@ func NewClusterHealth(logger log.Logger, client *http.Client, url *url.URL) *Clu
 				return 0
 			},
 		},
+		indexMetrics: []*clusterHealthIndexMetric{
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, subsystem, "index_active_primary_shards"),
+					"The number of active primary shards of the index.",
+					[]string{"index"}, nil,
+				),
+				Value: func(indexHealth clusterHealthIndexResponse) float64 {
+					return float64(indexHealth.ActivePrimaryShards)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, subsystem, "index_active_shards"),
+					"The number of active primary and replica shards of the index.",
+					[]string{"index"}, nil,
+				),
+				Value: func(indexHealth clusterHealthIndexResponse) float64 {
+					return float64(indexHealth.ActiveShards)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, subsystem, "index_initializing_shards"),
+					"The number of shards of the index that are being freshly created.",
+					[]string{"index"}, nil,
+				),
+				Value: func(indexHealth clusterHealthIndexResponse) float64 {
+					return float64(indexHealth.InitializingShards)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, subsystem, "index_relocating_shards"),
+					"The number of shards of the index that are currently moving from one node to another node.",
+					[]string{"index"}, nil,
+				),
+				Value: func(indexHealth clusterHealthIndexResponse) float64 {
+					return float64(indexHealth.RelocatingShards)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, subsystem, "index_unassigned_shards"),
+					"The number of shards of the index that are not allocated to any node.",
+					[]string{"index"}, nil,
+				),
+				Value: func(indexHealth clusterHealthIndexResponse) float64 {
+					return float64(indexHealth.UnassignedShards)
+				},
+			},
+		},
+		indexStatusMetric: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "index_status"),
+			"Whether all primary and replica shards of the index are allocated.",
+			[]string{"index", "status"}, nil,
+		),
 	}
 }
 
@@ -215,6 +299,10 @@ func (c *ClusterHealth) Describe(ch chan<- *prometheus.Desc) {
 		ch <- metric.Desc
 	}
 	ch <- c.statusMetric.Desc
+	for _, metric := range c.indexMetrics {
+		ch <- metric.Desc
+	}
+	ch <- c.indexStatusMetric
 
 	ch <- c.up.Desc()
 	ch <- c.totalScrapes.Desc()
@@ -226,6 +314,11 @@ func (c *ClusterHealth) fetchAndDecodeClusterHealth() (clusterHealthResponse, er
 
 	u := *c.url
 	u.Path = path.Join(u.Path, "/_cluster/health")
+	if c.level != "" && c.level != ClusterHealthLevelCluster {
+		q := u.Query()
+		q.Set("level", c.level)
+		u.RawQuery = q.Encode()
+	}
 	res, err := c.client.Get(u.String())
 	if err != nil {
 		return chr, fmt.Errorf("failed to get cluster health from %s://%s:%s%s: %s",
@@ -248,6 +341,7 @@ func (c *ClusterHealth) fetchAndDecodeClusterHealth() (clusterHealthResponse, er
 
 	if err := json.NewDecoder(res.Body).Decode(&chr); err != nil {
 		c.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
 		return chr, err
 	}
 
@@ -292,4 +386,27 @@ func (c *ClusterHealth) Collect(ch chan<- prometheus.Metric) {
 			clusterHealthResp.ClusterName, color,
 		)
 	}
+
+	for index, indexHealth := range clusterHealthResp.Indices {
+		for _, metric := range c.indexMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(indexHealth),
+				index,
+			)
+		}
+		for _, color := range colors {
+			var value float64
+			if indexHealth.Status == color {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.indexStatusMetric,
+				prometheus.GaugeValue,
+				value,
+				index, color,
+			)
+		}
+	}
 }
@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestClusterHealth(t *testing.T) {
@@ -30,7 +32,7 @@ func TestClusterHealth(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to parse URL: %s", err)
 		}
-		c := NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u)
+		c := NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u, ClusterHealthLevelCluster)
 		chr, err := c.fetchAndDecodeClusterHealth()
 		if err != nil {
 			t.Fatalf("Failed to fetch or decode cluster health: %s", err)
@@ -58,3 +60,45 @@ func TestClusterHealth(t *testing.T) {
 		}
 	}
 }
+
+func TestClusterHealthIndices(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.6.2
+	//  curl -XPUT http://localhost:9200/foo_1 --header "Content-Type: application/json" -d '{"settings":{"number_of_replicas":0}}'
+	//  curl -XPUT http://localhost:9200/foo_2
+	//  curl "http://localhost:9200/_cluster/health?level=indices"
+	out := `{"cluster_name":"elasticsearch","status":"yellow","timed_out":false,"number_of_nodes":1,"number_of_data_nodes":1,"active_primary_shards":2,"active_shards":2,"relocating_shards":0,"initializing_shards":0,"unassigned_shards":1,"delayed_unassigned_shards":0,"number_of_pending_tasks":0,"number_of_in_flight_fetch":0,"task_max_waiting_in_queue_millis":0,"active_shards_percent_as_number":66.66666666666666,"indices":{"foo_1":{"status":"green","number_of_shards":1,"number_of_replicas":0,"active_primary_shards":1,"active_shards":1,"relocating_shards":0,"initializing_shards":0,"unassigned_shards":0},"foo_2":{"status":"yellow","number_of_shards":1,"number_of_replicas":1,"active_primary_shards":1,"active_shards":1,"relocating_shards":0,"initializing_shards":0,"unassigned_shards":1}}}`
+	var level string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		level = r.URL.Query().Get("level")
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u, ClusterHealthLevelIndices)
+	expected := `
+# HELP elasticsearch_cluster_health_index_status Whether all primary and replica shards of the index are allocated.
+# TYPE elasticsearch_cluster_health_index_status gauge
+elasticsearch_cluster_health_index_status{index="foo_1",status="green"} 1
+elasticsearch_cluster_health_index_status{index="foo_1",status="red"} 0
+elasticsearch_cluster_health_index_status{index="foo_1",status="yellow"} 0
+elasticsearch_cluster_health_index_status{index="foo_2",status="green"} 0
+elasticsearch_cluster_health_index_status{index="foo_2",status="red"} 0
+elasticsearch_cluster_health_index_status{index="foo_2",status="yellow"} 1
+# HELP elasticsearch_cluster_health_index_unassigned_shards The number of shards of the index that are not allocated to any node.
+# TYPE elasticsearch_cluster_health_index_unassigned_shards gauge
+elasticsearch_cluster_health_index_unassigned_shards{index="foo_1"} 0
+elasticsearch_cluster_health_index_unassigned_shards{index="foo_2"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"elasticsearch_cluster_health_index_status", "elasticsearch_cluster_health_index_unassigned_shards"); err != nil {
+		t.Errorf("Unexpected index health: %s", err)
+	}
+	if level != ClusterHealthLevelIndices {
+		t.Errorf("Expected level %q, got %q", ClusterHealthLevelIndices, level)
+	}
+}
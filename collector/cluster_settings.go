@@ -11,9 +11,43 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/imdario/mergo"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/bytesize"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var (
+	// allocationModes are the values of cluster.routing.allocation.enable
+	allocationModes = []string{"all", "primaries", "new_primaries", "none"}
+	// rebalanceModes are the values of cluster.routing.rebalance.enable
+	rebalanceModes = []string{"all", "primaries", "replicas", "none"}
+)
+
+// breakerLimit is a circuit breaker limit setting, which is exported as a
+// ratio of the heap or in bytes depending on its value
+type breakerLimit struct {
+	setting string
+	ratio   *prometheus.Desc
+	bytes   *prometheus.Desc
+	value   func(csr ClusterSettingsResponse) string
+}
+
+func newBreakerLimit(name, setting string, value func(csr ClusterSettingsResponse) string) *breakerLimit {
+	return &breakerLimit{
+		setting: setting,
+		ratio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster_breaker", name+"_limit_ratio"),
+			"Current "+setting+" setting if set as a percentage, as a ratio of the heap",
+			nil, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster_breaker", name+"_limit_bytes"),
+			"Current "+setting+" setting if set as a byte size, in bytes",
+			nil, nil,
+		),
+		value: value,
+	}
+}
+
 // ClusterSettings information struct
 type ClusterSettings struct {
 	logger log.Logger
@@ -23,7 +57,13 @@ type ClusterSettings struct {
 	up                              prometheus.Gauge
 	shardAllocationEnabled          prometheus.Gauge
 	maxShardsPerNode                prometheus.Gauge
+	destructiveRequiresName         prometheus.Gauge
 	totalScrapes, jsonParseFailures prometheus.Counter
+	allocationEnabled               *prometheus.Desc
+	rebalanceEnabled                *prometheus.Desc
+	recoveryMaxBytesPerSec          *prometheus.Desc
+	concurrentRecoveriesLimit       *prometheus.Desc
+	breakerLimits                   []*breakerLimit
 }
 
 // NewClusterSettings defines Cluster Settings Prometheus metrics
@@ -49,10 +89,50 @@ func NewClusterSettings(logger log.Logger, client *http.Client, url *url.URL) *C
 			Name: prometheus.BuildFQName(namespace, "clustersettings_stats", "max_shards_per_node"),
 			Help: "Current maximum number of shards per node setting.",
 		}),
+		destructiveRequiresName: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster", "destructive_requires_name_enabled"),
+			Help: "Whether destructive actions like deleting indices require explicit index names.",
+		}),
 		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, "clustersettings_stats", "json_parse_failures"),
 			Help: "Number of errors while parsing JSON.",
 		}),
+		allocationEnabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster_routing", "allocation_enabled"),
+			"Whether the mode is the current cluster.routing.allocation.enable setting",
+			[]string{"mode"}, nil,
+		),
+		rebalanceEnabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster_routing", "rebalance_enabled"),
+			"Whether the mode is the current cluster.routing.rebalance.enable setting",
+			[]string{"mode"}, nil,
+		),
+		recoveryMaxBytesPerSec: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "indices_recovery", "max_bytes_per_sec"),
+			"Current indices.recovery.max_bytes_per_sec setting, the bandwidth limit of shard recoveries per node. Zero means unlimited.",
+			nil, nil,
+		),
+		concurrentRecoveriesLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "concurrent_recoveries_limit"),
+			"Current cluster.routing.allocation.node_concurrent_recoveries setting, the number of concurrent shard recoveries allowed per node",
+			nil, nil,
+		),
+		// the node breaker stats report the resulting limits in bytes per
+		// node, e.g. as the parent breaker for indices.breaker.total.limit
+		breakerLimits: []*breakerLimit{
+			newBreakerLimit("total", "indices.breaker.total.limit", func(csr ClusterSettingsResponse) string {
+				return csr.Indices.Breaker.Total.Limit
+			}),
+			newBreakerLimit("fielddata", "indices.breaker.fielddata.limit", func(csr ClusterSettingsResponse) string {
+				return csr.Indices.Breaker.Fielddata.Limit
+			}),
+			newBreakerLimit("request", "indices.breaker.request.limit", func(csr ClusterSettingsResponse) string {
+				return csr.Indices.Breaker.Request.Limit
+			}),
+			newBreakerLimit("inflight_requests", "network.breaker.inflight_requests.limit", func(csr ClusterSettingsResponse) string {
+				return csr.Network.Breaker.InflightRequests.Limit
+			}),
+		},
 	}
 }
 
@@ -62,7 +142,16 @@ func (cs *ClusterSettings) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cs.totalScrapes.Desc()
 	ch <- cs.shardAllocationEnabled.Desc()
 	ch <- cs.maxShardsPerNode.Desc()
+	ch <- cs.destructiveRequiresName.Desc()
 	ch <- cs.jsonParseFailures.Desc()
+	ch <- cs.allocationEnabled
+	ch <- cs.rebalanceEnabled
+	ch <- cs.recoveryMaxBytesPerSec
+	ch <- cs.concurrentRecoveriesLimit
+	for _, limit := range cs.breakerLimits {
+		ch <- limit.ratio
+		ch <- limit.bytes
+	}
 }
 
 func (cs *ClusterSettings) getAndParseURL(u *url.URL, data interface{}) error {
@@ -88,6 +177,7 @@ func (cs *ClusterSettings) getAndParseURL(u *url.URL, data interface{}) error {
 
 	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
 		cs.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
 		return err
 	}
 	return nil
@@ -157,4 +247,103 @@ func (cs *ClusterSettings) Collect(ch chan<- prometheus.Metric) {
 	if err == nil {
 		cs.maxShardsPerNode.Set(float64(maxShardsPerNode))
 	}
+
+	// only sent after a successful fetch, as 0 would report the safety as off
+	if destructiveRequiresName(csr) {
+		cs.destructiveRequiresName.Set(1)
+	} else {
+		cs.destructiveRequiresName.Set(0)
+	}
+	ch <- cs.destructiveRequiresName
+
+	for _, mode := range allocationModes {
+		ch <- prometheus.MustNewConstMetric(
+			cs.allocationEnabled,
+			prometheus.GaugeValue,
+			modeEnabled(csr.Cluster.Routing.Allocation.Enabled, mode),
+			mode,
+		)
+	}
+	for _, mode := range rebalanceModes {
+		ch <- prometheus.MustNewConstMetric(
+			cs.rebalanceEnabled,
+			prometheus.GaugeValue,
+			modeEnabled(csr.Cluster.Routing.Rebalance.Enabled, mode),
+			mode,
+		)
+	}
+
+	// not reported by clusters predating include_defaults, unless set explicitly
+	if setting := csr.Indices.Recovery.MaxBytesPerSec; setting != "" {
+		maxBytesPerSec, err := bytesize.Parse(setting)
+		if err != nil {
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to parse indices.recovery.max_bytes_per_sec",
+				"err", err,
+			)
+		} else {
+			ch <- prometheus.MustNewConstMetric(
+				cs.recoveryMaxBytesPerSec,
+				prometheus.GaugeValue,
+				maxBytesPerSec,
+			)
+		}
+	}
+
+	// compared with the relocating and initializing shards of the cluster health
+	if setting := csr.Cluster.Routing.Allocation.NodeConcurrentRecoveries; setting != "" {
+		limit, err := strconv.ParseInt(setting, 10, 64)
+		if err != nil {
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to parse cluster.routing.allocation.node_concurrent_recoveries",
+				"err", err,
+			)
+		} else {
+			ch <- prometheus.MustNewConstMetric(
+				cs.concurrentRecoveriesLimit,
+				prometheus.GaugeValue,
+				float64(limit),
+			)
+		}
+	}
+
+	for _, limit := range cs.breakerLimits {
+		setting := limit.value(csr)
+		if setting == "" {
+			continue
+		}
+		v, isRatio, err := parseMemorySize(setting)
+		if err != nil {
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to parse "+limit.setting,
+				"err", err,
+			)
+			continue
+		}
+		desc := limit.bytes
+		if isRatio {
+			desc = limit.ratio
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+	}
+}
+
+// modeEnabled returns 1 if the setting is set to the mode, 0 otherwise
+func modeEnabled(setting, mode string) float64 {
+	if setting == mode {
+		return 1
+	}
+	return 0
+}
+
+// destructiveRequiresName returns whether action.destructive_requires_name is
+// enabled. The defaults reported by the cluster already reflect the version
+// dependent default (false before 8.0, true since). If the setting isn't
+// reported at all, the cluster predates include_defaults and the setting is off.
+func destructiveRequiresName(csr ClusterSettingsResponse) bool {
+	enabled, err := strconv.ParseBool(csr.Action.DestructiveRequiresName)
+	if err != nil {
+		return false
+	}
+	return enabled
 }
@@ -9,7 +9,10 @@ type ClusterSettingsFullResponse struct {
 
 // ClusterSettingsResponse is a representation of a Elasticsearch Cluster Settings
 type ClusterSettingsResponse struct {
-	Cluster Cluster `json:"cluster"`
+	Cluster Cluster        `json:"cluster"`
+	Action  Action         `json:"action"`
+	Indices ClusterIndices `json:"indices"`
+	Network ClusterNetwork `json:"network"`
 }
 
 // Cluster is a representation of a Elasticsearch Cluster Settings
@@ -21,9 +24,54 @@ type Cluster struct {
 // Routing is a representation of a Elasticsearch Cluster shard routing configuration
 type Routing struct {
 	Allocation Allocation `json:"allocation"`
+	Rebalance  Rebalance  `json:"rebalance"`
 }
 
 // Allocation is a representation of a Elasticsearch Cluster shard routing allocation settings
 type Allocation struct {
+	Enabled                  string `json:"enable"`
+	NodeConcurrentRecoveries string `json:"node_concurrent_recoveries"`
+}
+
+// Rebalance is a representation of a Elasticsearch Cluster shard routing rebalance settings
+type Rebalance struct {
 	Enabled string `json:"enable"`
 }
+
+// Action is a representation of Elasticsearch action settings
+type Action struct {
+	DestructiveRequiresName string `json:"destructive_requires_name"`
+}
+
+// ClusterIndices is a representation of the cluster wide Elasticsearch indices settings
+type ClusterIndices struct {
+	Recovery ClusterIndicesRecovery `json:"recovery"`
+	Breaker  ClusterIndicesBreaker  `json:"breaker"`
+}
+
+// ClusterIndicesRecovery is a representation of the Elasticsearch shard recovery settings
+type ClusterIndicesRecovery struct {
+	MaxBytesPerSec string `json:"max_bytes_per_sec"`
+}
+
+// ClusterIndicesBreaker is a representation of the Elasticsearch circuit breaker settings
+type ClusterIndicesBreaker struct {
+	Total     ClusterBreaker `json:"total"`
+	Fielddata ClusterBreaker `json:"fielddata"`
+	Request   ClusterBreaker `json:"request"`
+}
+
+// ClusterBreaker is a representation of the settings of a circuit breaker
+type ClusterBreaker struct {
+	Limit string `json:"limit"`
+}
+
+// ClusterNetwork is a representation of the Elasticsearch network settings
+type ClusterNetwork struct {
+	Breaker ClusterNetworkBreaker `json:"breaker"`
+}
+
+// ClusterNetworkBreaker is a representation of the Elasticsearch in flight requests circuit breaker settings
+type ClusterNetworkBreaker struct {
+	InflightRequests ClusterBreaker `json:"inflight_requests"`
+}
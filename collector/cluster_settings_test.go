@@ -6,9 +6,12 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestClusterSettingsStats(t *testing.T) {
@@ -78,3 +81,231 @@ func TestClusterMaxShardsPerNode(t *testing.T) {
 		}
 	}
 }
+
+func TestClusterDestructiveRequiresName(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {"persistent": {"action.destructive_requires_name": "true"}}'
+	//  curl "http://localhost:9200/_cluster/settings?include_defaults=true&filter_path=*.action"
+	tcs := map[string]struct {
+		out  string
+		want bool
+	}{
+		"7.3.0-default":    {`{"defaults":{"action":{"auto_create_index":"true","destructive_requires_name":"false"}}}`, false},
+		"7.3.0-persistent": {`{"persistent":{"action":{"destructive_requires_name":"true"}},"defaults":{"action":{"auto_create_index":"true","destructive_requires_name":"false"}}}`, true},
+		"8.0.0-default":    {`{"defaults":{"action":{"auto_create_index":"true","destructive_requires_name":"true"}}}`, true},
+		"8.0.0-transient":  {`{"transient":{"action":{"destructive_requires_name":"false"}},"defaults":{"action":{"auto_create_index":"true","destructive_requires_name":"true"}}}`, false},
+		"missing":          {`{}`, false},
+	}
+	for name, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+		csr, err := c.fetchAndDecodeClusterSettingsStats()
+		if err != nil {
+			t.Fatalf("[%s] Failed to fetch or decode cluster settings stats: %s", name, err)
+		}
+		if got := destructiveRequiresName(csr); got != tc.want {
+			t.Errorf("[%s] Wrong value for destructive_requires_name: got %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestClusterSettingsDestructiveRequiresNameFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "master_not_discovered_exception", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	// without settings, the safety must not be reported as off
+	expected := `
+# HELP elasticsearch_clustersettings_stats_up Was the last scrape of the ElasticSearch cluster settings endpoint successful.
+# TYPE elasticsearch_clustersettings_stats_up gauge
+elasticsearch_clustersettings_stats_up 0
+`
+	c := NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"elasticsearch_cluster_destructive_requires_name_enabled", "elasticsearch_clustersettings_stats_up"); err != nil {
+		t.Errorf("Unexpected metrics of a failed scrape: %s", err)
+	}
+}
+
+func TestClusterRoutingEnabled(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.6.2
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {"persistent": {"cluster.routing.allocation.enable": "primaries"}, "transient": {"cluster.routing.rebalance.enable": "none"}}'
+	//  curl "http://localhost:9200/_cluster/settings?include_defaults=true&filter_path=*.cluster.routing.allocation.enable,*.cluster.routing.rebalance.enable"
+	out := `{"persistent":{"cluster":{"routing":{"allocation":{"enable":"primaries"}}}},"transient":{"cluster":{"routing":{"rebalance":{"enable":"none"}}}},"defaults":{"cluster":{"routing":{"rebalance":{"enable":"all"},"allocation":{"enable":"all"}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+	expected := `
+# HELP elasticsearch_cluster_routing_allocation_enabled Whether the mode is the current cluster.routing.allocation.enable setting
+# TYPE elasticsearch_cluster_routing_allocation_enabled gauge
+elasticsearch_cluster_routing_allocation_enabled{mode="all"} 0
+elasticsearch_cluster_routing_allocation_enabled{mode="new_primaries"} 0
+elasticsearch_cluster_routing_allocation_enabled{mode="none"} 0
+elasticsearch_cluster_routing_allocation_enabled{mode="primaries"} 1
+# HELP elasticsearch_cluster_routing_rebalance_enabled Whether the mode is the current cluster.routing.rebalance.enable setting
+# TYPE elasticsearch_cluster_routing_rebalance_enabled gauge
+elasticsearch_cluster_routing_rebalance_enabled{mode="all"} 0
+elasticsearch_cluster_routing_rebalance_enabled{mode="none"} 1
+elasticsearch_cluster_routing_rebalance_enabled{mode="primaries"} 0
+elasticsearch_cluster_routing_rebalance_enabled{mode="replicas"} 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"elasticsearch_cluster_routing_allocation_enabled", "elasticsearch_cluster_routing_rebalance_enabled"); err != nil {
+		t.Errorf("Unexpected routing metrics: %s", err)
+	}
+}
+
+func TestClusterRecoveryMaxBytesPerSec(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {"transient": {"indices.recovery.max_bytes_per_sec": "1.5gb"}}'
+	//  curl "http://localhost:9200/_cluster/settings?include_defaults=true&filter_path=*.indices.recovery.max_bytes_per_sec"
+	tcs := map[string]struct {
+		out  string
+		want string
+	}{
+		"7.3.0-default":   {`{"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"40mb"}}}}`, "4.194304e+07"},
+		"7.3.0-transient": {`{"transient":{"indices":{"recovery":{"max_bytes_per_sec":"1.5gb"}}},"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"40mb"}}}}`, "1.610612736e+09"},
+		"7.3.0-kilobytes": {`{"persistent":{"indices":{"recovery":{"max_bytes_per_sec":"512k"}}},"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"40mb"}}}}`, "524288"},
+		"7.3.0-bytes":     {`{"persistent":{"indices":{"recovery":{"max_bytes_per_sec":"1000b"}}},"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"40mb"}}}}`, "1000"},
+		"7.3.0-unlimited": {`{"persistent":{"indices":{"recovery":{"max_bytes_per_sec":"0"}}},"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"40mb"}}}}`, "0"},
+		"missing":         {`{}`, ""},
+		"invalid":         {`{"defaults":{"indices":{"recovery":{"max_bytes_per_sec":"fast"}}}}`, ""},
+	}
+	for name, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+		expected := ""
+		if tc.want != "" {
+			expected = `
+# HELP elasticsearch_indices_recovery_max_bytes_per_sec Current indices.recovery.max_bytes_per_sec setting, the bandwidth limit of shard recoveries per node. Zero means unlimited.
+# TYPE elasticsearch_indices_recovery_max_bytes_per_sec gauge
+elasticsearch_indices_recovery_max_bytes_per_sec ` + tc.want + `
+`
+		}
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_indices_recovery_max_bytes_per_sec"); err != nil {
+			t.Errorf("[%s] Unexpected recovery max bytes per sec: %s", name, err)
+		}
+	}
+}
+
+func TestClusterConcurrentRecoveries(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:7.10.0
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {"persistent": {"cluster.routing.allocation.node_concurrent_recoveries": 4}}'
+	//  curl "http://localhost:9200/_cluster/settings?include_defaults=true&filter_path=*.cluster.routing.allocation.node_concurrent_recoveries"
+	//  curl http://localhost:9200/_cluster/health  # while shards are moved to a new node
+	settings := `{"persistent":{"cluster":{"routing":{"allocation":{"node_concurrent_recoveries":"4"}}}},"defaults":{"cluster":{"routing":{"allocation":{"node_concurrent_recoveries":"2"}}}}}`
+	health := `{"cluster_name":"elasticsearch","status":"green","timed_out":false,"number_of_nodes":2,"number_of_data_nodes":2,"active_primary_shards":10,"active_shards":10,"relocating_shards":3,"initializing_shards":0,"unassigned_shards":0,"delayed_unassigned_shards":0,"number_of_pending_tasks":0,"number_of_in_flight_fetch":0,"task_max_waiting_in_queue_millis":0,"active_shards_percent_as_number":100.0}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cluster/settings":
+			io.WriteString(w, settings)
+		case "/_cluster/health":
+			io.WriteString(w, health)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	// the actual recoveries come from the cluster health, the allowed ones
+	// from the cluster settings
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u),
+		NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u, ClusterHealthLevelCluster),
+	)
+	expected := `
+# HELP elasticsearch_cluster_concurrent_recoveries_limit Current cluster.routing.allocation.node_concurrent_recoveries setting, the number of concurrent shard recoveries allowed per node
+# TYPE elasticsearch_cluster_concurrent_recoveries_limit gauge
+elasticsearch_cluster_concurrent_recoveries_limit 4
+# HELP elasticsearch_cluster_health_relocating_shards The number of shards that are currently moving from one node to another node.
+# TYPE elasticsearch_cluster_health_relocating_shards gauge
+elasticsearch_cluster_health_relocating_shards{cluster="elasticsearch"} 3
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_cluster_concurrent_recoveries_limit", "elasticsearch_cluster_health_relocating_shards"); err != nil {
+		t.Errorf("Unexpected recovery metrics: %s", err)
+	}
+}
+
+func TestClusterBreakerLimits(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {"persistent": {"indices.breaker.fielddata.limit": "25%"}, "transient": {"indices.breaker.request.limit": "2gb"}}'
+	//  curl "http://localhost:9200/_cluster/settings?include_defaults=true&filter_path=*.indices.breaker.*.limit,*.network.breaker.*.limit"
+	out := `{"persistent":{"indices":{"breaker":{"fielddata":{"limit":"25%"}}}},"transient":{"indices":{"breaker":{"request":{"limit":"2gb"}}}},"defaults":{"indices":{"breaker":{"request":{"limit":"60%"},"total":{"limit":"95%"},"fielddata":{"limit":"40%"}}},"network":{"breaker":{"inflight_requests":{"limit":"100%"}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+	expected := `
+# HELP elasticsearch_cluster_breaker_fielddata_limit_ratio Current indices.breaker.fielddata.limit setting if set as a percentage, as a ratio of the heap
+# TYPE elasticsearch_cluster_breaker_fielddata_limit_ratio gauge
+elasticsearch_cluster_breaker_fielddata_limit_ratio 0.25
+# HELP elasticsearch_cluster_breaker_inflight_requests_limit_ratio Current network.breaker.inflight_requests.limit setting if set as a percentage, as a ratio of the heap
+# TYPE elasticsearch_cluster_breaker_inflight_requests_limit_ratio gauge
+elasticsearch_cluster_breaker_inflight_requests_limit_ratio 1
+# HELP elasticsearch_cluster_breaker_request_limit_bytes Current indices.breaker.request.limit setting if set as a byte size, in bytes
+# TYPE elasticsearch_cluster_breaker_request_limit_bytes gauge
+elasticsearch_cluster_breaker_request_limit_bytes 2.147483648e+09
+# HELP elasticsearch_cluster_breaker_total_limit_ratio Current indices.breaker.total.limit setting if set as a percentage, as a ratio of the heap
+# TYPE elasticsearch_cluster_breaker_total_limit_ratio gauge
+elasticsearch_cluster_breaker_total_limit_ratio 0.95
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"elasticsearch_cluster_breaker_fielddata_limit_bytes", "elasticsearch_cluster_breaker_fielddata_limit_ratio",
+		"elasticsearch_cluster_breaker_inflight_requests_limit_bytes", "elasticsearch_cluster_breaker_inflight_requests_limit_ratio",
+		"elasticsearch_cluster_breaker_request_limit_bytes", "elasticsearch_cluster_breaker_request_limit_ratio",
+		"elasticsearch_cluster_breaker_total_limit_bytes", "elasticsearch_cluster_breaker_total_limit_ratio"); err != nil {
+		t.Errorf("Unexpected breaker limits: %s", err)
+	}
+}
@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ClusterState information struct
+type ClusterState struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	votingConfig bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	version             *prometheus.Desc
+	masterNode          *prometheus.Desc
+	masterEligibleNodes *prometheus.Desc
+	votingConfigSize    *prometheus.Desc
+}
+
+// NewClusterState defines Cluster State Prometheus metrics. With votingConfig
+// the voting configuration is fetched from the cluster state metadata as well.
+func NewClusterState(logger log.Logger, client *http.Client, url *url.URL, votingConfig bool) *ClusterState {
+	return &ClusterState{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		votingConfig: votingConfig,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_state", "up"),
+			Help: "Was the last scrape of the ElasticSearch cluster state endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_state", "total_scrapes"),
+			Help: "Current total ElasticSearch cluster state scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_state", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+		version: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster_state", "version"),
+			"Version of the cluster state, incremented on every cluster state change",
+			[]string{"cluster"}, nil,
+		),
+		masterNode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "master_node_info"),
+			"Elected master node of the cluster, a changing node signals a master election",
+			[]string{"cluster", "node_id", "node_name"}, nil,
+		),
+		masterEligibleNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "master_eligible_nodes"),
+			"Number of master eligible nodes in the cluster",
+			[]string{"cluster"}, nil,
+		),
+		votingConfigSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "voting_config_size"),
+			"Number of master eligible nodes in the last committed voting configuration, a master election needs a majority of them",
+			[]string{"cluster"}, nil,
+		),
+	}
+}
+
+// Describe add Cluster State metrics descriptions
+func (cs *ClusterState) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cs.version
+	ch <- cs.masterNode
+	ch <- cs.masterEligibleNodes
+	if cs.votingConfig {
+		ch <- cs.votingConfigSize
+	}
+	ch <- cs.up.Desc()
+	ch <- cs.totalScrapes.Desc()
+	ch <- cs.jsonParseFailures.Desc()
+}
+
+func (cs *ClusterState) fetchAndDecodeClusterState() (clusterStateResponse, error) {
+	var csr clusterStateResponse
+
+	u := *cs.url
+	u.Path = path.Join(u.Path, "/_cluster/state/version,master_node,nodes")
+	q := u.Query()
+	// only the name of the master node is needed
+	q.Set("filter_path", "cluster_name,version,master_node,nodes.*.name")
+	u.RawQuery = q.Encode()
+	err := cs.getAndParseURL(&u, &csr)
+	return csr, err
+}
+
+func (cs *ClusterState) fetchAndDecodeVotingConfig() (clusterStateMetadataResponse, error) {
+	var cmr clusterStateMetadataResponse
+
+	u := *cs.url
+	// the metadata includes the mappings of all indices, so it's only
+	// requested if the voting configuration is wanted
+	u.Path = path.Join(u.Path, "/_cluster/state/metadata")
+	q := u.Query()
+	q.Set("filter_path", "metadata.cluster_coordination.last_committed_config")
+	u.RawQuery = q.Encode()
+	err := cs.getAndParseURL(&u, &cmr)
+	return cmr, err
+}
+
+func (cs *ClusterState) fetchAndDecodeMasterEligibleNodes() (masterEligibleNodesResponse, error) {
+	var mer masterEligibleNodesResponse
+
+	u := *cs.url
+	u.Path = path.Join(u.Path, "/_nodes/master:true")
+	q := u.Query()
+	// the header counts the selected nodes
+	q.Set("filter_path", "_nodes")
+	u.RawQuery = q.Encode()
+	err := cs.getAndParseURL(&u, &mer)
+	return mer, err
+}
+
+func (cs *ClusterState) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := cs.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		cs.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+// Collect gets Cluster State metric values
+func (cs *ClusterState) Collect(ch chan<- prometheus.Metric) {
+	cs.totalScrapes.Inc()
+	defer func() {
+		ch <- cs.up
+		ch <- cs.totalScrapes
+		ch <- cs.jsonParseFailures
+	}()
+
+	csr, err := cs.fetchAndDecodeClusterState()
+	if err != nil {
+		cs.up.Set(0)
+		_ = level.Warn(cs.logger).Log(
+			"msg", "failed to fetch and decode cluster state",
+			"err", err,
+		)
+		return
+	}
+	mer, err := cs.fetchAndDecodeMasterEligibleNodes()
+	if err != nil {
+		cs.up.Set(0)
+		_ = level.Warn(cs.logger).Log(
+			"msg", "failed to fetch and decode master eligible nodes",
+			"err", err,
+		)
+		return
+	}
+	var cmr clusterStateMetadataResponse
+	if cs.votingConfig {
+		cmr, err = cs.fetchAndDecodeVotingConfig()
+		if err != nil {
+			cs.up.Set(0)
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to fetch and decode voting configuration",
+				"err", err,
+			)
+			return
+		}
+	}
+	cs.up.Set(1)
+
+	ch <- prometheus.MustNewConstMetric(
+		cs.version,
+		prometheus.GaugeValue,
+		float64(csr.Version),
+		csr.ClusterName,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		cs.masterEligibleNodes,
+		prometheus.GaugeValue,
+		float64(mer.Nodes.Total),
+		csr.ClusterName,
+	)
+	// releases before 7.0 don't have a voting configuration
+	if cmr.Metadata.ClusterCoordination.LastCommittedConfig != nil {
+		ch <- prometheus.MustNewConstMetric(
+			cs.votingConfigSize,
+			prometheus.GaugeValue,
+			float64(len(cmr.Metadata.ClusterCoordination.LastCommittedConfig)),
+			csr.ClusterName,
+		)
+	}
+
+	// the master node is missing while no master is elected
+	if csr.MasterNode == "" {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		cs.masterNode,
+		prometheus.GaugeValue,
+		1,
+		csr.ClusterName, csr.MasterNode, csr.Nodes[csr.MasterNode].Name,
+	)
+}
@@ -0,0 +1,37 @@
+package collector
+
+// clusterStateResponse is a representation of the version and master node
+// parts of the Elasticsearch cluster state
+type clusterStateResponse struct {
+	ClusterName string                              `json:"cluster_name"`
+	Version     int64                               `json:"version"`
+	MasterNode  string                              `json:"master_node"`
+	Nodes       map[string]clusterStateNodeResponse `json:"nodes"`
+}
+
+// clusterStateMetadataResponse is a representation of the cluster
+// coordination part of the Elasticsearch cluster state metadata
+type clusterStateMetadataResponse struct {
+	Metadata struct {
+		ClusterCoordination clusterStateCoordinationResponse `json:"cluster_coordination"`
+	} `json:"metadata"`
+}
+
+// clusterStateCoordinationResponse defines the voting configuration, it is
+// missing before 7.0
+type clusterStateCoordinationResponse struct {
+	LastCommittedConfig []string `json:"last_committed_config"`
+}
+
+// masterEligibleNodesResponse is a representation of the header of the nodes
+// info of the master eligible nodes
+type masterEligibleNodesResponse struct {
+	Nodes struct {
+		Total int64 `json:"total"`
+	} `json:"_nodes"`
+}
+
+// clusterStateNodeResponse defines a node of the cluster state
+type clusterStateNodeResponse struct {
+	Name string `json:"name"`
+}
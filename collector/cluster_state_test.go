@@ -0,0 +1,125 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClusterState(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_cluster/state/version,master_node,nodes?filter_path=cluster_name,version,master_node,nodes.*.name"
+	tcs := map[string]string{
+		"6.8.8": `{"cluster_name":"elasticsearch","version":42,"master_node":"hxRJ4pWAThWEcS9yGF6NHg","nodes":{"hxRJ4pWAThWEcS9yGF6NHg":{"name":"es-master-0"},"Ft3QcNh9Sm2uC0HV5cPzVA":{"name":"es-data-0"}}}`,
+		"7.6.2": `{"cluster_name":"elasticsearch","version":42,"master_node":"hxRJ4pWAThWEcS9yGF6NHg","nodes":{"hxRJ4pWAThWEcS9yGF6NHg":{"name":"es-master-0"},"Ft3QcNh9Sm2uC0HV5cPzVA":{"name":"es-data-0"}}}`,
+	}
+	expected := `
+# HELP elasticsearch_cluster_master_node_info Elected master node of the cluster, a changing node signals a master election
+# TYPE elasticsearch_cluster_master_node_info gauge
+elasticsearch_cluster_master_node_info{cluster="elasticsearch",node_id="hxRJ4pWAThWEcS9yGF6NHg",node_name="es-master-0"} 1
+# HELP elasticsearch_cluster_state_version Version of the cluster state, incremented on every cluster state change
+# TYPE elasticsearch_cluster_state_version gauge
+elasticsearch_cluster_state_version{cluster="elasticsearch"} 42
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("filter_path") == "" {
+				t.Errorf("[%s] Cluster state requested without filter path", ver)
+			}
+			// the metadata is only requested for the voting configuration
+			if strings.Contains(r.URL.Path, "metadata") {
+				t.Errorf("[%s] Cluster state metadata requested without the voting configuration", ver)
+			}
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterState(log.NewNopLogger(), http.DefaultClient, u, false)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_cluster_master_node_info", "elasticsearch_cluster_state_version"); err != nil {
+			t.Errorf("[%s] Unexpected cluster state metrics: %s", ver, err)
+		}
+	}
+}
+
+func TestClusterStateNoMaster(t *testing.T) {
+	// Testcase created by stopping the master eligible nodes of a cluster
+	out := `{"cluster_name":"elasticsearch","version":43,"nodes":{"Ft3QcNh9Sm2uC0HV5cPzVA":{"name":"es-data-0"}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewClusterState(log.NewNopLogger(), http.DefaultClient, u, false)
+	if err := testutil.CollectAndCompare(c, strings.NewReader(""), "elasticsearch_cluster_master_node_info"); err != nil {
+		t.Errorf("Unexpected master node without an elected master: %s", err)
+	}
+}
+
+func TestClusterStateVotingConfig(t *testing.T) {
+	// Testcases created using:
+	//  docker-compose up -d # with three master eligible and one data only node
+	//  curl "http://localhost:9200/_cluster/state/version,master_node,nodes?filter_path=cluster_name,version,master_node,nodes.*.name"
+	//  curl "http://localhost:9200/_cluster/state/metadata?filter_path=metadata.cluster_coordination.last_committed_config"
+	//  curl "http://localhost:9200/_nodes/master:true?filter_path=_nodes"
+	state := `{"cluster_name":"elasticsearch","version":42,"master_node":"hxRJ4pWAThWEcS9yGF6NHg","nodes":{"hxRJ4pWAThWEcS9yGF6NHg":{"name":"es-master-0"},"bGdnEWHgRcuNOeRlDV0PGw":{"name":"es-master-1"},"q3ehzhr2QZ2prCfQ2UW7Sg":{"name":"es-master-2"},"Ft3QcNh9Sm2uC0HV5cPzVA":{"name":"es-data-0"}}}`
+	nodes := `{"_nodes":{"total":3,"successful":3,"failed":0}}`
+	tcs := map[string]struct {
+		metadata string
+		expected string
+	}{
+		// the filter path matches nothing before 7.0
+		"6.8.8": {`{}`, `
+# HELP elasticsearch_cluster_master_eligible_nodes Number of master eligible nodes in the cluster
+# TYPE elasticsearch_cluster_master_eligible_nodes gauge
+elasticsearch_cluster_master_eligible_nodes{cluster="elasticsearch"} 3
+`},
+		"7.6.2": {`{"metadata":{"cluster_coordination":{"last_committed_config":["hxRJ4pWAThWEcS9yGF6NHg","bGdnEWHgRcuNOeRlDV0PGw","q3ehzhr2QZ2prCfQ2UW7Sg"]}}}`, `
+# HELP elasticsearch_cluster_master_eligible_nodes Number of master eligible nodes in the cluster
+# TYPE elasticsearch_cluster_master_eligible_nodes gauge
+elasticsearch_cluster_master_eligible_nodes{cluster="elasticsearch"} 3
+# HELP elasticsearch_cluster_voting_config_size Number of master eligible nodes in the last committed voting configuration, a master election needs a majority of them
+# TYPE elasticsearch_cluster_voting_config_size gauge
+elasticsearch_cluster_voting_config_size{cluster="elasticsearch"} 3
+`},
+	}
+	for ver, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/_cluster/state/version,master_node,nodes":
+				fmt.Fprintln(w, state)
+			case "/_cluster/state/metadata":
+				fmt.Fprintln(w, tc.metadata)
+			case "/_nodes/master:true":
+				fmt.Fprintln(w, nodes)
+			default:
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterState(log.NewNopLogger(), http.DefaultClient, u, true)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(tc.expected), "elasticsearch_cluster_master_eligible_nodes", "elasticsearch_cluster_voting_config_size"); err != nil {
+			t.Errorf("[%s] Unexpected voting config metrics: %s", ver, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	defaultClusterStatsLabels = []string{"cluster"}
+)
+
+type clusterStatsMetric struct {
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(clusterStats clusterStatsResponse) float64
+}
+
+// ClusterStats information struct
+type ClusterStats struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	metrics              []*clusterStatsMetric
+	nodesCount           *prometheus.Desc
+	diskUtilizationRatio *prometheus.Desc
+	totalFieldsCount     *prometheus.Desc
+	fieldTypes           *prometheus.Desc
+}
+
+// NewClusterStats defines Cluster Stats Prometheus metrics
+func NewClusterStats(logger log.Logger, client *http.Client, url *url.URL) *ClusterStats {
+	return &ClusterStats{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_stats", "up"),
+			Help: "Was the last scrape of the ElasticSearch cluster stats endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_stats", "total_scrapes"),
+			Help: "Current total ElasticSearch cluster stats scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "cluster_stats", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		metrics: []*clusterStatsMetric{
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "clusterstats", "indices_count"),
+					"Number of indices in the cluster",
+					defaultClusterStatsLabels, nil,
+				),
+				Value: func(clusterStats clusterStatsResponse) float64 {
+					return float64(clusterStats.Indices.Count)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "clusterstats", "docs_count"),
+					"Number of documents in all primary shards of the cluster",
+					defaultClusterStatsLabels, nil,
+				),
+				Value: func(clusterStats clusterStatsResponse) float64 {
+					return intToFloat64(logger, "elasticsearch_clusterstats_docs_count", clusterStats.Indices.Docs.Count)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "clusterstats", "store_size_bytes"),
+					"Total size of all shards of the cluster in bytes",
+					defaultClusterStatsLabels, nil,
+				),
+				Value: func(clusterStats clusterStatsResponse) float64 {
+					return float64(clusterStats.Indices.Store.SizeInBytes)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "clusterstats", "jvm_heap_used_bytes"),
+					"JVM heap used by all nodes of the cluster in bytes",
+					defaultClusterStatsLabels, nil,
+				),
+				Value: func(clusterStats clusterStatsResponse) float64 {
+					return float64(clusterStats.Nodes.JVM.Mem.HeapUsedInBytes)
+				},
+			},
+		},
+		nodesCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "clusterstats", "nodes_count"),
+			"Number of nodes in the cluster by role, a node may have several roles",
+			append(defaultClusterStatsLabels, "role"), nil,
+		),
+		diskUtilizationRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "disk_utilization_ratio"),
+			"Ratio of the total store size of all indices to the total disk capacity of all data nodes",
+			defaultClusterStatsLabels, nil,
+		),
+		totalFieldsCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "total_fields_count"),
+			"Number of fields in the mappings of all indices of the cluster, only reported since 7.7",
+			defaultClusterStatsLabels, nil,
+		),
+		fieldTypes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "field_types"),
+			"Number of fields of the field type in the mappings of all indices of the cluster, only reported since 7.7",
+			append(defaultClusterStatsLabels, "type"), nil,
+		),
+	}
+}
+
+// Describe add Cluster Stats metrics descriptions
+func (cs *ClusterStats) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range cs.metrics {
+		ch <- metric.Desc
+	}
+	ch <- cs.nodesCount
+	ch <- cs.diskUtilizationRatio
+	ch <- cs.totalFieldsCount
+	ch <- cs.fieldTypes
+	ch <- cs.up.Desc()
+	ch <- cs.totalScrapes.Desc()
+	ch <- cs.jsonParseFailures.Desc()
+}
+
+func (cs *ClusterStats) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := cs.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(cs.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		cs.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+func (cs *ClusterStats) fetchAndDecodeClusterStats() (clusterStatsResponse, error) {
+	u := *cs.url
+	u.Path = path.Join(u.Path, "/_cluster/stats")
+	var csr clusterStatsResponse
+	err := cs.getAndParseURL(&u, &csr)
+	return csr, err
+}
+
+func (cs *ClusterStats) fetchAndDecodeCatAllocation() (catAllocationResponse, error) {
+	u := *cs.url
+	u.Path = path.Join(u.Path, "/_cat/allocation")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("bytes", "b")
+	u.RawQuery = q.Encode()
+	var car catAllocationResponse
+	err := cs.getAndParseURL(&u, &car)
+	return car, err
+}
+
+// totalDiskBytes sums up the disk capacity of all nodes holding shards. Nodes
+// without disk values, like the UNASSIGNED pseudo node, are skipped.
+func totalDiskBytes(car catAllocationResponse) float64 {
+	var total float64
+	for _, node := range car {
+		diskTotal, err := strconv.ParseFloat(node.DiskTotal, 64)
+		if err != nil {
+			continue
+		}
+		total += diskTotal
+	}
+	return total
+}
+
+// Collect gets Cluster Stats metric values
+func (cs *ClusterStats) Collect(ch chan<- prometheus.Metric) {
+	cs.totalScrapes.Inc()
+	defer func() {
+		ch <- cs.up
+		ch <- cs.totalScrapes
+		ch <- cs.jsonParseFailures
+	}()
+
+	csr, err := cs.fetchAndDecodeClusterStats()
+	if err != nil {
+		cs.up.Set(0)
+		_ = level.Warn(cs.logger).Log(
+			"msg", "failed to fetch and decode cluster stats",
+			"err", err,
+		)
+		return
+	}
+	cs.up.Set(1)
+
+	for _, metric := range cs.metrics {
+		ch <- prometheus.MustNewConstMetric(
+			metric.Desc,
+			metric.Type,
+			metric.Value(csr),
+			csr.ClusterName,
+		)
+	}
+	for role, count := range csr.Nodes.Count {
+		// the total isn't a role, it's the number of nodes
+		if role == "total" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			cs.nodesCount,
+			prometheus.GaugeValue,
+			float64(count),
+			csr.ClusterName, role,
+		)
+	}
+
+	if csr.Indices.Mappings != nil {
+		cs.collectFieldTypes(ch, csr.ClusterName, *csr.Indices.Mappings)
+	}
+
+	// the disk utilization is omitted if the allocation data is unavailable
+	car, err := cs.fetchAndDecodeCatAllocation()
+	if err != nil {
+		_ = level.Warn(cs.logger).Log(
+			"msg", "failed to fetch and decode cat allocation",
+			"err", err,
+		)
+		return
+	}
+	diskTotal := totalDiskBytes(car)
+	if diskTotal == 0 {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		cs.diskUtilizationRatio,
+		prometheus.GaugeValue,
+		float64(csr.Indices.Store.SizeInBytes)/diskTotal,
+		csr.ClusterName,
+	)
+}
+
+// collectFieldTypes sends the number of fields by field type and in total
+func (cs *ClusterStats) collectFieldTypes(ch chan<- prometheus.Metric, cluster string, mappings clusterStatsIndicesMappingsResponse) {
+	var total int64
+	for _, fieldType := range mappings.FieldTypes {
+		total += fieldType.Count
+		ch <- prometheus.MustNewConstMetric(
+			cs.fieldTypes,
+			prometheus.GaugeValue,
+			float64(fieldType.Count),
+			cluster, fieldType.Name,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		cs.totalFieldsCount,
+		prometheus.GaugeValue,
+		float64(total),
+		cluster,
+	)
+}
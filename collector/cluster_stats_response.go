@@ -0,0 +1,61 @@
+package collector
+
+// clusterStatsResponse is a representation of the Elasticsearch Cluster Stats
+type clusterStatsResponse struct {
+	ClusterName string                      `json:"cluster_name"`
+	ClusterUUID string                      `json:"cluster_uuid"`
+	Status      string                      `json:"status"`
+	Indices     clusterStatsIndicesResponse `json:"indices"`
+	Nodes       clusterStatsNodesResponse   `json:"nodes"`
+}
+
+// clusterStatsIndicesResponse defines the cluster stats indices information structure
+type clusterStatsIndicesResponse struct {
+	Count int64                            `json:"count"`
+	Docs  clusterStatsIndicesDocsResponse  `json:"docs"`
+	Store clusterStatsIndicesStoreResponse `json:"store"`
+	// only reported since 7.7
+	Mappings *clusterStatsIndicesMappingsResponse `json:"mappings"`
+}
+
+// clusterStatsIndicesDocsResponse defines the cluster stats indices docs information structure
+type clusterStatsIndicesDocsResponse struct {
+	Count   int64 `json:"count"`
+	Deleted int64 `json:"deleted"`
+}
+
+// clusterStatsIndicesStoreResponse defines the cluster stats indices store information structure
+type clusterStatsIndicesStoreResponse struct {
+	SizeInBytes int64 `json:"size_in_bytes"`
+}
+
+// clusterStatsIndicesMappingsResponse defines the mapping totals of all indices
+type clusterStatsIndicesMappingsResponse struct {
+	FieldTypes []clusterStatsFieldTypeResponse `json:"field_types"`
+}
+
+// clusterStatsFieldTypeResponse defines the number of fields of a field type
+// in the mappings of all indices
+type clusterStatsFieldTypeResponse struct {
+	Name       string `json:"name"`
+	Count      int64  `json:"count"`
+	IndexCount int64  `json:"index_count"`
+}
+
+// clusterStatsNodesResponse defines the cluster stats nodes information structure.
+// Count holds the number of nodes by role and the total number of nodes.
+type clusterStatsNodesResponse struct {
+	Count map[string]int64             `json:"count"`
+	JVM   clusterStatsNodesJVMResponse `json:"jvm"`
+}
+
+// clusterStatsNodesJVMResponse defines the JVM totals of all nodes
+type clusterStatsNodesJVMResponse struct {
+	Mem clusterStatsNodesJVMMemResponse `json:"mem"`
+}
+
+// clusterStatsNodesJVMMemResponse defines the JVM heap totals of all nodes
+type clusterStatsNodesJVMMemResponse struct {
+	HeapUsedInBytes int64 `json:"heap_used_in_bytes"`
+	HeapMaxInBytes  int64 `json:"heap_max_in_bytes"`
+}
@@ -0,0 +1,167 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClusterStatsDiskUtilization(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl http://localhost:9200/_cluster/stats
+	//  curl "http://localhost:9200/_cat/allocation?format=json&bytes=b"
+	clusterStats := `{"_nodes":{"total":2,"successful":2,"failed":0},"cluster_name":"elasticsearch","cluster_uuid":"r1bT9sBrR7S9-CamE41Qqg","timestamp":1589300813133,"status":"yellow","indices":{"count":2,"shards":{"total":4,"primaries":2,"replication":1.0},"docs":{"count":5,"deleted":0},"store":{"size_in_bytes":2000000000},"fielddata":{"memory_size_in_bytes":0,"evictions":0}},"nodes":{"count":{"total":2,"data":2,"master":2},"fs":{"total_in_bytes":20000000000,"free_in_bytes":18000000000,"available_in_bytes":18000000000}}}`
+	tcs := map[string]struct {
+		allocation string
+		expected   string
+	}{
+		"7.3.0": {
+			allocation: `[{"shards":"2","disk.indices":"1000000000","disk.used":"2000000000","disk.avail":"8000000000","disk.total":"10000000000","disk.percent":"20","host":"172.17.0.2","ip":"172.17.0.2","node":"es01"},{"shards":"2","disk.indices":"1000000000","disk.used":"2000000000","disk.avail":"8000000000","disk.total":"10000000000","disk.percent":"20","host":"172.17.0.3","ip":"172.17.0.3","node":"es02"},{"shards":"1","disk.indices":null,"disk.used":null,"disk.avail":null,"disk.total":null,"disk.percent":null,"host":null,"ip":null,"node":"UNASSIGNED"}]`,
+			expected: `
+# HELP elasticsearch_cluster_disk_utilization_ratio Ratio of the total store size of all indices to the total disk capacity of all data nodes
+# TYPE elasticsearch_cluster_disk_utilization_ratio gauge
+elasticsearch_cluster_disk_utilization_ratio{cluster="elasticsearch"} 0.1
+`,
+		},
+		"7.3.0-allocation-unavailable": {
+			allocation: "",
+			expected:   "",
+		},
+	}
+	for name, tc := range tcs {
+		allocation := tc.allocation
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/_cluster/stats":
+				fmt.Fprintln(w, clusterStats)
+			case "/_cat/allocation":
+				if allocation == "" {
+					http.Error(w, "not found", http.StatusNotFound)
+					return
+				}
+				fmt.Fprintln(w, allocation)
+			default:
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterStats(log.NewNopLogger(), http.DefaultClient, u)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(tc.expected), "elasticsearch_cluster_disk_utilization_ratio"); err != nil {
+			t.Errorf("[%s] Unexpected disk utilization ratio: %s", name, err)
+		}
+	}
+}
+
+func TestClusterStats(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_cluster/stats?filter_path=cluster_name,cluster_uuid,status,indices.count,indices.docs,indices.store,nodes.count,nodes.jvm.mem"
+	tcs := map[string]string{
+		"7.3.0": `{"cluster_name":"elasticsearch","cluster_uuid":"r1bT9sBrR7S9-CamE41Qqg","status":"green","indices":{"count":12,"docs":{"count":15022,"deleted":17},"store":{"size_in_bytes":48234905}},"nodes":{"count":{"total":3,"coordinating_only":0,"data":2,"ingest":3,"master":3,"voting_only":0},"jvm":{"mem":{"heap_used_in_bytes":1063528256,"heap_max_in_bytes":3116367872}}}}`,
+	}
+	expected := `
+# HELP elasticsearch_clusterstats_docs_count Number of documents in all primary shards of the cluster
+# TYPE elasticsearch_clusterstats_docs_count gauge
+elasticsearch_clusterstats_docs_count{cluster="elasticsearch"} 15022
+# HELP elasticsearch_clusterstats_indices_count Number of indices in the cluster
+# TYPE elasticsearch_clusterstats_indices_count gauge
+elasticsearch_clusterstats_indices_count{cluster="elasticsearch"} 12
+# HELP elasticsearch_clusterstats_jvm_heap_used_bytes JVM heap used by all nodes of the cluster in bytes
+# TYPE elasticsearch_clusterstats_jvm_heap_used_bytes gauge
+elasticsearch_clusterstats_jvm_heap_used_bytes{cluster="elasticsearch"} 1.063528256e+09
+# HELP elasticsearch_clusterstats_nodes_count Number of nodes in the cluster by role, a node may have several roles
+# TYPE elasticsearch_clusterstats_nodes_count gauge
+elasticsearch_clusterstats_nodes_count{cluster="elasticsearch",role="coordinating_only"} 0
+elasticsearch_clusterstats_nodes_count{cluster="elasticsearch",role="data"} 2
+elasticsearch_clusterstats_nodes_count{cluster="elasticsearch",role="ingest"} 3
+elasticsearch_clusterstats_nodes_count{cluster="elasticsearch",role="master"} 3
+elasticsearch_clusterstats_nodes_count{cluster="elasticsearch",role="voting_only"} 0
+# HELP elasticsearch_clusterstats_store_size_bytes Total size of all shards of the cluster in bytes
+# TYPE elasticsearch_clusterstats_store_size_bytes gauge
+elasticsearch_clusterstats_store_size_bytes{cluster="elasticsearch"} 4.8234905e+07
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/_cluster/stats" {
+				fmt.Fprintln(w, out)
+				return
+			}
+			http.Error(w, "not found", http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterStats(log.NewNopLogger(), http.DefaultClient, u)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+			"elasticsearch_clusterstats_docs_count",
+			"elasticsearch_clusterstats_indices_count",
+			"elasticsearch_clusterstats_jvm_heap_used_bytes",
+			"elasticsearch_clusterstats_nodes_count",
+			"elasticsearch_clusterstats_store_size_bytes",
+		); err != nil {
+			t.Errorf("[%s] Unexpected cluster stats: %s", ver, err)
+		}
+	}
+}
+
+func TestClusterStatsFieldTypes(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/foo_1/_doc/1 -H 'Content-Type: application/json' -d '{"title":"abc","content":"hello","count":1}'
+	//  curl -XPUT http://localhost:9200/foo_2/_doc/1 -H 'Content-Type: application/json' -d '{"title":"def","created":"2021-01-01"}'
+	//  curl "http://localhost:9200/_cluster/stats?filter_path=cluster_name,indices.count,indices.mappings"
+	tcs := map[string]struct {
+		out      string
+		expected string
+	}{
+		"7.17.9": {`{"cluster_name":"elasticsearch","indices":{"count":2,"mappings":{"field_types":[{"name":"date","count":1,"index_count":1,"script_count":0},{"name":"keyword","count":3,"index_count":2,"script_count":0},{"name":"long","count":1,"index_count":1,"script_count":0},{"name":"text","count":3,"index_count":2,"script_count":0}],"runtime_field_types":[]}}}`, `
+# HELP elasticsearch_cluster_field_types Number of fields of the field type in the mappings of all indices of the cluster, only reported since 7.7
+# TYPE elasticsearch_cluster_field_types gauge
+elasticsearch_cluster_field_types{cluster="elasticsearch",type="date"} 1
+elasticsearch_cluster_field_types{cluster="elasticsearch",type="keyword"} 3
+elasticsearch_cluster_field_types{cluster="elasticsearch",type="long"} 1
+elasticsearch_cluster_field_types{cluster="elasticsearch",type="text"} 3
+# HELP elasticsearch_cluster_total_fields_count Number of fields in the mappings of all indices of the cluster, only reported since 7.7
+# TYPE elasticsearch_cluster_total_fields_count gauge
+elasticsearch_cluster_total_fields_count{cluster="elasticsearch"} 8
+`},
+		// no mapping stats at all
+		"7.3.0": {`{"cluster_name":"elasticsearch","indices":{"count":2}}`, ``},
+	}
+	for ver, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/_cluster/stats" {
+				fmt.Fprintln(w, out)
+				return
+			}
+			http.Error(w, "not found", http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewClusterStats(log.NewNopLogger(), http.DefaultClient, u)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(tc.expected),
+			"elasticsearch_cluster_field_types", "elasticsearch_cluster_total_fields_count"); err != nil {
+			t.Errorf("[%s] Unexpected field type metrics: %s", ver, err)
+		}
+	}
+}
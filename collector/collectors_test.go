@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type testCollector struct {
+	collector func(u *url.URL) prometheus.Collector
+	up        string
+}
+
+// testCollectors returns all collectors of the package with the name of their
+// up metric
+func testCollectors() map[string]testCollector {
+	return map[string]testCollector{
+		"async search": {func(u *url.URL) prometheus.Collector {
+			return NewAsyncSearch(log.NewNopLogger(), http.DefaultClient, u)
+		}, "elasticsearch_async_search_up"},
+		"cat allocation": {func(u *url.URL) prometheus.Collector {
+			return NewCatAllocation(log.NewNopLogger(), http.DefaultClient, u)
+		}, "elasticsearch_cat_allocation_up"},
+		"cluster health": {func(u *url.URL) prometheus.Collector {
+			return NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u, ClusterHealthLevelIndices)
+		}, "elasticsearch_cluster_health_up"},
+		"cluster settings": {func(u *url.URL) prometheus.Collector {
+			return NewClusterSettings(log.NewNopLogger(), http.DefaultClient, u)
+		}, "elasticsearch_clustersettings_stats_up"},
+		"cluster state": {func(u *url.URL) prometheus.Collector {
+			return NewClusterState(log.NewNopLogger(), http.DefaultClient, u, true)
+		}, "elasticsearch_cluster_state_up"},
+		"cluster stats": {func(u *url.URL) prometheus.Collector {
+			return NewClusterStats(log.NewNopLogger(), http.DefaultClient, u)
+		}, "elasticsearch_cluster_stats_up"},
+		"enrich": {func(u *url.URL) prometheus.Collector {
+			return NewEnrich(log.NewNopLogger(), http.DefaultClient, u, false)
+		}, "elasticsearch_enrich_up"},
+		"indices": {func(u *url.URL) prometheus.Collector {
+			return NewIndices(log.NewNopLogger(), http.DefaultClient, u, true, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		}, "elasticsearch_index_stats_up"},
+		"indices aggregation": {func(u *url.URL) prometheus.Collector {
+			return NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, true, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		}, "elasticsearch_index_stats_up"},
+		"indices health": {func(u *url.URL) prometheus.Collector {
+			return NewIndicesHealth(log.NewNopLogger(), http.DefaultClient, u, false)
+		}, "elasticsearch_indices_health_up"},
+		"indices settings": {func(u *url.URL) prometheus.Collector {
+			return NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, []string{"number_of_replicas"})
+		}, "elasticsearch_indices_settings_stats_up"},
+		"license": {func(u *url.URL) prometheus.Collector {
+			return NewLicense(log.NewNopLogger(), http.DefaultClient, u, false)
+		}, "elasticsearch_license_up"},
+		"mappings": {func(u *url.URL) prometheus.Collector {
+			return NewMappings(log.NewNopLogger(), http.DefaultClient, u, nil)
+		}, "elasticsearch_mappings_up"},
+		"nodes": {func(u *url.URL) prometheus.Collector {
+			return NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+		}, "elasticsearch_node_stats_up"},
+		"nodes usage": {func(u *url.URL) prometheus.Collector {
+			return NewNodesUsage(log.NewNopLogger(), http.DefaultClient, u, nil)
+		}, "elasticsearch_nodes_usage_up"},
+		"pending tasks": {func(u *url.URL) prometheus.Collector {
+			return NewPendingTasks(log.NewNopLogger(), http.DefaultClient, u)
+		}, "elasticsearch_pending_tasks_up"},
+		"retention": {func(u *url.URL) prometheus.Collector {
+			return NewRetention(log.NewNopLogger(), http.DefaultClient, u, nil, "@timestamp", time.Hour)
+		}, "elasticsearch_retention_up"},
+		"remote info": {func(u *url.URL) prometheus.Collector { return NewRemoteInfo(log.NewNopLogger(), http.DefaultClient, u) }, "elasticsearch_remote_info_up"},
+		"security": {func(u *url.URL) prometheus.Collector {
+			return NewSecurity(log.NewNopLogger(), http.DefaultClient, u, false)
+		}, "elasticsearch_security_stats_up"},
+		"shards": {func(u *url.URL) prometheus.Collector {
+			return NewShards(log.NewNopLogger(), http.DefaultClient, u, true)
+		}, "elasticsearch_shards_stats_up"},
+		"snapshots": {func(u *url.URL) prometheus.Collector { return NewSnapshots(log.NewNopLogger(), http.DefaultClient, u) }, "elasticsearch_snapshot_stats_up"},
+		"templates": {func(u *url.URL) prometheus.Collector { return NewTemplates(log.NewNopLogger(), http.DefaultClient, u) }, "elasticsearch_templates_up"},
+	}
+}
+
+func TestCollectorsNoRequestBeforeCollect(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	for cn, c := range testCollectors() {
+		// collectors are constructed on every scrape in multi-target mode,
+		// only Collect may talk to Elasticsearch
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(c.collector(u)); err != nil {
+			t.Errorf("[%s] Failed to register collector: %s", cn, err)
+		}
+		mu.Lock()
+		if len(requests) > 0 {
+			t.Errorf("[%s] Unexpected requests before Collect: %v", cn, requests)
+		}
+		requests = nil
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// JSONParseErrors counts the responses of all collectors that couldn't be
+// decoded, by endpoint. Unlike the json_parse_failures counter of each
+// collector it's kept across scrapes and has to be registered once.
+var JSONParseErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: prometheus.BuildFQName(namespace, "exporter", "json_parse_errors_total"),
+		Help: "Count of responses from Elasticsearch which failed to parse by endpoint",
+	},
+	[]string{"endpoint"},
+)
+
+// countJSONParseError counts a decode failure of the response from the given URL path
+func countJSONParseError(path string) {
+	JSONParseErrors.WithLabelValues(NormalizeEndpoint(path)).Inc()
+}
+
+// NormalizeEndpoint strips any path prefix in front of the first API segment
+// (starting with an underscore) and replaces names of nodes, repositories etc.
+// between API segments with a wildcard to bound the label cardinality, e.g.
+// /es/_snapshot/backups/_all becomes /_snapshot/*/_all
+func NormalizeEndpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "_") {
+			continue
+		}
+		segments = segments[i:]
+		for j := 1; j < len(segments)-1; j++ {
+			if !strings.HasPrefix(segments[j], "_") {
+				segments[j] = "*"
+			}
+		}
+		return "/" + strings.Join(segments, "/")
+	}
+	return "/"
+}
+
+var (
+	// errFeatureUnavailable is returned by fetchOptional if Elasticsearch
+	// doesn't know the endpoint, e.g. the OSS distribution or older releases
+	errFeatureUnavailable = errors.New("feature not available")
+	// errJSONParse is wrapped by fetchOptional if the response couldn't be decoded
+	errJSONParse = errors.New("failed to parse JSON")
+)
+
+// fetchOptional gets the endpoint u of an optional feature and decodes the
+// response into target. Elasticsearch answers requests to unknown endpoints
+// with 400 (no handler found) or 404, which is returned as errFeatureUnavailable.
+// Decode failures are counted in JSONParseErrors and wrap errJSONParse.
+func fetchOptional(client *http.Client, u *url.URL, target interface{}) error {
+	res, err := client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusNotFound {
+		return errFeatureUnavailable
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(target); err != nil {
+		countJSONParseError(u.Path)
+		return fmt.Errorf("%w: %s", errJSONParse, err)
+	}
+	return nil
+}
+
+// newSupportedDesc returns the description of whether the cluster supports the
+// feature of the optional collector. The collector is a constant label, so
+// every collector can describe the metric on its own.
+func newSupportedDesc(collector string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "collector", "supported"),
+		"Whether the cluster supports the feature of the collector, 0 if Elasticsearch doesn't know its endpoint",
+		nil, prometheus.Labels{"collector": collector},
+	)
+}
+
+// collectSupported sends whether the feature is supported from the result of
+// fetchOptional. Nothing is sent if the request failed otherwise, as it's
+// unknown then.
+func collectSupported(ch chan<- prometheus.Metric, desc *prometheus.Desc, err error) {
+	var value float64
+	switch {
+	case err == errFeatureUnavailable:
+		value = 0
+	case err == nil, errors.Is(err, errJSONParse):
+		value = 1
+	default:
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+}
+
+// decodeMembers decodes the JSON object at the current position of dec member
+// by member, so large responses are never held in memory at once. decode is
+// called with the key of every member and has to consume its value from dec.
+// A null value is decoded as an empty object.
+func decodeMembers(dec *json.Decoder, decode func(key string) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", t)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("expected a JSON object key, got %v", t)
+		}
+		if err := decode(key); err != nil {
+			return err
+		}
+	}
+	// the closing brace
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next JSON value of dec without decoding it
+func skipValue(dec *json.Decoder) error {
+	var v json.RawMessage
+	return dec.Decode(&v)
+}
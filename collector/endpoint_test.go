@@ -0,0 +1,173 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	for path, want := range map[string]string{
+		"":                           "/",
+		"/":                          "/",
+		"/es/":                       "/",
+		"/_cluster/health":           "/_cluster/health",
+		"/es/_cluster/health":        "/_cluster/health",
+		"/_nodes/stats":              "/_nodes/stats",
+		"/_nodes/es01/stats":         "/_nodes/*/stats",
+		"/_all/_stats":               "/_all/_stats",
+		"/_snapshot":                 "/_snapshot",
+		"/_snapshot/backups/_all":    "/_snapshot/*/_all",
+		"/es/_snapshot/backups/_all": "/_snapshot/*/_all",
+	} {
+		if got := NormalizeEndpoint(path); got != want {
+			t.Errorf("Wrong endpoint for %q: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	// the cluster stats are fine, but the allocation response is cut off
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/es/_cluster/stats":
+			fmt.Fprintln(w, `{"cluster_name":"elasticsearch","indices":{"count":2,"store":{"size_in_bytes":2000000000}}}`)
+		case "/es/_cat/allocation":
+			fmt.Fprintln(w, `[{"shards":"2","disk.indices":"1000000000","disk.used":`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL + "/es")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	allocationErrors := testutil.ToFloat64(JSONParseErrors.WithLabelValues("/_cat/allocation"))
+	statsErrors := testutil.ToFloat64(JSONParseErrors.WithLabelValues("/_cluster/stats"))
+
+	testutil.CollectAndCount(NewClusterStats(log.NewNopLogger(), http.DefaultClient, u))
+
+	if got := testutil.ToFloat64(JSONParseErrors.WithLabelValues("/_cat/allocation")) - allocationErrors; got != 1 {
+		t.Errorf("Wrong number of parse errors for /_cat/allocation: %v", got)
+	}
+	if got := testutil.ToFloat64(JSONParseErrors.WithLabelValues("/_cluster/stats")) - statsErrors; got != 0 {
+		t.Errorf("Wrong number of parse errors for /_cluster/stats: %v", got)
+	}
+}
+
+func TestFetchOptional(t *testing.T) {
+	tcs := map[string]struct {
+		code int
+		out  string
+		want error
+	}{
+		"available":  {http.StatusOK, `{"executing_policies":[],"coordinator_stats":[]}`, nil},
+		"not found":  {http.StatusNotFound, `{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [_enrich]"}],"type":"index_not_found_exception","reason":"no such index [_enrich]"},"status":404}`, errFeatureUnavailable},
+		"no handler": {http.StatusBadRequest, `{"error":"no handler found for uri [/_enrich/_stats] and method [GET]"}`, errFeatureUnavailable},
+		"error":      {http.StatusInternalServerError, `{"error":"internal server error"}`, nil},
+		"truncated":  {http.StatusOK, `{"executing_policies":[`, errJSONParse},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.code)
+			fmt.Fprintln(w, tc.out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL + "/_enrich/_stats")
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		var esr enrichStatsResponse
+		err = fetchOptional(http.DefaultClient, u, &esr)
+		switch {
+		case tc.code == http.StatusInternalServerError:
+			if err == nil || errors.Is(err, errFeatureUnavailable) {
+				t.Errorf("[%s] Expected a request error, got %v", name, err)
+			}
+		case !errors.Is(err, tc.want):
+			t.Errorf("[%s] Wrong error: got %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// the collectors of unavailable features are reported as down if requested
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	for disable, want := range map[bool]float64{false: 1, true: 0} {
+		e := NewEnrich(log.NewNopLogger(), http.DefaultClient, u, disable)
+		testutil.CollectAndCount(e)
+		if got := testutil.ToFloat64(e.up); got != want {
+			t.Errorf("Wrong enrich up with disableUnavailable=%t: got %v, want %v", disable, got, want)
+		}
+	}
+}
+
+func TestCollectorSupported(t *testing.T) {
+	// the cluster has a license, but neither enrich policies nor X-Pack
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_license":
+			fmt.Fprintln(w, `{"license":{"status":"active","uid":"0e9d8c7b-6a5f-4e3d-2c1b-0a9f8e7d6c5b","type":"basic","issue_date":"2020-11-25T12:00:00.000Z","issue_date_in_millis":1606305600000,"max_nodes":1000,"issued_to":"docker-cluster","issuer":"elasticsearch","start_date_in_millis":-1}}`)
+		case "/_xpack":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error":"no handler found for uri [/_xpack] and method [GET]"}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(
+		NewEnrich(log.NewNopLogger(), http.DefaultClient, u, false),
+		NewLicense(log.NewNopLogger(), http.DefaultClient, u, false),
+		NewSecurity(log.NewNopLogger(), http.DefaultClient, u, false),
+	)
+	expected := `
+# HELP elasticsearch_collector_supported Whether the cluster supports the feature of the collector, 0 if Elasticsearch doesn't know its endpoint
+# TYPE elasticsearch_collector_supported gauge
+elasticsearch_collector_supported{collector="enrich"} 0
+elasticsearch_collector_supported{collector="license"} 1
+elasticsearch_collector_supported{collector="security"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "elasticsearch_collector_supported"); err != nil {
+		t.Errorf("Unexpected supported metrics: %s", err)
+	}
+
+	// nothing is known about the features while Elasticsearch fails
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	u, err = url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	if err := testutil.CollectAndCompare(NewSecurity(log.NewNopLogger(), http.DefaultClient, u, false),
+		strings.NewReader(""), "elasticsearch_collector_supported"); err != nil {
+		t.Errorf("Unexpected supported metrics: %s", err)
+	}
+}
@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type enrichCoordinatorMetric struct {
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(coordinator enrichCoordinatorResponse) float64
+}
+
+// Enrich information struct
+type Enrich struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	disableUnavailable bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	supported          *prometheus.Desc
+	executingPolicies  *prometheus.Desc
+	coordinatorMetrics []*enrichCoordinatorMetric
+}
+
+// NewEnrich defines Enrich Prometheus metrics. If disableUnavailable is true,
+// the collector is reported as down if the cluster doesn't provide enrich.
+func NewEnrich(logger log.Logger, client *http.Client, url *url.URL, disableUnavailable bool) *Enrich {
+	return &Enrich{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		disableUnavailable: disableUnavailable,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "enrich", "up"),
+			Help: "Was the last scrape of the ElasticSearch enrich stats endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "enrich", "total_scrapes"),
+			Help: "Current total ElasticSearch enrich stats scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "enrich", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		supported: newSupportedDesc("enrich"),
+		executingPolicies: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "enrich", "executing_policies_count"),
+			"Number of enrich policies whose enrich index is currently being built",
+			nil, nil,
+		),
+		coordinatorMetrics: []*enrichCoordinatorMetric{
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "enrich", "coordinator_queue_size"),
+					"Number of enrich lookups queued on the node",
+					[]string{"node"}, nil,
+				),
+				Value: func(coordinator enrichCoordinatorResponse) float64 {
+					return float64(coordinator.QueueSize)
+				},
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "enrich", "coordinator_remote_requests_current"),
+					"Current number of outstanding search requests to enrich indices of the node",
+					[]string{"node"}, nil,
+				),
+				Value: func(coordinator enrichCoordinatorResponse) float64 {
+					return float64(coordinator.RemoteRequestsCurrent)
+				},
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "enrich", "coordinator_remote_requests_total"),
+					"Total number of search requests to enrich indices of the node",
+					[]string{"node"}, nil,
+				),
+				Value: func(coordinator enrichCoordinatorResponse) float64 {
+					return float64(coordinator.RemoteRequestsTotal)
+				},
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "enrich", "coordinator_executed_searches_total"),
+					"Total number of searches of enrich lookups executed by the node",
+					[]string{"node"}, nil,
+				),
+				Value: func(coordinator enrichCoordinatorResponse) float64 {
+					return float64(coordinator.ExecutedSearchesTotal)
+				},
+			},
+		},
+	}
+}
+
+// Describe add Enrich metrics descriptions
+func (e *Enrich) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.supported
+	ch <- e.executingPolicies
+	for _, metric := range e.coordinatorMetrics {
+		ch <- metric.Desc
+	}
+	ch <- e.up.Desc()
+	ch <- e.totalScrapes.Desc()
+	ch <- e.jsonParseFailures.Desc()
+}
+
+func (e *Enrich) fetchAndDecodeEnrichStats() (enrichStatsResponse, error) {
+	var esr enrichStatsResponse
+
+	u := *e.url
+	u.Path = path.Join(u.Path, "/_enrich/_stats")
+	err := fetchOptional(e.client, &u, &esr)
+	if errors.Is(err, errJSONParse) {
+		e.jsonParseFailures.Inc()
+	}
+	return esr, err
+}
+
+// Collect gets Enrich metric values
+func (e *Enrich) Collect(ch chan<- prometheus.Metric) {
+	e.totalScrapes.Inc()
+	defer func() {
+		ch <- e.up
+		ch <- e.totalScrapes
+		ch <- e.jsonParseFailures
+	}()
+
+	esr, err := e.fetchAndDecodeEnrichStats()
+	collectSupported(ch, e.supported, err)
+	if err == errFeatureUnavailable && !e.disableUnavailable {
+		// the OSS distribution and releases before 7.5 don't know the
+		// endpoint, so there's no enrich policy at all
+		err = nil
+	}
+	if err == errFeatureUnavailable {
+		e.up.Set(0)
+		_ = level.Debug(e.logger).Log(
+			"msg", "enrich stats not available",
+		)
+		return
+	}
+	if err != nil {
+		e.up.Set(0)
+		_ = level.Warn(e.logger).Log(
+			"msg", "failed to fetch and decode enrich stats",
+			"err", err,
+		)
+		return
+	}
+	e.up.Set(1)
+
+	ch <- prometheus.MustNewConstMetric(
+		e.executingPolicies,
+		prometheus.GaugeValue,
+		float64(len(esr.ExecutingPolicies)),
+	)
+	for _, coordinator := range esr.CoordinatorStats {
+		for _, metric := range e.coordinatorMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(coordinator),
+				coordinator.NodeID,
+			)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package collector
+
+// enrichStatsResponse is a representation of the Elasticsearch enrich stats
+type enrichStatsResponse struct {
+	ExecutingPolicies []enrichExecutingPolicyResponse `json:"executing_policies"`
+	CoordinatorStats  []enrichCoordinatorResponse     `json:"coordinator_stats"`
+}
+
+// enrichExecutingPolicyResponse defines a policy whose enrich index is being built
+type enrichExecutingPolicyResponse struct {
+	Name string `json:"name"`
+}
+
+// enrichCoordinatorResponse defines the enrich lookups coordinated by a single
+// ingest node
+type enrichCoordinatorResponse struct {
+	NodeID                string `json:"node_id"`
+	QueueSize             int64  `json:"queue_size"`
+	RemoteRequestsCurrent int64  `json:"remote_requests_current"`
+	RemoteRequestsTotal   int64  `json:"remote_requests_total"`
+	ExecutedSearchesTotal int64  `json:"executed_searches_total"`
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEnrich(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/_enrich/policy/users-policy --header "Content-Type: application/json" -d '
+	//  {"match": {"indices": "users", "match_field": "email", "enrich_fields": ["first_name", "last_name"]}}'
+	//  curl -XPOST "http://localhost:9200/_enrich/policy/users-policy/_execute?wait_for_completion=false"
+	//  curl http://localhost:9200/_enrich/_stats
+	tcs := map[string]struct {
+		status   int
+		out      string
+		expected string
+	}{
+		"7.10.0": {http.StatusOK, `{"executing_policies":[{"name":"users-policy","task":{"node":"oxTbPpPWRfWtKZaIy-ndgw","id":4711,"type":"enrich","action":"policy_execution","status":{"phase":"RUNNING"},"start_time_in_millis":1606305000000,"running_time_in_nanos":1500000000,"cancellable":false,"headers":{}}}],"coordinator_stats":[{"node_id":"oxTbPpPWRfWtKZaIy-ndgw","queue_size":0,"remote_requests_current":1,"remote_requests_total":42,"executed_searches_total":84}]}`, `
+# HELP elasticsearch_enrich_coordinator_executed_searches_total Total number of searches of enrich lookups executed by the node
+# TYPE elasticsearch_enrich_coordinator_executed_searches_total counter
+elasticsearch_enrich_coordinator_executed_searches_total{node="oxTbPpPWRfWtKZaIy-ndgw"} 84
+# HELP elasticsearch_enrich_coordinator_remote_requests_current Current number of outstanding search requests to enrich indices of the node
+# TYPE elasticsearch_enrich_coordinator_remote_requests_current gauge
+elasticsearch_enrich_coordinator_remote_requests_current{node="oxTbPpPWRfWtKZaIy-ndgw"} 1
+# HELP elasticsearch_enrich_executing_policies_count Number of enrich policies whose enrich index is currently being built
+# TYPE elasticsearch_enrich_executing_policies_count gauge
+elasticsearch_enrich_executing_policies_count 1
+# HELP elasticsearch_enrich_up Was the last scrape of the ElasticSearch enrich stats endpoint successful.
+# TYPE elasticsearch_enrich_up gauge
+elasticsearch_enrich_up 1
+`},
+		// the OSS distribution
+		"7.10.0-oss": {http.StatusBadRequest, `{"error":"no handler found for uri [/_enrich/_stats] and method [GET]"}`, `
+# HELP elasticsearch_enrich_executing_policies_count Number of enrich policies whose enrich index is currently being built
+# TYPE elasticsearch_enrich_executing_policies_count gauge
+elasticsearch_enrich_executing_policies_count 0
+# HELP elasticsearch_enrich_up Was the last scrape of the ElasticSearch enrich stats endpoint successful.
+# TYPE elasticsearch_enrich_up gauge
+elasticsearch_enrich_up 1
+`},
+		"7.4.2": {http.StatusNotFound, `{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [_enrich]"}],"type":"index_not_found_exception","reason":"no such index [_enrich]"},"status":404}`, `
+# HELP elasticsearch_enrich_executing_policies_count Number of enrich policies whose enrich index is currently being built
+# TYPE elasticsearch_enrich_executing_policies_count gauge
+elasticsearch_enrich_executing_policies_count 0
+# HELP elasticsearch_enrich_up Was the last scrape of the ElasticSearch enrich stats endpoint successful.
+# TYPE elasticsearch_enrich_up gauge
+elasticsearch_enrich_up 1
+`},
+	}
+	for ver, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/_enrich/_stats" {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(tc.status)
+			fmt.Fprintln(w, tc.out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		e := NewEnrich(log.NewNopLogger(), http.DefaultClient, u, false)
+		if err := testutil.CollectAndCompare(e, strings.NewReader(tc.expected),
+			"elasticsearch_enrich_coordinator_executed_searches_total", "elasticsearch_enrich_coordinator_remote_requests_current",
+			"elasticsearch_enrich_executing_policies_count", "elasticsearch_enrich_up"); err != nil {
+			t.Errorf("[%s] Unexpected enrich metrics: %s", ver, err)
+		}
+	}
+}
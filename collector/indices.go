@@ -1,29 +1,74 @@
 package collector
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/blang/semver"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Values of the index label mode, which controls how the index label of the
+// index and shard metrics is exported
+const (
+	// IndexLabelModeFull exports the index name as is
+	IndexLabelModeFull = "full"
+	// IndexLabelModeHashed replaces the index name with a stable short hash
+	IndexLabelModeHashed = "hashed"
+	// IndexLabelModeDrop omits the index label and exports the stats summed up
+	// across all indices. Shard metrics are not exported in this mode.
+	IndexLabelModeDrop = "drop"
+)
+
+// hiddenIndicesVersion is the first version with hidden indices, system indices
+// are hidden from then on and only part of _all with expand_wildcards=hidden
+var hiddenIndicesVersion = semver.MustParse("7.7.0")
+
+// frozenSettings are the index settings which mark an index as frozen or as
+// partially mounted from a searchable snapshot in the frozen tier
+var frozenSettings = []string{"index.frozen", "index.store.snapshot.partial"}
+
+// frozenExclusionBatchSize is the number of indices per batch when the stats
+// of the indices other than the frozen ones are fetched, which keeps the
+// request lines short however many indices there are
+const frozenExclusionBatchSize = 100
+
+// indexStatsBatchWorkers is the number of batches of indices whose stats are
+// fetched at the same time
+const indexStatsBatchWorkers = 4
+
+// indexStatsSections are the sections of the index stats which are decoded
+// for every shard. Has to be kept in sync with IndexStatsIndexDetailResponse.
+var indexStatsSections = []string{
+	"docs", "store", "indexing", "get", "search", "merges", "refresh", "flush", "warmer",
+	"query_cache", "fielddata", "completion", "segments", "translog", "request_cache", "recovery",
+}
+
 type labels struct {
 	keys   func(...string) []string
 	values func(*clusterinfo.Response, ...string) []string
 }
 
 type indexMetric struct {
-	Type   prometheus.ValueType
-	Desc   *prometheus.Desc
-	Value  func(indexStats IndexStatsIndexResponse) float64
-	Labels labels
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(indexStats IndexStatsIndexResponse) float64
+	// Reported returns false if the value isn't part of the stats of the
+	// Elasticsearch version, the metric isn't exported then. Nil means always.
+	Reported func(indexStats IndexStatsIndexResponse) bool
+	Labels   labels
 }
 
 type shardMetric struct {
@@ -39,19 +84,57 @@ type Indices struct {
 	client          *http.Client
 	url             *url.URL
 	shards          bool
+	aggregation     bool
+	labelMode       string
+	topN            int
+	openOnly        bool
+	searchGroups    []string
+	batchSize       int
+	includeSystem   bool
+	excludeFrozen   bool
 	clusterInfoCh   chan *clusterinfo.Response
 	lastClusterInfo *clusterinfo.Response
 
 	up                prometheus.Gauge
+	failedBatches     prometheus.Gauge
 	totalScrapes      prometheus.Counter
 	jsonParseFailures prometheus.Counter
 
-	indexMetrics []*indexMetric
-	shardMetrics []*shardMetric
+	indexMetrics            []*indexMetric
+	indexAggregationMetrics []*indexAggregationMetric
+	mergesAutoThrottle      *indexAggregationMetric
+	shardMetrics            []*shardMetric
+
+	otherIndices   *prometheus.Desc
+	otherDocs      *prometheus.Desc
+	otherStoreSize *prometheus.Desc
+	indexStatus    *prometheus.Desc
+	systemHealth   *prometheus.Desc
+	frozenHealth   *prometheus.Desc
+	frozenSize     *prometheus.Desc
+
+	searchGroupQueryTotal *prometheus.Desc
+	searchGroupQueryTime  *prometheus.Desc
+	searchGroupLabels     labels
+	// indexStatusLabels are the labels of the metrics of the status and health
+	// of an index, which are never summed up
+	indexStatusLabels labels
+	frozenSizeLabels  labels
 }
 
-// NewIndices defines Indices Prometheus metrics
-func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards bool) *Indices {
+// NewIndices defines Indices Prometheus metrics. If aggregation is true, index
+// metrics are exported with an aggregation label (primaries or total) instead of
+// separate metric names. The labelMode is one of the IndexLabelMode values and
+// defaults to IndexLabelModeFull. If topN is positive, only the topN largest
+// indices are exported in detail and the remaining ones summed up. If openOnly
+// is true, only the stats of open indices are exported. The query stats of the
+// searchGroups are exported per index and group. If batchSize is positive, the
+// stats are fetched in parallel for batches of batchSize indices. System indices,
+// whose names start with a dot, and their health are only exported if
+// includeSystem is true. If excludeFrozen is true, only the
+// health and store size of frozen and partially mounted indices are exported,
+// as fetching their stats is slow.
+func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards bool, aggregation bool, labelMode string, topN int, openOnly bool, searchGroups []string, batchSize int, includeSystem bool, excludeFrozen bool) *Indices {
 
 	indexLabels := labels{
 		keys: func(...string) []string {
@@ -67,6 +150,32 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 		},
 	}
 
+	indexAggregationLabels := labels{
+		keys: func(...string) []string {
+			return []string{"index", "aggregation", "cluster"}
+		},
+		values: indexLabels.values,
+	}
+
+	searchGroupLabels := labels{
+		keys: func(...string) []string {
+			return []string{"index", "group", "cluster"}
+		},
+		values: indexLabels.values,
+	}
+
+	indexStatusLabels := labels{
+		keys: func(...string) []string {
+			return []string{"index", "status", "cluster"}
+		},
+		values: indexLabels.values,
+	}
+
+	frozenSizeLabels := labels{
+		keys:   indexLabels.keys,
+		values: indexLabels.values,
+	}
+
 	shardLabels := labels{
 		keys: func(...string) []string {
 			return []string{"index", "shard", "node", "primary", "cluster"}
@@ -81,11 +190,43 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 		},
 	}
 
+	switch labelMode {
+	case IndexLabelModeHashed:
+		indexLabels = hashIndexLabel(indexLabels)
+		indexAggregationLabels = hashIndexLabel(indexAggregationLabels)
+		shardLabels = hashIndexLabel(shardLabels)
+		searchGroupLabels = hashIndexLabel(searchGroupLabels)
+		indexStatusLabels = hashIndexLabel(indexStatusLabels)
+		frozenSizeLabels = hashIndexLabel(frozenSizeLabels)
+	case IndexLabelModeDrop:
+		indexLabels = dropIndexLabel(indexLabels)
+		indexAggregationLabels = dropIndexLabel(indexAggregationLabels)
+		// the search groups are summed up across all indices like the index stats
+		searchGroupLabels = dropIndexLabel(searchGroupLabels)
+		// shard stats can't be summed up across indices in a meaningful way
+		shards = false
+		// all indices are summed up already
+		topN = 0
+		openOnly = false
+		batchSize = 0
+		excludeFrozen = false
+	default:
+		labelMode = IndexLabelModeFull
+	}
+
 	indices := &Indices{
 		logger:        logger,
 		client:        client,
 		url:           url,
 		shards:        shards,
+		aggregation:   aggregation,
+		labelMode:     labelMode,
+		topN:          topN,
+		openOnly:      openOnly,
+		searchGroups:  searchGroups,
+		batchSize:     batchSize,
+		includeSystem: includeSystem,
+		excludeFrozen: excludeFrozen,
 		clusterInfoCh: make(chan *clusterinfo.Response),
 		lastClusterInfo: &clusterinfo.Response{
 			ClusterName: "unknown_cluster",
@@ -95,6 +236,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			Name: prometheus.BuildFQName(namespace, "index_stats", "up"),
 			Help: "Was the last scrape of the ElasticSearch index endpoint successful.",
 		}),
+		failedBatches: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "index_stats", "failed_batches"),
+			Help: "Number of batches of indices whose stats couldn't be fetched in the last scrape, the index stats are partial if positive",
+		}),
 		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, "index_stats", "total_scrapes"),
 			Help: "Current total ElasticSearch index scrapes.",
@@ -104,6 +249,55 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			Help: "Number of errors while parsing JSON.",
 		}),
 
+		otherIndices: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "indices_other", "indices"),
+			"Number of indices which are not among the largest indices exported in detail",
+			[]string{"cluster"}, nil,
+		),
+		otherDocs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "indices_other", "docs"),
+			"Count of documents of the indices which are not among the largest indices exported in detail",
+			[]string{"cluster"}, nil,
+		),
+		otherStoreSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "indices_other", "store_size_bytes"),
+			"Store size of the indices which are not among the largest indices exported in detail",
+			[]string{"cluster"}, nil,
+		),
+		indexStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "status"),
+			"Status of the index (open or close), always 1",
+			indexStatusLabels.keys(), nil,
+		),
+		systemHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "system_index", "health"),
+			"Whether the health of the system index is the given status (green, yellow or red)",
+			indexStatusLabels.keys(), nil,
+		),
+		frozenHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "frozen_health"),
+			"Whether the health of the frozen or partially mounted index is the given status (green, yellow or red)",
+			indexStatusLabels.keys(), nil,
+		),
+		frozenSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "frozen_store_size_bytes"),
+			"Store size of the frozen or partially mounted index in bytes",
+			frozenSizeLabels.keys(), nil,
+		),
+		searchGroupQueryTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "search_group_query_total"),
+			"Total number of search queries of the search group",
+			searchGroupLabels.keys(), nil,
+		),
+		searchGroupQueryTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", timeUnitName("search_group_query_time_seconds_total")),
+			timeUnitHelp("Total search query time of the search group in seconds"),
+			searchGroupLabels.keys(), nil,
+		),
+		searchGroupLabels: searchGroupLabels,
+		indexStatusLabels: indexStatusLabels,
+		frozenSizeLabels:  frozenSizeLabels,
+
 		indexMetrics: []*indexMetric{
 			{
 				Type: prometheus.GaugeValue,
@@ -113,7 +307,7 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Docs.Count)
+					return intToFloat64(logger, "elasticsearch_indices_docs_primary", indexStats.Primaries.Docs.Count)
 				},
 				Labels: indexLabels,
 			},
@@ -137,7 +331,7 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Docs.Count)
+					return intToFloat64(logger, "elasticsearch_indices_docs_total", indexStats.Total.Docs.Count)
 				},
 				Labels: indexLabels,
 			},
@@ -233,7 +427,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Segments.TermsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Primaries.Segments.TermsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Primaries.Segments.TermsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -245,7 +442,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Segments.TermsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Total.Segments.TermsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Total.Segments.TermsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -257,7 +457,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Segments.StoredFieldsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Primaries.Segments.StoredFieldsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Primaries.Segments.StoredFieldsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -269,7 +472,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Segments.StoredFieldsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Total.Segments.StoredFieldsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Total.Segments.StoredFieldsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -305,7 +511,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Segments.NormsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Primaries.Segments.NormsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Primaries.Segments.NormsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -317,7 +526,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Segments.NormsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Total.Segments.NormsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Total.Segments.NormsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -329,7 +541,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Segments.PointsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Primaries.Segments.PointsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Primaries.Segments.PointsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -341,7 +556,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Segments.PointsMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Total.Segments.PointsMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Total.Segments.PointsMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -353,7 +571,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Primaries.Segments.DocValuesMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Primaries.Segments.DocValuesMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Primaries.Segments.DocValuesMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -365,7 +586,10 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Segments.DocValuesMemoryInBytes)
+					return segmentsMemoryValue(indexStats.Total.Segments.DocValuesMemoryInBytes)
+				},
+				Reported: func(indexStats IndexStatsIndexResponse) bool {
+					return indexStats.Total.Segments.DocValuesMemoryInBytes != nil
 				},
 				Labels: indexLabels,
 			},
@@ -468,12 +692,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "search_query_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_query_time_seconds_total")),
 					"Total search query time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Search.QueryTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Search.QueryTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -492,12 +716,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "search_fetch_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_fetch_time_seconds_total")),
 					"Total search fetch time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Search.FetchTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Search.FetchTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -516,12 +740,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "search_scroll_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_scroll_time_seconds_total")),
 					"Total search scroll time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Search.ScrollTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Search.ScrollTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -552,12 +776,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "search_suggest_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_suggest_time_seconds_total")),
 					"Total search suggest time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Search.SuggestTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Search.SuggestTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -576,12 +800,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "indexing_index_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_index_time_seconds_total")),
 					"Total indexing index time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Indexing.IndexTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Indexing.IndexTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -600,12 +824,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "indexing_delete_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_delete_time_seconds_total")),
 					"Total indexing delete time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Indexing.DeleteTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Indexing.DeleteTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -633,27 +857,51 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 				},
 				Labels: indexLabels,
 			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index", "indexing_delete_current"),
+					"Current number of in-flight indexing delete operations",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Indexing.DeleteCurrent)
+				},
+				Labels: indexLabels,
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index", "indexing_index_current"),
+					"Current number of documents being indexed",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Indexing.IndexCurrent)
+				},
+				Labels: indexLabels,
+			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "indexing_throttle_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_throttle_time_seconds_total")),
 					"Total indexing throttle time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Indexing.ThrottleTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Indexing.ThrottleTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "get_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("get_time_seconds_total")),
 					"Total get time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Get.TimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Get.TimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -669,15 +917,51 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 				},
 				Labels: indexLabels,
 			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index_stats", "get_current"),
+					"Current get operations",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Get.Current)
+				},
+				Labels: indexLabels,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index_stats", "get_exists_total"),
+					"Total get count of existing documents",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Get.ExistsTotal)
+				},
+				Labels: indexLabels,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index_stats", "get_missing_total"),
+					"Total get count of missing documents",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Get.MissingTotal)
+				},
+				Labels: indexLabels,
+			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "merge_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_time_seconds_total")),
 					"Total merge time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Merges.TotalTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Merges.TotalTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -696,24 +980,24 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "merge_throttle_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_throttle_time_seconds_total")),
 					"Total merge I/O throttle time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Merges.TotalThrottledTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Merges.TotalThrottledTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "merge_stopped_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_stopped_time_seconds_total")),
 					"Total large merge stopped time in seconds, allowing smaller merges to complete",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Merges.TotalStoppedTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Merges.TotalStoppedTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -732,12 +1016,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "refresh_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("refresh_time_seconds_total")),
 					"Total refresh time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Refresh.TotalTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Refresh.TotalTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -756,12 +1040,36 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "flush_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", "refresh_external_total"),
+					"Total external refresh count, which make changes visible to searches",
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return float64(indexStats.Total.Refresh.ExternalTotal)
+				},
+				Labels: indexLabels,
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index", timeUnitName("refresh_avg_seconds")),
+					timeUnitHelp("Average time per refresh in seconds"),
+					indexLabels.keys(), nil,
+				),
+				Value: func(indexStats IndexStatsIndexResponse) float64 {
+					return refreshAvgTime(indexStats.Total.Refresh)
+				},
+				Labels: indexLabels,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("flush_time_seconds_total")),
 					"Total flush time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Flush.TotalTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Flush.TotalTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -780,12 +1088,12 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "index_stats", "warmer_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "index_stats", timeUnitName("warmer_time_seconds_total")),
 					"Total warmer time in seconds",
 					indexLabels.keys(), nil,
 				),
 				Value: func(indexStats IndexStatsIndexResponse) float64 {
-					return float64(indexStats.Total.Warmer.TotalTimeInMillis) / 1000
+					return millisToTimeUnit(indexStats.Total.Warmer.TotalTimeInMillis)
 				},
 				Labels: indexLabels,
 			},
@@ -946,6 +1254,19 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 				Labels: indexLabels,
 			},
 		},
+		indexAggregationMetrics: newIndexAggregationMetrics(logger, indexAggregationLabels),
+		mergesAutoThrottle: &indexAggregationMetric{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index", "merges_auto_throttle_bytes"),
+				"Current rate merges of the index are auto-throttled to in bytes per second, summed up across its shards",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Merges.TotalAutoThrottleInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
 		shardMetrics: []*shardMetric{
 			{
 				Type: prometheus.GaugeValue,
@@ -983,6 +1304,18 @@ func NewIndices(logger log.Logger, client *http.Client, url *url.URL, shards boo
 				},
 				Labels: shardLabels,
 			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "index", "shard_segments_memory_bytes"),
+					"Memory used by the segments of this shard",
+					shardLabels.keys(), nil,
+				),
+				Value: func(data IndexStatsIndexShardsDetailResponse) float64 {
+					return float64(data.Segments.MemoryInBytes)
+				},
+				Labels: shardLabels,
+			},
 		},
 	}
 
@@ -1011,28 +1344,339 @@ func (i *Indices) String() string {
 	return namespace + "indices"
 }
 
+// isSystemIndex returns whether the index is a system index like .kibana,
+// .tasks or .security
+func isSystemIndex(indexName string) bool {
+	return strings.HasPrefix(indexName, ".")
+}
+
+// hashIndexName returns a stable short hash of the index name
+func hashIndexName(indexName string) string {
+	sum := sha256.Sum256([]byte(indexName))
+	return hex.EncodeToString(sum[:6])
+}
+
+// hashIndexLabel wraps the given labels to replace the index name, which is
+// the first label value, with its hash
+func hashIndexLabel(l labels) labels {
+	return labels{
+		keys: l.keys,
+		values: func(lastClusterinfo *clusterinfo.Response, s ...string) []string {
+			values := append([]string{hashIndexName(s[0])}, s[1:]...)
+			return l.values(lastClusterinfo, values...)
+		},
+	}
+}
+
+// dropIndexLabel wraps the given labels to omit the index label, which is
+// the first label
+func dropIndexLabel(l labels) labels {
+	return labels{
+		keys: func(s ...string) []string {
+			return l.keys(s...)[1:]
+		},
+		values: func(lastClusterinfo *clusterinfo.Response, s ...string) []string {
+			return l.values(lastClusterinfo, s...)[1:]
+		},
+	}
+}
+
+// collectOtherIndices sends the sums of the indices which aren't among the
+// topN largest ones and returns the names of the largest ones
+func (i *Indices) collectOtherIndices(ch chan<- prometheus.Metric, indices catIndicesResponse) []string {
+	var top []string
+	var count float64
+	var docs, storeSize int64
+	for n, index := range indices {
+		if n < i.topN {
+			top = append(top, index.Index)
+			continue
+		}
+		count++
+		// closed indices don't have any values
+		if v, err := strconv.ParseInt(index.DocsCount, 10, 64); err == nil {
+			docs += v
+		}
+		if v, err := strconv.ParseInt(index.StoreSize, 10, 64); err == nil {
+			storeSize += v
+		}
+	}
+	clusterName := i.lastClusterInfo.ClusterName
+	ch <- prometheus.MustNewConstMetric(i.otherIndices, prometheus.GaugeValue, count, clusterName)
+	ch <- prometheus.MustNewConstMetric(i.otherDocs, prometheus.GaugeValue,
+		intToFloat64(i.logger, "elasticsearch_indices_other_docs", docs), clusterName)
+	ch <- prometheus.MustNewConstMetric(i.otherStoreSize, prometheus.GaugeValue,
+		intToFloat64(i.logger, "elasticsearch_indices_other_store_size_bytes", storeSize), clusterName)
+	return top
+}
+
+// collectFrozenIndices sends the health and store size of the frozen indices
+// and returns the other indices
+func (i *Indices) collectFrozenIndices(ch chan<- prometheus.Metric, indices catIndicesResponse, frozenIndices map[string]bool) catIndicesResponse {
+	var other catIndicesResponse
+	for _, index := range indices {
+		if !frozenIndices[index.Index] {
+			other = append(other, index)
+			continue
+		}
+		for _, color := range colors {
+			var value float64
+			if index.Health == color {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(
+				i.frozenHealth,
+				prometheus.GaugeValue,
+				value,
+				i.indexStatusLabels.values(i.lastClusterInfo, index.Index, color)...,
+			)
+		}
+		// closed indices don't have a size
+		if v, err := strconv.ParseInt(index.StoreSize, 10, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(
+				i.frozenSize,
+				prometheus.GaugeValue,
+				intToFloat64(i.logger, "elasticsearch_index_frozen_store_size_bytes", v),
+				i.frozenSizeLabels.values(i.lastClusterInfo, index.Index)...,
+			)
+		}
+	}
+	return other
+}
+
+// refreshAvgTime returns the average time per refresh in the configured
+// TimeUnit, or 0 if the index hasn't been refreshed yet
+func refreshAvgTime(refresh IndexStatsIndexRefreshResponse) float64 {
+	if refresh.Total == 0 {
+		return 0
+	}
+	return millisToTimeUnit(refresh.TotalTimeInMillis) / float64(refresh.Total)
+}
+
+// segmentsMemoryValue returns the segments memory stat, or 0 if it isn't
+// reported by the Elasticsearch version. The memory breakdown of the segments
+// was removed in Elasticsearch 8.x.
+func segmentsMemoryValue(v *int64) float64 {
+	if v == nil {
+		return 0
+	}
+	return float64(*v)
+}
+
 // Describe add Indices metrics descriptions
 func (i *Indices) Describe(ch chan<- *prometheus.Desc) {
-	for _, metric := range i.indexMetrics {
-		ch <- metric.Desc
+	if i.aggregation {
+		for _, metric := range i.indexAggregationMetrics {
+			ch <- metric.Desc
+		}
+	} else {
+		for _, metric := range i.indexMetrics {
+			ch <- metric.Desc
+		}
+	}
+	ch <- i.mergesAutoThrottle.Desc
+	if i.shards {
+		for _, metric := range i.shardMetrics {
+			ch <- metric.Desc
+		}
+	}
+	if i.topN > 0 {
+		ch <- i.otherIndices
+		ch <- i.otherDocs
+		ch <- i.otherStoreSize
+	}
+	if i.openOnly {
+		ch <- i.indexStatus
+	}
+	if i.collectsSystemIndexHealth() {
+		ch <- i.systemHealth
+	}
+	if i.excludeFrozen {
+		ch <- i.frozenHealth
+		ch <- i.frozenSize
+	}
+	if i.batchSize > 0 {
+		ch <- i.failedBatches.Desc()
+	}
+	if len(i.searchGroups) > 0 {
+		ch <- i.searchGroupQueryTotal
+		ch <- i.searchGroupQueryTime
 	}
 	ch <- i.up.Desc()
 	ch <- i.totalScrapes.Desc()
 	ch <- i.jsonParseFailures.Desc()
 }
 
-func (i *Indices) fetchAndDecodeIndexStats() (indexStatsResponse, error) {
-	var isr indexStatsResponse
+// fetchAndDecodeCatIndices lists all indices with their status, sorted by store
+// size, the largest first
+func (i *Indices) fetchAndDecodeCatIndices() (catIndicesResponse, error) {
+	var cir catIndicesResponse
+
+	u := *i.url
+	u.Path = path.Join(u.Path, "/_cat/indices")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("bytes", "b")
+	q.Set("h", "index,health,status,docs.count,store.size")
+	q.Set("s", "store.size:desc")
+	u.RawQuery = q.Encode()
+
+	res, err := i.client.Get(u.String())
+	if err != nil {
+		return cir, fmt.Errorf("failed to get indices from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return cir, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&cir); err != nil {
+		i.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return cir, err
+	}
+
+	return cir, nil
+}
+
+// fetchAndDecodeFrozenSettings fetches the settings of all indices which
+// mark them as frozen or partially mounted from a searchable snapshot
+func (i *Indices) fetchAndDecodeFrozenSettings() (indicesFrozenSettingsResponse, error) {
+	var ifsr indicesFrozenSettingsResponse
+
+	u := *i.url
+	u.Path = path.Join(u.Path, "/_all/_settings", strings.Join(frozenSettings, ","))
+	q := u.Query()
+	q.Set("flat_settings", "true")
+	u.RawQuery = q.Encode()
+
+	res, err := i.client.Get(u.String())
+	if err != nil {
+		return ifsr, fmt.Errorf("failed to get index settings from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return ifsr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&ifsr); err != nil {
+		i.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return ifsr, err
+	}
+	return ifsr, nil
+}
+
+// frozenIndices returns the names of the frozen and partially mounted indices
+func (i *Indices) frozenIndices() (map[string]bool, error) {
+	ifsr, err := i.fetchAndDecodeFrozenSettings()
+	if err != nil {
+		return nil, err
+	}
+	frozen := make(map[string]bool)
+	for indexName, index := range ifsr {
+		for _, setting := range frozenSettings {
+			if index.Settings[setting] == "true" {
+				frozen[indexName] = true
+			}
+		}
+	}
+	return frozen, nil
+}
+
+// fetchAndDecodeSystemIndexHealth lists the system indices with their health.
+// Like Elasticsearch, the pattern .* also matches hidden indices.
+func (i *Indices) fetchAndDecodeSystemIndexHealth() (catIndicesHealthResponse, error) {
+	var cihr catIndicesHealthResponse
 
 	u := *i.url
-	u.Path = path.Join(u.Path, "/_all/_stats")
+	u.Path = path.Join(u.Path, "/_cat/indices/.*")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("h", "index,health")
+	q.Set("expand_wildcards", "all")
+	u.RawQuery = q.Encode()
+
+	res, err := i.client.Get(u.String())
+	if err != nil {
+		return cihr, fmt.Errorf("failed to get system indices from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return cihr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&cihr); err != nil {
+		i.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return cihr, err
+	}
+	return cihr, nil
+}
+
+// fetchAndDecodeIndexStats streams the stats of the given indices, or of all
+// indices if none are given. fn is called with every index as soon as it's
+// decoded, so the stats of all indices are never held in memory at once. The
+// stats summed up across the indices are returned.
+func (i *Indices) fetchAndDecodeIndexStats(fn func(indexName string, indexStats IndexStatsIndexResponse), indexNames ...string) (IndexStatsIndexResponse, error) {
+	var all IndexStatsIndexResponse
+
+	u := *i.url
+	if len(indexNames) > 0 {
+		u.Path = path.Join(u.Path, strings.Join(indexNames, ","), "_stats")
+	} else {
+		u.Path = path.Join(u.Path, "/_all/_stats")
+	}
+	q := u.Query()
 	if i.shards {
-		u.RawQuery = "level=shards"
+		q.Set("level", "shards")
+	}
+	if len(i.searchGroups) > 0 {
+		q.Set("groups", strings.Join(i.searchGroups, ","))
+	}
+	q.Set("filter_path", i.indexStatsFilterPath())
+	// hidden system indices aren't part of _all by default
+	if len(indexNames) == 0 && i.includeSystem && i.lastClusterInfo.Version.Number.GTE(hiddenIndicesVersion) {
+		q.Set("expand_wildcards", "open,hidden")
 	}
+	u.RawQuery = q.Encode()
 
 	res, err := i.client.Get(u.String())
 	if err != nil {
-		return isr, fmt.Errorf("failed to get index stats from %s://%s:%s%s: %s",
+		return all, fmt.Errorf("failed to get index stats from %s://%s:%s%s: %s",
 			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
 	}
 
@@ -1047,15 +1691,118 @@ func (i *Indices) fetchAndDecodeIndexStats() (indexStatsResponse, error) {
 	}()
 
 	if res.StatusCode != http.StatusOK {
-		return isr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+		return all, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&isr); err != nil {
+	all, err = decodeIndexStats(res.Body, fn)
+	if err != nil {
 		i.jsonParseFailures.Inc()
-		return isr, err
+		countJSONParseError(u.Path)
+		return all, err
+	}
+
+	return all, nil
+}
+
+// decodeIndexStats decodes the index stats response from r index by index and
+// calls fn with every index. The _all section is returned.
+func decodeIndexStats(r io.Reader, fn func(indexName string, indexStats IndexStatsIndexResponse)) (IndexStatsIndexResponse, error) {
+	var isr indexStatsResponse
+	dec := json.NewDecoder(r)
+	err := decodeMembers(dec, func(key string) error {
+		switch key {
+		case "_all":
+			return dec.Decode(&isr.All)
+		case "indices":
+			return decodeMembers(dec, func(indexName string) error {
+				var indexStats IndexStatsIndexResponse
+				if err := dec.Decode(&indexStats); err != nil {
+					return err
+				}
+				fn(indexName, indexStats)
+				return nil
+			})
+		}
+		return skipValue(dec)
+	})
+	return isr.All, err
+}
+
+// indexStatsFilterPath returns the filter_path of the index stats request.
+// The primaries and totals are kept whole, with shards only the decoded
+// sections of every shard are kept, as they come with large sections like
+// commit, seq_no or retention_leases.
+func (i *Indices) indexStatsFilterPath() string {
+	paths := []string{"_shards", "_all.primaries", "_all.total", "indices.*.primaries", "indices.*.total"}
+	if i.shards {
+		paths = append(paths, "indices.*.shards.*.routing")
+		for _, section := range indexStatsSections {
+			paths = append(paths, "indices.*.shards.*."+section)
+		}
+	}
+	return strings.Join(paths, ",")
+}
+
+// fetchAndDecodeIndexStatsBatches streams the stats of the given indices in
+// batches of batchSize indices, at most indexStatsBatchWorkers at a time, so
+// fn is called concurrently. A failed batch is logged and doesn't fail the
+// others. The number of failed batches is returned, with an error if all of
+// them failed.
+func (i *Indices) fetchAndDecodeIndexStatsBatches(indexNames []string, batchSize int, fn func(indexName string, indexStats IndexStatsIndexResponse)) (int, error) {
+	var batches [][]string
+	for len(indexNames) > batchSize {
+		batches = append(batches, indexNames[:batchSize])
+		indexNames = indexNames[batchSize:]
+	}
+	if len(indexNames) > 0 {
+		batches = append(batches, indexNames)
+	}
+
+	errs := make([]error, len(batches))
+	workers := make(chan struct{}, indexStatsBatchWorkers)
+	var wg sync.WaitGroup
+	for n, batch := range batches {
+		wg.Add(1)
+		go func(n int, batch []string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			_, errs[n] = i.fetchAndDecodeIndexStats(fn, batch...)
+		}(n, batch)
 	}
+	wg.Wait()
 
-	return isr, nil
+	var failed int
+	for n, err := range errs {
+		if err != nil {
+			failed++
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to fetch and decode the index stats of a batch",
+				"first_index", batches[n][0],
+				"indices", len(batches[n]),
+				"err", err,
+			)
+		}
+	}
+	if failed > 0 && failed == len(batches) {
+		return failed, fmt.Errorf("all %d batches failed", failed)
+	}
+	return failed, nil
+}
+
+// batchIndexNames returns the names of the indices to fetch the stats of in
+// batches. Closed indices don't have any stats and fail the whole batch.
+func (i *Indices) batchIndexNames(indices catIndicesResponse, topIndices []string) []string {
+	if i.topN > 0 {
+		indices = indices[:len(topIndices)]
+	}
+	var names []string
+	for _, index := range indices {
+		if index.Status == "open" {
+			names = append(names, index.Index)
+		}
+	}
+	return names
 }
 
 // Collect gets Indices metric values
@@ -1067,8 +1814,113 @@ func (i *Indices) Collect(ch chan<- prometheus.Metric) {
 		ch <- i.jsonParseFailures
 	}()
 
-	// indices
-	indexStatsResp, err := i.fetchAndDecodeIndexStats()
+	if i.collectsSystemIndexHealth() {
+		i.collectSystemIndexHealth(ch)
+	}
+
+	var catIndicesResp catIndicesResponse
+	if i.topN > 0 || i.openOnly || i.batchSize > 0 || i.excludeFrozen {
+		var err error
+		catIndicesResp, err = i.fetchAndDecodeCatIndices()
+		if err != nil {
+			i.up.Set(0)
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to fetch and decode cat indices",
+				"err", err,
+			)
+			return
+		}
+		if !i.includeSystem {
+			var userIndices catIndicesResponse
+			for _, index := range catIndicesResp {
+				if !isSystemIndex(index.Index) {
+					userIndices = append(userIndices, index)
+				}
+			}
+			catIndicesResp = userIndices
+		}
+	}
+
+	// closed indices are only counted by their status
+	openIndices := make(map[string]bool)
+	if i.openOnly {
+		var open catIndicesResponse
+		for _, index := range catIndicesResp {
+			ch <- prometheus.MustNewConstMetric(
+				i.indexStatus,
+				prometheus.GaugeValue,
+				1,
+				i.indexStatusLabels.values(i.lastClusterInfo, index.Index, index.Status)...,
+			)
+			if index.Status == "open" {
+				open = append(open, index)
+				openIndices[index.Index] = true
+			}
+		}
+		catIndicesResp = open
+	}
+
+	// frozen indices are only exported by their health and size
+	var frozenIndices map[string]bool
+	if i.excludeFrozen {
+		var err error
+		frozenIndices, err = i.frozenIndices()
+		if err != nil {
+			i.up.Set(0)
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to fetch and decode frozen indices",
+				"err", err,
+			)
+			return
+		}
+		catIndicesResp = i.collectFrozenIndices(ch, catIndicesResp, frozenIndices)
+	}
+
+	// on huge clusters, only the largest indices are exported in detail
+	var topIndices []string
+	if i.topN > 0 {
+		topIndices = i.collectOtherIndices(ch, catIndicesResp)
+		if len(topIndices) == 0 {
+			// without any index, the stats of all indices would be fetched
+			i.up.Set(1)
+			return
+		}
+	}
+
+	// the stats of every index are sent as soon as the index is decoded
+	collect := func(indexName string, indexStats IndexStatsIndexResponse) {
+		if i.openOnly && !openIndices[indexName] {
+			return
+		}
+		if !i.includeSystem && isSystemIndex(indexName) {
+			return
+		}
+		if frozenIndices[indexName] {
+			return
+		}
+		// the _all section holds the stats summed up across all indices
+		if i.labelMode == IndexLabelModeDrop {
+			return
+		}
+		i.collectIndex(ch, indexName, indexStats)
+	}
+	var all IndexStatsIndexResponse
+	var err error
+	if i.batchSize > 0 {
+		var failed int
+		indexNames := i.batchIndexNames(catIndicesResp, topIndices)
+		failed, err = i.fetchAndDecodeIndexStatsBatches(indexNames, i.batchSize, collect)
+		i.failedBatches.Set(float64(failed))
+		ch <- i.failedBatches
+	} else if len(topIndices) == 0 && len(frozenIndices) > 0 {
+		// listing every frozen index as excluded in the path exceeds the
+		// request line limit on clusters with many of them, so the other
+		// indices are fetched in batches instead
+		indexNames := i.batchIndexNames(catIndicesResp, topIndices)
+		_, err = i.fetchAndDecodeIndexStatsBatches(indexNames, frozenExclusionBatchSize, collect)
+	} else {
+		all, err = i.fetchAndDecodeIndexStats(collect, topIndices...)
+	}
 	if err != nil {
 		i.up.Set(0)
 		_ = level.Warn(i.logger).Log(
@@ -1080,9 +1932,33 @@ func (i *Indices) Collect(ch chan<- prometheus.Metric) {
 	i.totalScrapes.Inc()
 	i.up.Set(1)
 
-	// Index stats
-	for indexName, indexStats := range indexStatsResp.Indices {
+	if i.labelMode == IndexLabelModeDrop {
+		i.collectIndex(ch, "_all", all)
+	}
+}
+
+// collectIndex sends the stats of a single index
+func (i *Indices) collectIndex(ch chan<- prometheus.Metric, indexName string, indexStats IndexStatsIndexResponse) {
+	if i.aggregation {
+		for _, aggregation := range indexAggregations {
+			for _, metric := range i.indexAggregationMetrics {
+				indexDetail := indexDetailForAggregation(indexStats, aggregation)
+				if metric.Reported != nil && !metric.Reported(indexDetail) {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(
+					metric.Desc,
+					metric.Type,
+					metric.Value(indexDetail),
+					metric.Labels.values(i.lastClusterInfo, indexName, aggregation)...,
+				)
+			}
+		}
+	} else {
 		for _, metric := range i.indexMetrics {
+			if metric.Reported != nil && !metric.Reported(indexStats) {
+				continue
+			}
 			ch <- prometheus.MustNewConstMetric(
 				metric.Desc,
 				metric.Type,
@@ -1091,20 +1967,77 @@ func (i *Indices) Collect(ch chan<- prometheus.Metric) {
 			)
 
 		}
-		if i.shards {
-			for _, metric := range i.shardMetrics {
-				// gaugeVec := prometheus.NewGaugeVec(metric.Opts, metric.Labels)
-				for shardNumber, shards := range indexStats.Shards {
-					for _, shard := range shards {
-						ch <- prometheus.MustNewConstMetric(
-							metric.Desc,
-							metric.Type,
-							metric.Value(shard),
-							metric.Labels.values(i.lastClusterInfo, indexName, shardNumber, shard.Routing.Node, strconv.FormatBool(shard.Routing.Primary))...,
-						)
-					}
+	}
+	// the rate merges are throttled to rises with the indexing load
+	for _, aggregation := range indexAggregations {
+		ch <- prometheus.MustNewConstMetric(
+			i.mergesAutoThrottle.Desc,
+			i.mergesAutoThrottle.Type,
+			i.mergesAutoThrottle.Value(indexDetailForAggregation(indexStats, aggregation)),
+			i.mergesAutoThrottle.Labels.values(i.lastClusterInfo, indexName, aggregation)...,
+		)
+	}
+	// only the requested search groups are part of the search stats
+	for group, search := range indexStats.Total.Search.Groups {
+		ch <- prometheus.MustNewConstMetric(
+			i.searchGroupQueryTotal,
+			prometheus.CounterValue,
+			float64(search.QueryTotal),
+			i.searchGroupLabels.values(i.lastClusterInfo, indexName, group)...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			i.searchGroupQueryTime,
+			prometheus.CounterValue,
+			millisToTimeUnit(search.QueryTimeInMillis),
+			i.searchGroupLabels.values(i.lastClusterInfo, indexName, group)...,
+		)
+	}
+	if i.shards {
+		for _, metric := range i.shardMetrics {
+			// gaugeVec := prometheus.NewGaugeVec(metric.Opts, metric.Labels)
+			for shardNumber, shards := range indexStats.Shards {
+				for _, shard := range shards {
+					ch <- prometheus.MustNewConstMetric(
+						metric.Desc,
+						metric.Type,
+						metric.Value(shard),
+						metric.Labels.values(i.lastClusterInfo, indexName, shardNumber, shard.Routing.Node, strconv.FormatBool(shard.Routing.Primary))...,
+					)
 				}
 			}
 		}
 	}
 }
+
+// collectsSystemIndexHealth returns whether the health of the system indices
+// is exported, which needs the index label
+func (i *Indices) collectsSystemIndexHealth() bool {
+	return i.includeSystem && i.labelMode != IndexLabelModeDrop
+}
+
+// collectSystemIndexHealth sends the health of the system indices. A failure
+// doesn't affect the index stats, so it's only logged.
+func (i *Indices) collectSystemIndexHealth(ch chan<- prometheus.Metric) {
+	health, err := i.fetchAndDecodeSystemIndexHealth()
+	if err != nil {
+		_ = level.Warn(i.logger).Log(
+			"msg", "failed to fetch and decode system index health",
+			"err", err,
+		)
+		return
+	}
+	for _, index := range health {
+		for _, color := range colors {
+			var value float64
+			if index.Health == color {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(
+				i.systemHealth,
+				prometheus.GaugeValue,
+				value,
+				i.indexStatusLabels.values(i.lastClusterInfo, index.Index, color)...,
+			)
+		}
+	}
+}
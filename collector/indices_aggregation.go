@@ -0,0 +1,794 @@
+package collector
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// indexAggregations are the values of the aggregation label of index metrics
+var indexAggregations = []string{"primaries", "total"}
+
+type indexAggregationMetric struct {
+	Type  prometheus.ValueType
+	Desc  *prometheus.Desc
+	Value func(indexStats IndexStatsIndexDetailResponse) float64
+	// Reported returns false if the value isn't part of the stats of the
+	// Elasticsearch version, the metric isn't exported then. Nil means always.
+	Reported func(indexStats IndexStatsIndexDetailResponse) bool
+	Labels   labels
+}
+
+// indexDetailForAggregation returns the primaries or total index stats for the given aggregation
+func indexDetailForAggregation(indexStats IndexStatsIndexResponse, aggregation string) IndexStatsIndexDetailResponse {
+	if aggregation == "primaries" {
+		return indexStats.Primaries
+	}
+	return indexStats.Total
+}
+
+// newIndexAggregationMetrics defines the index metrics exported with an aggregation
+// label (primaries or total) instead of separate metric names. They replace the
+// default index metrics if enabled.
+func newIndexAggregationMetrics(logger log.Logger, indexAggregationLabels labels) []*indexAggregationMetric {
+	return []*indexAggregationMetric{
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "docs"),
+				"Count of documents",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return intToFloat64(logger, "elasticsearch_index_stats_docs", indexStats.Docs.Count)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "deleted_docs"),
+				"Count of deleted documents",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Docs.Deleted)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "store_size_bytes"),
+				"Current total size of stored index data in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Store.SizeInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_count"),
+				"Current number of segments",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.Count)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_memory_bytes"),
+				"Current size of segments in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.MemoryInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_terms_memory_bytes"),
+				"Current size of terms in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return segmentsMemoryValue(indexStats.Segments.TermsMemoryInBytes)
+			},
+			Reported: func(indexStats IndexStatsIndexDetailResponse) bool {
+				return indexStats.Segments.TermsMemoryInBytes != nil
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_fields_memory_bytes"),
+				"Current size of fields in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return segmentsMemoryValue(indexStats.Segments.StoredFieldsMemoryInBytes)
+			},
+			Reported: func(indexStats IndexStatsIndexDetailResponse) bool {
+				return indexStats.Segments.StoredFieldsMemoryInBytes != nil
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_term_vectors_memory_bytes"),
+				"Current size of term vectors in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.TermVectorsMemoryInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_norms_memory_bytes"),
+				"Current size of norms in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return segmentsMemoryValue(indexStats.Segments.NormsMemoryInBytes)
+			},
+			Reported: func(indexStats IndexStatsIndexDetailResponse) bool {
+				return indexStats.Segments.NormsMemoryInBytes != nil
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_points_memory_bytes"),
+				"Current size of points in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return segmentsMemoryValue(indexStats.Segments.PointsMemoryInBytes)
+			},
+			Reported: func(indexStats IndexStatsIndexDetailResponse) bool {
+				return indexStats.Segments.PointsMemoryInBytes != nil
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_doc_values_memory_bytes"),
+				"Current size of doc values in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return segmentsMemoryValue(indexStats.Segments.DocValuesMemoryInBytes)
+			},
+			Reported: func(indexStats IndexStatsIndexDetailResponse) bool {
+				return indexStats.Segments.DocValuesMemoryInBytes != nil
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_index_writer_memory_bytes"),
+				"Current size of index writer in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.IndexWriterMemoryInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_version_map_memory_bytes"),
+				"Current size of version map in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.VersionMapMemoryInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "segment_fixed_bit_set_memory_bytes"),
+				"Current size of fixed bit in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Segments.FixedBitSetMemoryInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "completion_bytes"),
+				"Current size of completion in bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Completion.SizeInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_query_time_seconds_total")),
+				"Total search query time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Search.QueryTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "search_query_total"),
+				"Total number of queries",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Search.QueryTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_fetch_time_seconds_total")),
+				"Total search fetch time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Search.FetchTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "search_fetch_total"),
+				"Total search fetch count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Search.FetchTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_scroll_time_seconds_total")),
+				"Total search scroll time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Search.ScrollTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "search_scroll_current"),
+				"Current search scroll count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Search.ScrollCurrent)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "search_scroll_total"),
+				"Total search scroll count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Search.ScrollTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("search_suggest_time_seconds_total")),
+				"Total search suggest time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Search.SuggestTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "search_suggest_total"),
+				"Total search suggest count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Search.SuggestTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_index_time_seconds_total")),
+				"Total indexing index time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Indexing.IndexTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "indexing_index_total"),
+				"Total indexing index count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Indexing.IndexTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_delete_time_seconds_total")),
+				"Total indexing delete time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Indexing.DeleteTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "indexing_delete_total"),
+				"Total indexing delete count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Indexing.DeleteTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "indexing_noop_update_total"),
+				"Total indexing no-op update count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Indexing.NoopUpdateTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index", "indexing_delete_current"),
+				"Current number of in-flight indexing delete operations",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Indexing.DeleteCurrent)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index", "indexing_index_current"),
+				"Current number of documents being indexed",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Indexing.IndexCurrent)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("indexing_throttle_time_seconds_total")),
+				"Total indexing throttle time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Indexing.ThrottleTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("get_time_seconds_total")),
+				"Total get time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Get.TimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "get_total"),
+				"Total get count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Get.Total)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "get_current"),
+				"Current get operations",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Get.Current)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "get_exists_total"),
+				"Total get count of existing documents",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Get.ExistsTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "get_missing_total"),
+				"Total get count of missing documents",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Get.MissingTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_time_seconds_total")),
+				"Total merge time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Merges.TotalTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "merge_total"),
+				"Total merge count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Merges.Total)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_throttle_time_seconds_total")),
+				"Total merge I/O throttle time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Merges.TotalThrottledTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("merge_stopped_time_seconds_total")),
+				"Total large merge stopped time in seconds, allowing smaller merges to complete",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Merges.TotalStoppedTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "merge_auto_throttle_bytes_total"),
+				"Total bytes that were auto-throttled during merging",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Merges.TotalAutoThrottleInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("refresh_time_seconds_total")),
+				"Total refresh time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Refresh.TotalTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "refresh_total"),
+				"Total refresh count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Refresh.Total)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "refresh_external_total"),
+				"Total external refresh count, which make changes visible to searches",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Refresh.ExternalTotal)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index", timeUnitName("refresh_avg_seconds")),
+				timeUnitHelp("Average time per refresh in seconds"),
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return refreshAvgTime(indexStats.Refresh)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("flush_time_seconds_total")),
+				"Total flush time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Flush.TotalTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "flush_total"),
+				"Total flush count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Flush.Total)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", timeUnitName("warmer_time_seconds_total")),
+				"Total warmer time in seconds",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return millisToTimeUnit(indexStats.Warmer.TotalTimeInMillis)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "warmer_total"),
+				"Total warmer count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Warmer.Total)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_memory_bytes_total"),
+				"Total query cache memory bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.MemorySizeInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.GaugeValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_size"),
+				"Total query cache size",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.CacheSize)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_hits_total"),
+				"Total query cache hits count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.HitCount)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_misses_total"),
+				"Total query cache misses count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.MissCount)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_caches_total"),
+				"Total query cache caches count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.CacheCount)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "query_cache_evictions_total"),
+				"Total query cache evictions count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.QueryCache.Evictions)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "request_cache_memory_bytes_total"),
+				"Total request cache memory bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.RequestCache.MemorySizeInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "request_cache_hits_total"),
+				"Total request cache hits count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.RequestCache.HitCount)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "request_cache_misses_total"),
+				"Total request cache misses count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.RequestCache.MissCount)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "request_cache_evictions_total"),
+				"Total request cache evictions count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.RequestCache.Evictions)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "fielddata_memory_bytes_total"),
+				"Total fielddata memory bytes",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Fielddata.MemorySizeInBytes)
+			},
+			Labels: indexAggregationLabels,
+		},
+		{
+			Type: prometheus.CounterValue,
+			Desc: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, "index_stats", "fielddata_evictions_total"),
+				"Total fielddata evictions count",
+				indexAggregationLabels.keys(), nil,
+			),
+			Value: func(indexStats IndexStatsIndexDetailResponse) float64 {
+				return float64(indexStats.Fielddata.Evictions)
+			},
+			Labels: indexAggregationLabels,
+		},
+	}
+}
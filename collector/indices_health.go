@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// IndicesHealth information struct
+type IndicesHealth struct {
+	logger        log.Logger
+	client        *http.Client
+	url           *url.URL
+	includeSystem bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	health *prometheus.Desc
+	status *prometheus.Desc
+}
+
+// NewIndicesHealth defines IndicesHealth Prometheus metrics. Only the health
+// and status of every index are exported, without requesting the index stats,
+// which are expensive on large clusters. System indices are left out unless
+// includeSystem is true.
+func NewIndicesHealth(logger log.Logger, client *http.Client, url *url.URL, includeSystem bool) *IndicesHealth {
+	return &IndicesHealth{
+		logger:        logger,
+		client:        client,
+		url:           url,
+		includeSystem: includeSystem,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "indices_health", "up"),
+			Help: "Was the last scrape of the ElasticSearch index health successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "indices_health", "total_scrapes"),
+			Help: "Current total ElasticSearch index health scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "indices_health", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		health: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "health"),
+			"Whether the health of the index is the given health (green, yellow or red), omitted for closed indices",
+			[]string{"index", "health", "cluster"}, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "status"),
+			"Status of the index (open or close), always 1",
+			[]string{"index", "status", "cluster"}, nil,
+		),
+	}
+}
+
+// Describe add IndicesHealth metrics descriptions
+func (i *IndicesHealth) Describe(ch chan<- *prometheus.Desc) {
+	ch <- i.health
+	ch <- i.status
+	ch <- i.up.Desc()
+	ch <- i.totalScrapes.Desc()
+	ch <- i.jsonParseFailures.Desc()
+}
+
+func (i *IndicesHealth) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := i.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(i.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		i.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+func (i *IndicesHealth) fetchAndDecodeClusterHealth() (clusterHealthResponse, error) {
+	var chr clusterHealthResponse
+
+	u := *i.url
+	u.Path = path.Join(u.Path, "/_cluster/health")
+	q := u.Query()
+	q.Set("level", "indices")
+	q.Set("filter_path", "cluster_name,indices.*.status")
+	u.RawQuery = q.Encode()
+	err := i.getAndParseURL(&u, &chr)
+	return chr, err
+}
+
+func (i *IndicesHealth) fetchAndDecodeCatIndices() (catIndicesStatusResponse, error) {
+	var cisr catIndicesStatusResponse
+
+	u := *i.url
+	u.Path = path.Join(u.Path, "/_cat/indices")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("h", "index,health,status")
+	u.RawQuery = q.Encode()
+	err := i.getAndParseURL(&u, &cisr)
+	return cisr, err
+}
+
+// Collect gets IndicesHealth metric values
+func (i *IndicesHealth) Collect(ch chan<- prometheus.Metric) {
+	i.totalScrapes.Inc()
+	defer func() {
+		ch <- i.up
+		ch <- i.totalScrapes
+		ch <- i.jsonParseFailures
+	}()
+
+	chr, err := i.fetchAndDecodeClusterHealth()
+	if err != nil {
+		i.up.Set(0)
+		_ = level.Warn(i.logger).Log(
+			"msg", "failed to fetch and decode index health",
+			"err", err,
+		)
+		return
+	}
+	cisr, err := i.fetchAndDecodeCatIndices()
+	if err != nil {
+		i.up.Set(0)
+		_ = level.Warn(i.logger).Log(
+			"msg", "failed to fetch and decode cat indices",
+			"err", err,
+		)
+		return
+	}
+	i.up.Set(1)
+
+	for _, index := range cisr {
+		if !i.includeSystem && isSystemIndex(index.Index) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			i.status,
+			prometheus.GaugeValue,
+			1,
+			index.Index, index.Status, chr.ClusterName,
+		)
+
+		// an index created after the cluster health request only has
+		// the health of the cat indices
+		health := index.Health
+		if indexHealth, ok := chr.Indices[index.Index]; ok {
+			health = indexHealth.Status
+		}
+		if health == "" {
+			continue
+		}
+		for _, color := range colors {
+			var value float64
+			if health == color {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(
+				i.health,
+				prometheus.GaugeValue,
+				value,
+				index.Index, color, chr.ClusterName,
+			)
+		}
+	}
+}
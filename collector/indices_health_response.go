@@ -0,0 +1,9 @@
+package collector
+
+// catIndicesStatusResponse is a representation of the _cat/indices API with
+// the health and status of every index. Closed indices have no health.
+type catIndicesStatusResponse []struct {
+	Index  string `json:"index"`
+	Health string `json:"health"`
+	Status string `json:"status"`
+}
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIndicesHealth(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:7.17.9
+	//  curl -XPUT http://localhost:9200/foo_1 -H 'Content-Type: application/json' -d '{"settings":{"number_of_replicas":0}}'
+	//  curl -XPUT http://localhost:9200/foo_2
+	//  curl -XPUT http://localhost:9200/foo_3 && curl -XPOST http://localhost:9200/foo_3/_close
+	//  curl "http://localhost:9200/_cluster/health?level=indices&filter_path=cluster_name,indices.*.status"
+	//  curl "http://localhost:9200/_cat/indices?format=json&h=index,health,status"
+	health := `{"cluster_name":"elasticsearch","indices":{"foo_1":{"status":"green"},"foo_2":{"status":"yellow"},"foo_3":{"status":"yellow"},".geoip_databases":{"status":"green"}}}`
+	cat := `[{"index":"foo_1","health":"green","status":"open"},{"index":"foo_2","health":"yellow","status":"open"},{"index":"foo_3","health":"yellow","status":"close"},{"index":".geoip_databases","health":"green","status":"open"}]`
+	var statsRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cluster/health":
+			if r.URL.Query().Get("level") != "indices" {
+				http.Error(w, "wrong level", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, health)
+		case "/_cat/indices":
+			fmt.Fprintln(w, cat)
+		default:
+			statsRequests++
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndicesHealth(log.NewNopLogger(), http.DefaultClient, u, false)
+	expected := `
+# HELP elasticsearch_index_health Whether the health of the index is the given health (green, yellow or red), omitted for closed indices
+# TYPE elasticsearch_index_health gauge
+elasticsearch_index_health{cluster="elasticsearch",health="green",index="foo_1"} 1
+elasticsearch_index_health{cluster="elasticsearch",health="green",index="foo_2"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="green",index="foo_3"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="red",index="foo_1"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="red",index="foo_2"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="red",index="foo_3"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="yellow",index="foo_1"} 0
+elasticsearch_index_health{cluster="elasticsearch",health="yellow",index="foo_2"} 1
+elasticsearch_index_health{cluster="elasticsearch",health="yellow",index="foo_3"} 1
+# HELP elasticsearch_index_status Status of the index (open or close), always 1
+# TYPE elasticsearch_index_status gauge
+elasticsearch_index_status{cluster="elasticsearch",index="foo_1",status="open"} 1
+elasticsearch_index_status{cluster="elasticsearch",index="foo_2",status="open"} 1
+elasticsearch_index_status{cluster="elasticsearch",index="foo_3",status="close"} 1
+# HELP elasticsearch_indices_health_up Was the last scrape of the ElasticSearch index health successful.
+# TYPE elasticsearch_indices_health_up gauge
+elasticsearch_indices_health_up 1
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_index_health", "elasticsearch_index_status", "elasticsearch_indices_health_up"); err != nil {
+		t.Errorf("Unexpected index health metrics: %s", err)
+	}
+	// the index stats are never requested
+	if statsRequests > 0 {
+		t.Errorf("Unexpected requests for index stats: %d", statsRequests)
+	}
+}
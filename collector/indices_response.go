@@ -7,6 +7,33 @@ type indexStatsResponse struct {
 	Indices map[string]IndexStatsIndexResponse `json:"indices"`
 }
 
+// catIndicesResponse is a representation of the _cat/indices API with bytes=b,
+// sorted by store size
+type catIndicesResponse []catIndexResponse
+
+// catIndexResponse defines the status and size of a single index. Closed
+// indices are listed without any values.
+type catIndexResponse struct {
+	Index     string `json:"index"`
+	Health    string `json:"health"`
+	Status    string `json:"status"`
+	DocsCount string `json:"docs.count"`
+	StoreSize string `json:"store.size"`
+}
+
+// catIndicesHealthResponse is a representation of the _cat/indices API with
+// the health of every index
+type catIndicesHealthResponse []struct {
+	Index  string `json:"index"`
+	Health string `json:"health"`
+}
+
+// indicesFrozenSettingsResponse is a representation of the flat settings of
+// every index which mark it as frozen
+type indicesFrozenSettingsResponse map[string]struct {
+	Settings map[string]string `json:"settings"`
+}
+
 // IndexStatsShardsResponse defines index stats shards information structure
 type IndexStatsShardsResponse struct {
 	Total      int64 `json:"total"`
@@ -105,6 +132,8 @@ type IndexStatsIndexSearchResponse struct {
 	SuggestTotal        int64 `json:"suggest_total"`
 	SuggestTimeInMillis int64 `json:"suggest_time_in_millis"`
 	SuggestCurrent      int64 `json:"suggest_current"`
+	// the stats of the search groups requested with the groups parameter
+	Groups map[string]IndexStatsIndexSearchResponse `json:"groups"`
 }
 
 // IndexStatsIndexMergesResponse defines index stats index merges information structure
@@ -125,6 +154,7 @@ type IndexStatsIndexMergesResponse struct {
 type IndexStatsIndexRefreshResponse struct {
 	Total             int64 `json:"total"`
 	TotalTimeInMillis int64 `json:"total_time_in_millis"`
+	ExternalTotal     int64 `json:"external_total"`
 	Listeners         int64 `json:"listeners"`
 }
 
@@ -165,18 +195,19 @@ type IndexStatsIndexCompletionResponse struct {
 
 // IndexStatsIndexSegmentsResponse defines index stats index segments information structure
 type IndexStatsIndexSegmentsResponse struct {
-	Count                     int64 `json:"count"`
-	MemoryInBytes             int64 `json:"memory_in_bytes"`
-	TermsMemoryInBytes        int64 `json:"terms_memory_in_bytes"`
-	StoredFieldsMemoryInBytes int64 `json:"stored_fields_memory_in_bytes"`
-	TermVectorsMemoryInBytes  int64 `json:"term_vectors_memory_in_bytes"`
-	NormsMemoryInBytes        int64 `json:"norms_memory_in_bytes"`
-	PointsMemoryInBytes       int64 `json:"points_memory_in_bytes"`
-	DocValuesMemoryInBytes    int64 `json:"doc_values_memory_in_bytes"`
-	IndexWriterMemoryInBytes  int64 `json:"index_writer_memory_in_bytes"`
-	VersionMapMemoryInBytes   int64 `json:"version_map_memory_in_bytes"`
-	FixedBitSetMemoryInBytes  int64 `json:"fixed_bit_set_memory_in_bytes"`
-	MaxUnsafeAutoIDTimestamp  int64 `json:"max_unsafe_auto_id_timestamp"`
+	Count                    int64 `json:"count"`
+	MemoryInBytes            int64 `json:"memory_in_bytes"`
+	TermVectorsMemoryInBytes int64 `json:"term_vectors_memory_in_bytes"`
+	IndexWriterMemoryInBytes int64 `json:"index_writer_memory_in_bytes"`
+	VersionMapMemoryInBytes  int64 `json:"version_map_memory_in_bytes"`
+	FixedBitSetMemoryInBytes int64 `json:"fixed_bit_set_memory_in_bytes"`
+	MaxUnsafeAutoIDTimestamp int64 `json:"max_unsafe_auto_id_timestamp"`
+	// the memory breakdown isn't reported by Elasticsearch 8.x anymore
+	TermsMemoryInBytes        *int64 `json:"terms_memory_in_bytes"`
+	StoredFieldsMemoryInBytes *int64 `json:"stored_fields_memory_in_bytes"`
+	NormsMemoryInBytes        *int64 `json:"norms_memory_in_bytes"`
+	PointsMemoryInBytes       *int64 `json:"points_memory_in_bytes"`
+	DocValuesMemoryInBytes    *int64 `json:"doc_values_memory_in_bytes"`
 }
 
 // IndexStatsIndexTranslogResponse defines index stats index translog information structure
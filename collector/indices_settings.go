@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -17,18 +18,26 @@ type IndicesSettings struct {
 	logger log.Logger
 	client *http.Client
 	url    *url.URL
+	keys   []string
 
 	up                              prometheus.Gauge
 	readOnlyIndices                 prometheus.Gauge
+	searchableSnapshotIndices       prometheus.Gauge
 	totalScrapes, jsonParseFailures prometheus.Counter
+	settingValue                    *prometheus.Desc
+	routingShards                   *prometheus.Desc
+	shardsConfigured                *prometheus.Desc
+	splitFactor                     *prometheus.Desc
 }
 
-// NewIndicesSettings defines Indices Settings Prometheus metrics
-func NewIndicesSettings(logger log.Logger, client *http.Client, url *url.URL) *IndicesSettings {
+// NewIndicesSettings defines Indices Settings Prometheus metrics. If keys is
+// not empty, the value of each of these settings is exported per index.
+func NewIndicesSettings(logger log.Logger, client *http.Client, url *url.URL, keys []string) *IndicesSettings {
 	return &IndicesSettings{
 		logger: logger,
 		client: client,
 		url:    url,
+		keys:   keys,
 
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: prometheus.BuildFQName(namespace, "indices_settings_stats", "up"),
@@ -42,10 +51,34 @@ func NewIndicesSettings(logger log.Logger, client *http.Client, url *url.URL) *I
 			Name: prometheus.BuildFQName(namespace, "indices_settings_stats", "read_only_indices"),
 			Help: "Current number of read only indices within cluster",
 		}),
+		searchableSnapshotIndices: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "searchable_snapshot", "indices_total"),
+			Help: "Current number of indices backed by searchable snapshots within cluster",
+		}),
 		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, "indices_settings_stats", "json_parse_failures"),
 			Help: "Number of errors while parsing JSON.",
 		}),
+		settingValue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "indices_settings", "value"),
+			"Value of an index setting selected with es.indices_settings.keys, booleans are exported as 0 or 1",
+			[]string{"index", "setting"}, nil,
+		),
+		routingShards: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "routing_shards"),
+			"Configured number of routing shards (index.number_of_routing_shards) of the index",
+			[]string{"index"}, nil,
+		),
+		shardsConfigured: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "shards_configured"),
+			"Configured number of primary shards (index.number_of_shards) of the index",
+			[]string{"index"}, nil,
+		),
+		splitFactor: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "split_factor"),
+			"Number of routing shards per primary shard, the index can be split into a multiple of its shards by a factor of this value",
+			[]string{"index"}, nil,
+		),
 	}
 }
 
@@ -54,7 +87,12 @@ func (cs *IndicesSettings) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cs.up.Desc()
 	ch <- cs.totalScrapes.Desc()
 	ch <- cs.readOnlyIndices.Desc()
+	ch <- cs.searchableSnapshotIndices.Desc()
 	ch <- cs.jsonParseFailures.Desc()
+	ch <- cs.settingValue
+	ch <- cs.routingShards
+	ch <- cs.shardsConfigured
+	ch <- cs.splitFactor
 }
 
 func (cs *IndicesSettings) getAndParseURL(u *url.URL, data interface{}) error {
@@ -80,6 +118,7 @@ func (cs *IndicesSettings) getAndParseURL(u *url.URL, data interface{}) error {
 
 	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
 		cs.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
 		return err
 	}
 	return nil
@@ -107,11 +146,13 @@ func (cs *IndicesSettings) Collect(ch chan<- prometheus.Metric) {
 		ch <- cs.totalScrapes
 		ch <- cs.jsonParseFailures
 		ch <- cs.readOnlyIndices
+		ch <- cs.searchableSnapshotIndices
 	}()
 
 	asr, err := cs.fetchAndDecodeIndicesSettings()
 	if err != nil {
 		cs.readOnlyIndices.Set(0)
+		cs.searchableSnapshotIndices.Set(0)
 		cs.up.Set(0)
 		_ = level.Warn(cs.logger).Log(
 			"msg", "failed to fetch and decode cluster settings stats",
@@ -121,11 +162,61 @@ func (cs *IndicesSettings) Collect(ch chan<- prometheus.Metric) {
 	}
 	cs.up.Set(1)
 
-	var c int
+	var c, snapshots int
 	for _, value := range asr {
 		if value.Settings.IndexInfo.Blocks.ReadOnly == "true" {
 			c++
 		}
+		// searchable snapshot indices, including the partially mounted ones
+		// of the frozen tier, use the snapshot store type
+		if value.Settings.IndexInfo.Flat["store.type"] == "snapshot" {
+			snapshots++
+		}
 	}
 	cs.readOnlyIndices.Set(float64(c))
+	cs.searchableSnapshotIndices.Set(float64(snapshots))
+
+	for index, value := range asr {
+		shards, hasShards := parseSettingValue(value.Settings.IndexInfo.Flat["number_of_shards"])
+		if hasShards {
+			ch <- prometheus.MustNewConstMetric(cs.shardsConfigured, prometheus.GaugeValue, shards, index)
+		}
+		// the number of routing shards is only part of the settings if it
+		// was set explicitly when creating the index
+		routingShards, hasRoutingShards := parseSettingValue(value.Settings.IndexInfo.Flat["number_of_routing_shards"])
+		if hasRoutingShards {
+			ch <- prometheus.MustNewConstMetric(cs.routingShards, prometheus.GaugeValue, routingShards, index)
+		}
+		if hasShards && hasRoutingShards && shards > 0 {
+			ch <- prometheus.MustNewConstMetric(cs.splitFactor, prometheus.GaugeValue, routingShards/shards, index)
+		}
+
+		for _, key := range cs.keys {
+			setting, ok := value.Settings.IndexInfo.Flat[key]
+			if !ok {
+				continue
+			}
+			v, ok := parseSettingValue(setting)
+			if !ok {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(cs.settingValue, prometheus.GaugeValue, v, index, key)
+		}
+	}
+}
+
+// parseSettingValue converts numeric and boolean settings to a float64.
+// Any other setting can't be exported as a metric value.
+func parseSettingValue(setting string) (float64, bool) {
+	switch setting {
+	case "true":
+		return 1, true
+	case "false":
+		return 0, true
+	}
+	v, err := strconv.ParseFloat(setting, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
 }
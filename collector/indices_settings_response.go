@@ -1,5 +1,10 @@
 package collector
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // IndicesSettingsResponse is a representation of Elasticsearch Settings for each Index
 type IndicesSettingsResponse map[string]Index
 
@@ -16,6 +21,43 @@ type Settings struct {
 // IndexInfo defines the blocks of the current index
 type IndexInfo struct {
 	Blocks Blocks `json:"blocks"`
+	// Flat holds all index settings keyed by their dotted name,
+	// e.g. "blocks.read_only" or "number_of_replicas"
+	Flat map[string]string `json:"-"`
+}
+
+// UnmarshalJSON decodes the typed index settings and additionally
+// flattens all settings into IndexInfo.Flat
+func (ii *IndexInfo) UnmarshalJSON(data []byte) error {
+	type indexInfo IndexInfo
+	var typed indexInfo
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*ii = IndexInfo(typed)
+	ii.Flat = make(map[string]string)
+	flattenSettings("", raw, ii.Flat)
+	return nil
+}
+
+func flattenSettings(prefix string, raw map[string]interface{}, flat map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenSettings(key, v, flat)
+		case string:
+			flat[key] = v
+		default:
+			flat[key] = fmt.Sprint(v)
+		}
+	}
 }
 
 // Blocks defines whether current index has read_only_allow_delete enabled
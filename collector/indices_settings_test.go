@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestIndicesSettings(t *testing.T) {
@@ -52,7 +54,7 @@ func TestIndicesSettings(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to parse URL: %s", err)
 			}
-			c := NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u)
+			c := NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, nil)
 			nsr, err := c.fetchAndDecodeIndicesSettings()
 			if err != nil {
 				t.Fatalf("Failed to fetch or decode indices settings: %s", err)
@@ -76,3 +78,97 @@ func TestIndicesSettings(t *testing.T) {
 		}
 	}
 }
+
+func TestIndicesSettingsKeys(t *testing.T) {
+	// Testcase created using the same indices as TestIndicesSettings:
+	// curl http://localhost:9200/_all/_settings
+	out := `{"viber":{"settings":{"index":{"creation_date":"1548066996192","number_of_shards":"5","number_of_replicas":"1","uuid":"kt2cGV-yQRaloESpqj2zsg","version":{"created":"6050499"},"provided_name":"viber"}}},"twitter":{"settings":{"index":{"number_of_shards":"5","blocks":{"read_only_allow_delete":"true"},"provided_name":"twitter","creation_date":"1548066697559","number_of_replicas":"2","uuid":"-sqtc4fVRrS2jHJCZ2hQ9Q","version":{"created":"6050499"}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+
+	c := NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, nil)
+	// 5 collector metrics and the configured shards of both indices
+	if n := testutil.CollectAndCount(c); n != 7 {
+		t.Errorf("Without keys no per index settings should be exported, got %d metrics", n)
+	}
+
+	c = NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, []string{"number_of_replicas", "blocks.read_only_allow_delete", "provided_name"})
+	expected := `
+# HELP elasticsearch_indices_settings_value Value of an index setting selected with es.indices_settings.keys, booleans are exported as 0 or 1
+# TYPE elasticsearch_indices_settings_value gauge
+elasticsearch_indices_settings_value{index="twitter",setting="blocks.read_only_allow_delete"} 1
+elasticsearch_indices_settings_value{index="twitter",setting="number_of_replicas"} 2
+elasticsearch_indices_settings_value{index="viber",setting="number_of_replicas"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_indices_settings_value"); err != nil {
+		t.Errorf("Unexpected index settings metrics: %s", err)
+	}
+}
+
+func TestIndicesSettingsSearchableSnapshots(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.12.0
+	//  curl -XPOST "http://localhost:9200/_snapshot/backups/snap-1/_mount?storage=full_copy" --header "Content-Type: application/json" -d '{"index": "logs-1"}'
+	//  curl -XPOST "http://localhost:9200/_snapshot/backups/snap-1/_mount?storage=shared_cache" --header "Content-Type: application/json" -d '{"index": "logs-2"}'
+	//  curl "http://localhost:9200/_all/_settings?filter_path=*.settings.index.store,*.settings.index.blocks"
+	out := `{"logs-3":{"settings":{"index":{"number_of_replicas":"1"}}},"logs-1":{"settings":{"index":{"store":{"type":"snapshot","snapshot":{"snapshot_name":"snap-1","index_name":"logs-1","repository_name":"backups"}}}}},"partial-logs-2":{"settings":{"index":{"store":{"type":"snapshot","snapshot":{"snapshot_name":"snap-1","index_name":"logs-2","partial":"true","repository_name":"backups"}},"blocks":{"write":"true"}}}},"logs-4":{"settings":{"index":{"store":{"type":"fs"}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, nil)
+	expected := `
+# HELP elasticsearch_searchable_snapshot_indices_total Current number of indices backed by searchable snapshots within cluster
+# TYPE elasticsearch_searchable_snapshot_indices_total gauge
+elasticsearch_searchable_snapshot_indices_total 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_searchable_snapshot_indices_total"); err != nil {
+		t.Errorf("Unexpected searchable snapshot indices: %s", err)
+	}
+}
+
+func TestIndicesSettingsSplitFactor(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.6.0
+	//  curl -XPUT "http://localhost:9200/logs-1" --header "Content-Type: application/json" -d '{"settings":{"index":{"number_of_shards":2,"number_of_routing_shards":16}}}'
+	//  curl -XPUT "http://localhost:9200/logs-2" --header "Content-Type: application/json" -d '{"settings":{"index":{"number_of_shards":3}}}'
+	//  curl "http://localhost:9200/_all/_settings?filter_path=*.settings.index.number_of_shards,*.settings.index.number_of_routing_shards"
+	out := `{"logs-1":{"settings":{"index":{"number_of_shards":"2","number_of_routing_shards":"16"}}},"logs-2":{"settings":{"index":{"number_of_shards":"3"}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewIndicesSettings(log.NewNopLogger(), http.DefaultClient, u, nil)
+	expected := `
+# HELP elasticsearch_index_routing_shards Configured number of routing shards (index.number_of_routing_shards) of the index
+# TYPE elasticsearch_index_routing_shards gauge
+elasticsearch_index_routing_shards{index="logs-1"} 16
+# HELP elasticsearch_index_shards_configured Configured number of primary shards (index.number_of_shards) of the index
+# TYPE elasticsearch_index_shards_configured gauge
+elasticsearch_index_shards_configured{index="logs-1"} 2
+elasticsearch_index_shards_configured{index="logs-2"} 3
+# HELP elasticsearch_index_split_factor Number of routing shards per primary shard, the index can be split into a multiple of its shards by a factor of this value
+# TYPE elasticsearch_index_split_factor gauge
+elasticsearch_index_split_factor{index="logs-1"} 8
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_index_routing_shards", "elasticsearch_index_shards_configured", "elasticsearch_index_split_factor"); err != nil {
+		t.Errorf("Unexpected split metrics: %s", err)
+	}
+}
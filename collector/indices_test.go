@@ -1,13 +1,21 @@
 package collector
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/blang/semver"
 	"github.com/go-kit/kit/log"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestIndices(t *testing.T) {
@@ -35,8 +43,11 @@ func TestIndices(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to parse URL: %s", err)
 		}
-		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false)
-		stats, err := i.fetchAndDecodeIndexStats()
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		stats := indexStatsResponse{Indices: make(map[string]IndexStatsIndexResponse)}
+		stats.All, err = i.fetchAndDecodeIndexStats(func(indexName string, indexStats IndexStatsIndexResponse) {
+			stats.Indices[indexName] = indexStats
+		})
 		if err != nil {
 			t.Fatalf("Failed to fetch or decode indices stats: %s", err)
 		}
@@ -58,3 +69,922 @@ func TestIndices(t *testing.T) {
 		}
 	}
 }
+
+func TestIndicesAggregationLabel(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/foo_1/type1/1 -d '{"title":"abc","content":"hello"}'
+	//  curl -XPUT http://localhost:9200/foo_1/type1/2 -d '{"title":"def","content":"world"}'
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.primaries.docs,indices.*.total.docs"
+	out := `{"indices":{"foo_1":{"primaries":{"docs":{"count":2,"deleted":0}},"total":{"docs":{"count":4,"deleted":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, true, IndexLabelModeFull, 0, false, nil, 0, false, false))
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather index metrics: %s", err)
+	}
+
+	docs := map[string]float64{}
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), "elasticsearch_indices_docs") {
+			t.Errorf("Unexpected metric %s with aggregation label enabled", mf.GetName())
+		}
+		if mf.GetName() != "elasticsearch_index_stats_docs" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "aggregation" {
+					docs[l.GetValue()] = m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	if docs["primaries"] != 2 {
+		t.Errorf("Wrong number of primary docs: %v", docs)
+	}
+	if docs["total"] != 4 {
+		t.Errorf("Wrong number of total docs: %v", docs)
+	}
+}
+
+func TestIndicesIndexingDeleteCurrent(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_delete_by_query -d '{"query":{"match_all":{}}}' &
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.total.indexing"
+	out := `{"indices":{"foo_1":{"total":{"indexing":{"index_total":5,"index_time_in_millis":52,"index_current":0,"index_failed":0,"delete_total":120,"delete_time_in_millis":35,"delete_current":3,"noop_update_total":0,"is_throttled":false,"throttle_time_in_millis":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_indexing_delete_current Current number of in-flight indexing delete operations
+# TYPE elasticsearch_index_indexing_delete_current gauge
+elasticsearch_index_indexing_delete_current{cluster="unknown_cluster",index="foo_1"} 3
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_indexing_delete_current"); err != nil {
+		t.Errorf("Unexpected indexing delete current metric: %s", err)
+	}
+}
+
+func TestIndicesRefreshAvg(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/foo_1
+	//  curl -XPUT http://localhost:9200/foo_2
+	//  curl -XPOST http://localhost:9200/foo_1/_refresh
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.total.refresh"
+	out := `{"indices":{"foo_1":{"total":{"refresh":{"total":8,"total_time_in_millis":1200,"listeners":0}}},"foo_2":{"total":{"refresh":{"total":0,"total_time_in_millis":0,"listeners":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	defer func() {
+		TimeUnit = TimeUnitSeconds
+	}()
+	for _, tc := range []struct {
+		unit     string
+		name     string
+		expected string
+	}{
+		{TimeUnitSeconds, "elasticsearch_index_refresh_avg_seconds", `
+# HELP elasticsearch_index_refresh_avg_seconds Average time per refresh in seconds
+# TYPE elasticsearch_index_refresh_avg_seconds gauge
+elasticsearch_index_refresh_avg_seconds{cluster="unknown_cluster",index="foo_1"} 0.15
+elasticsearch_index_refresh_avg_seconds{cluster="unknown_cluster",index="foo_2"} 0
+`},
+		{TimeUnitMillis, "elasticsearch_index_refresh_avg_millis", `
+# HELP elasticsearch_index_refresh_avg_millis Average time per refresh in milliseconds
+# TYPE elasticsearch_index_refresh_avg_millis gauge
+elasticsearch_index_refresh_avg_millis{cluster="unknown_cluster",index="foo_1"} 150
+elasticsearch_index_refresh_avg_millis{cluster="unknown_cluster",index="foo_2"} 0
+`},
+	} {
+		// the unit applies to collectors created after setting it
+		TimeUnit = tc.unit
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(tc.expected), tc.name); err != nil {
+			t.Errorf("Unexpected refresh average metric in %s: %s", tc.unit, err)
+		}
+	}
+}
+
+func TestIndicesIndexingIndexCurrent(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk.json &
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.total.indexing"
+	out := `{"indices":{"foo_1":{"total":{"indexing":{"index_total":5012,"index_time_in_millis":4382,"index_current":42,"index_failed":0,"delete_total":0,"delete_time_in_millis":0,"delete_current":0,"noop_update_total":0,"is_throttled":false,"throttle_time_in_millis":0}}},"foo_2":{"total":{"indexing":{"index_total":3,"index_time_in_millis":12,"index_current":0,"index_failed":0,"delete_total":0,"delete_time_in_millis":0,"delete_current":0,"noop_update_total":0,"is_throttled":false,"throttle_time_in_millis":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_indexing_index_current Current number of documents being indexed
+# TYPE elasticsearch_index_indexing_index_current gauge
+elasticsearch_index_indexing_index_current{cluster="unknown_cluster",index="foo_1"} 42
+elasticsearch_index_indexing_index_current{cluster="unknown_cluster",index="foo_2"} 0
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_indexing_index_current"); err != nil {
+		t.Errorf("Unexpected indexing index current metric: %s", err)
+	}
+}
+
+func TestIndicesLabelMode(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl -XPOST http://localhost:9200/foo_2/_bulk --data-binary @bulk_2.json
+	//  curl -XPOST http://localhost:9200/foo_3/_bulk --data-binary @bulk_3.json
+	//  curl "http://localhost:9200/_all/_stats?filter_path=_all.*.indexing.index_total,indices.*.*.indexing.index_total"
+	out := `{"_all":{"primaries":{"indexing":{"index_total":60}},"total":{"indexing":{"index_total":120}}},"indices":{"foo_1":{"primaries":{"indexing":{"index_total":10}},"total":{"indexing":{"index_total":20}}},"foo_2":{"primaries":{"indexing":{"index_total":20}},"total":{"indexing":{"index_total":40}}},"foo_3":{"primaries":{"indexing":{"index_total":30}},"total":{"indexing":{"index_total":60}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+
+	for labelMode, expected := range map[string]string{
+		IndexLabelModeFull: `
+# HELP elasticsearch_index_stats_indexing_index_total Total indexing index count
+# TYPE elasticsearch_index_stats_indexing_index_total counter
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="foo_1"} 20
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="foo_2"} 40
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="foo_3"} 60
+`,
+		IndexLabelModeHashed: `
+# HELP elasticsearch_index_stats_indexing_index_total Total indexing index count
+# TYPE elasticsearch_index_stats_indexing_index_total counter
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="93332dec2f92"} 20
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="90bd987c72c4"} 40
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="6af9a8c7a6ca"} 60
+`,
+		IndexLabelModeDrop: `
+# HELP elasticsearch_index_stats_indexing_index_total Total indexing index count
+# TYPE elasticsearch_index_stats_indexing_index_total counter
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster"} 120
+`,
+	} {
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, labelMode, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_stats_indexing_index_total"); err != nil {
+			t.Errorf("Unexpected index metrics in label mode %s: %s", labelMode, err)
+		}
+	}
+
+	// the aggregation label is kept if the index label is dropped
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, true, IndexLabelModeDrop, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_stats_indexing_index_total Total indexing index count
+# TYPE elasticsearch_index_stats_indexing_index_total counter
+elasticsearch_index_stats_indexing_index_total{aggregation="primaries",cluster="unknown_cluster"} 60
+elasticsearch_index_stats_indexing_index_total{aggregation="total",cluster="unknown_cluster"} 120
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_stats_indexing_index_total"); err != nil {
+		t.Errorf("Unexpected aggregated index metrics in label mode %s: %s", IndexLabelModeDrop, err)
+	}
+}
+
+func TestIndicesTopN(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl -XPOST http://localhost:9200/foo_2/_bulk --data-binary @bulk_2.json
+	//  curl -XPOST http://localhost:9200/foo_3/_bulk --data-binary @bulk_3.json
+	//  curl -XPOST http://localhost:9200/foo_4/_close
+	//  curl "http://localhost:9200/_cat/indices?format=json&bytes=b&h=index,docs.count,store.size&s=store.size:desc"
+	//  curl "http://localhost:9200/foo_3,foo_2/_stats?filter_path=indices.*.*.indexing.index_total"
+	catIndices := `[{"index":"foo_3","docs.count":"30","store.size":"30000"},{"index":"foo_2","docs.count":"20","store.size":"20000"},{"index":"foo_1","docs.count":"10","store.size":"10000"},{"index":"foo_4","docs.count":null,"store.size":null}]`
+	stats := `{"indices":{"foo_2":{"primaries":{"indexing":{"index_total":20}},"total":{"indexing":{"index_total":40}}},"foo_3":{"primaries":{"indexing":{"index_total":30}},"total":{"indexing":{"index_total":60}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/indices":
+			fmt.Fprintln(w, catIndices)
+		case "/foo_3,foo_2/_stats":
+			fmt.Fprintln(w, stats)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 2, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_stats_indexing_index_total Total indexing index count
+# TYPE elasticsearch_index_stats_indexing_index_total counter
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="foo_2"} 40
+elasticsearch_index_stats_indexing_index_total{cluster="unknown_cluster",index="foo_3"} 60
+# HELP elasticsearch_index_stats_up Was the last scrape of the ElasticSearch index endpoint successful.
+# TYPE elasticsearch_index_stats_up gauge
+elasticsearch_index_stats_up 1
+# HELP elasticsearch_indices_other_docs Count of documents of the indices which are not among the largest indices exported in detail
+# TYPE elasticsearch_indices_other_docs gauge
+elasticsearch_indices_other_docs{cluster="unknown_cluster"} 10
+# HELP elasticsearch_indices_other_indices Number of indices which are not among the largest indices exported in detail
+# TYPE elasticsearch_indices_other_indices gauge
+elasticsearch_indices_other_indices{cluster="unknown_cluster"} 2
+# HELP elasticsearch_indices_other_store_size_bytes Store size of the indices which are not among the largest indices exported in detail
+# TYPE elasticsearch_indices_other_store_size_bytes gauge
+elasticsearch_indices_other_store_size_bytes{cluster="unknown_cluster"} 10000
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_index_stats_indexing_index_total", "elasticsearch_index_stats_up",
+		"elasticsearch_indices_other_docs", "elasticsearch_indices_other_indices", "elasticsearch_indices_other_store_size_bytes"); err != nil {
+		t.Errorf("Unexpected top N index metrics: %s", err)
+	}
+}
+
+func TestIndicesGet(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl http://localhost:9200/foo_1/_doc/1
+	//  curl http://localhost:9200/foo_1/_doc/unknown
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.*.get"
+	out := `{"indices":{"foo_1":{"primaries":{"get":{"total":3,"time_in_millis":2,"exists_total":2,"exists_time_in_millis":2,"missing_total":1,"missing_time_in_millis":0,"current":0}},"total":{"get":{"total":3,"time_in_millis":2,"exists_total":2,"exists_time_in_millis":2,"missing_total":1,"missing_time_in_millis":0,"current":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_stats_get_current Current get operations
+# TYPE elasticsearch_index_stats_get_current gauge
+elasticsearch_index_stats_get_current{cluster="unknown_cluster",index="foo_1"} 0
+# HELP elasticsearch_index_stats_get_exists_total Total get count of existing documents
+# TYPE elasticsearch_index_stats_get_exists_total counter
+elasticsearch_index_stats_get_exists_total{cluster="unknown_cluster",index="foo_1"} 2
+# HELP elasticsearch_index_stats_get_missing_total Total get count of missing documents
+# TYPE elasticsearch_index_stats_get_missing_total counter
+elasticsearch_index_stats_get_missing_total{cluster="unknown_cluster",index="foo_1"} 1
+# HELP elasticsearch_index_stats_get_time_seconds_total Total get time in seconds
+# TYPE elasticsearch_index_stats_get_time_seconds_total counter
+elasticsearch_index_stats_get_time_seconds_total{cluster="unknown_cluster",index="foo_1"} 0.002
+# HELP elasticsearch_index_stats_get_total Total get count
+# TYPE elasticsearch_index_stats_get_total counter
+elasticsearch_index_stats_get_total{cluster="unknown_cluster",index="foo_1"} 3
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_index_stats_get_current", "elasticsearch_index_stats_get_exists_total", "elasticsearch_index_stats_get_missing_total",
+		"elasticsearch_index_stats_get_time_seconds_total", "elasticsearch_index_stats_get_total"); err != nil {
+		t.Errorf("Unexpected index get metrics: %s", err)
+	}
+}
+
+func TestIndicesSearchGroups(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl -XPOST http://localhost:9200/foo_1/_search -d '{"stats":["dashboard"],"query":{"match_all":{}}}'
+	//  curl -XPOST http://localhost:9200/foo_1/_search -d '{"stats":["reports"],"query":{"match_all":{}}}'
+	//  curl "http://localhost:9200/_all/_stats?groups=dashboard,reports&filter_path=_all.total.search.groups,indices.*.total.search.groups"
+	out := `{"_all":{"total":{"search":{"groups":{"dashboard":{"query_total":12,"query_time_in_millis":340,"query_current":0,"fetch_total":12,"fetch_time_in_millis":8,"fetch_current":0,"scroll_total":0,"scroll_time_in_millis":0,"scroll_current":0,"suggest_total":0,"suggest_time_in_millis":0,"suggest_current":0},"reports":{"query_total":3,"query_time_in_millis":5200,"query_current":1,"fetch_total":2,"fetch_time_in_millis":90,"fetch_current":0,"scroll_total":0,"scroll_time_in_millis":0,"scroll_current":0,"suggest_total":0,"suggest_time_in_millis":0,"suggest_current":0}}}}},"indices":{"foo_1":{"total":{"search":{"groups":{"dashboard":{"query_total":12,"query_time_in_millis":340,"query_current":0,"fetch_total":12,"fetch_time_in_millis":8,"fetch_current":0,"scroll_total":0,"scroll_time_in_millis":0,"scroll_current":0,"suggest_total":0,"suggest_time_in_millis":0,"suggest_current":0},"reports":{"query_total":3,"query_time_in_millis":5200,"query_current":1,"fetch_total":2,"fetch_time_in_millis":90,"fetch_current":0,"scroll_total":0,"scroll_time_in_millis":0,"scroll_current":0,"suggest_total":0,"suggest_time_in_millis":0,"suggest_current":0}}}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if groups := r.URL.Query().Get("groups"); groups != "dashboard,reports" {
+			t.Errorf("Unexpected search groups %q requested", groups)
+		}
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	defer func() {
+		TimeUnit = TimeUnitSeconds
+	}()
+	for _, tc := range []struct {
+		labelMode string
+		unit      string
+		names     []string
+		expected  string
+	}{
+		{IndexLabelModeFull, TimeUnitSeconds, []string{"elasticsearch_index_search_group_query_time_seconds_total", "elasticsearch_index_search_group_query_total"}, `
+# HELP elasticsearch_index_search_group_query_time_seconds_total Total search query time of the search group in seconds
+# TYPE elasticsearch_index_search_group_query_time_seconds_total counter
+elasticsearch_index_search_group_query_time_seconds_total{cluster="unknown_cluster",group="dashboard",index="foo_1"} 0.34
+elasticsearch_index_search_group_query_time_seconds_total{cluster="unknown_cluster",group="reports",index="foo_1"} 5.2
+# HELP elasticsearch_index_search_group_query_total Total number of search queries of the search group
+# TYPE elasticsearch_index_search_group_query_total counter
+elasticsearch_index_search_group_query_total{cluster="unknown_cluster",group="dashboard",index="foo_1"} 12
+elasticsearch_index_search_group_query_total{cluster="unknown_cluster",group="reports",index="foo_1"} 3
+`},
+		{IndexLabelModeDrop, TimeUnitMillis, []string{"elasticsearch_index_search_group_query_time_millis_total", "elasticsearch_index_search_group_query_total"}, `
+# HELP elasticsearch_index_search_group_query_time_millis_total Total search query time of the search group in milliseconds
+# TYPE elasticsearch_index_search_group_query_time_millis_total counter
+elasticsearch_index_search_group_query_time_millis_total{cluster="unknown_cluster",group="dashboard"} 340
+elasticsearch_index_search_group_query_time_millis_total{cluster="unknown_cluster",group="reports"} 5200
+# HELP elasticsearch_index_search_group_query_total Total number of search queries of the search group
+# TYPE elasticsearch_index_search_group_query_total counter
+elasticsearch_index_search_group_query_total{cluster="unknown_cluster",group="dashboard"} 12
+elasticsearch_index_search_group_query_total{cluster="unknown_cluster",group="reports"} 3
+`},
+	} {
+		// the unit applies to collectors created after setting it
+		TimeUnit = tc.unit
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, tc.labelMode, 0, false, []string{"dashboard", "reports"}, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(tc.expected), tc.names...); err != nil {
+			t.Errorf("[%s] Unexpected search group metrics: %s", tc.labelMode, err)
+		}
+	}
+}
+
+func TestIndicesOpenOnly(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl -XPOST http://localhost:9200/foo_2/_bulk --data-binary @bulk_2.json
+	//  curl -XPOST http://localhost:9200/foo_2/_close
+	//  curl "http://localhost:9200/_cat/indices?format=json&bytes=b&h=index,status,docs.count,store.size&s=store.size:desc"
+	//  curl "http://localhost:9200/_all/_stats?expand_wildcards=all&filter_path=indices.*.*.docs"
+	catIndices := `[{"index":"foo_1","status":"open","docs.count":"10","store.size":"10000"},{"index":"foo_2","status":"close","docs.count":null,"store.size":null}]`
+	stats := `{"indices":{"foo_1":{"primaries":{"docs":{"count":10,"deleted":0}},"total":{"docs":{"count":10,"deleted":0}}},"foo_2":{"primaries":{"docs":{"count":0,"deleted":0}},"total":{"docs":{"count":0,"deleted":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/indices":
+			fmt.Fprintln(w, catIndices)
+		case "/_all/_stats":
+			fmt.Fprintln(w, stats)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, true, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_status Status of the index (open or close), always 1
+# TYPE elasticsearch_index_status gauge
+elasticsearch_index_status{cluster="unknown_cluster",index="foo_1",status="open"} 1
+elasticsearch_index_status{cluster="unknown_cluster",index="foo_2",status="close"} 1
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_1"} 10
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_status", "elasticsearch_indices_docs_primary"); err != nil {
+		t.Errorf("Unexpected open index metrics: %s", err)
+	}
+}
+
+func TestIndicesExcludeFrozen(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:7.10.0
+	//  curl -XPOST http://localhost:9200/foo_N/_bulk --data-binary @bulk_N.json
+	//  curl -XPOST http://localhost:9200/foo_2/_freeze
+	//  curl -XPOST "http://localhost:9200/_snapshot/repo/snap/_mount?storage=shared_cache" -d '{"index":"foo_3"}'
+	//  curl "http://localhost:9200/_cat/indices?format=json&bytes=b&h=index,health,status,docs.count,store.size&s=store.size:desc"
+	//  curl "http://localhost:9200/_all/_settings/index.frozen,index.store.snapshot.partial?flat_settings=true"
+	//  curl "http://localhost:9200/foo_1/_stats?filter_path=indices.*.*.docs"
+	catIndices := `[{"index":"foo_3","health":"green","status":"open","docs.count":"30","store.size":"30000"},{"index":"foo_2","health":"yellow","status":"open","docs.count":"20","store.size":"20000"},{"index":"foo_1","health":"green","status":"open","docs.count":"10","store.size":"10000"}]`
+	settings := `{"foo_1":{"settings":{}},"foo_2":{"settings":{"index.frozen":"true"}},"foo_3":{"settings":{"index.store.snapshot.partial":"true"}}}`
+	stats := `{"indices":{"foo_1":{"primaries":{"docs":{"count":10,"deleted":0}},"total":{"docs":{"count":10,"deleted":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/indices":
+			fmt.Fprintln(w, catIndices)
+		case "/_all/_settings/index.frozen,index.store.snapshot.partial":
+			fmt.Fprintln(w, settings)
+		case "/foo_1/_stats":
+			fmt.Fprintln(w, stats)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, true)
+	expected := `
+# HELP elasticsearch_index_frozen_health Whether the health of the frozen or partially mounted index is the given status (green, yellow or red)
+# TYPE elasticsearch_index_frozen_health gauge
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_2",status="green"} 0
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_2",status="red"} 0
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_2",status="yellow"} 1
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_3",status="green"} 1
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_3",status="red"} 0
+elasticsearch_index_frozen_health{cluster="unknown_cluster",index="foo_3",status="yellow"} 0
+# HELP elasticsearch_index_frozen_store_size_bytes Store size of the frozen or partially mounted index in bytes
+# TYPE elasticsearch_index_frozen_store_size_bytes gauge
+elasticsearch_index_frozen_store_size_bytes{cluster="unknown_cluster",index="foo_2"} 20000
+elasticsearch_index_frozen_store_size_bytes{cluster="unknown_cluster",index="foo_3"} 30000
+# HELP elasticsearch_index_stats_up Was the last scrape of the ElasticSearch index endpoint successful.
+# TYPE elasticsearch_index_stats_up gauge
+elasticsearch_index_stats_up 1
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_1"} 10
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_index_frozen_health", "elasticsearch_index_frozen_store_size_bytes",
+		"elasticsearch_index_stats_up", "elasticsearch_indices_docs_primary"); err != nil {
+		t.Errorf("Unexpected frozen index metrics: %s", err)
+	}
+}
+
+func TestIndicesParallelFetch(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_N/_bulk --data-binary @bulk_N.json
+	//  curl -XPOST http://localhost:9200/foo_5/_close
+	//  curl "http://localhost:9200/_cat/indices?format=json&bytes=b&h=index,status,docs.count,store.size&s=store.size:desc"
+	//  curl "http://localhost:9200/foo_N/_stats?filter_path=indices.*.*.docs"
+	catIndices := `[{"index":"foo_1","status":"open","docs.count":"40","store.size":"40000"},{"index":"foo_2","status":"open","docs.count":"30","store.size":"30000"},{"index":"foo_3","status":"open","docs.count":"20","store.size":"20000"},{"index":"foo_4","status":"open","docs.count":"10","store.size":"10000"},{"index":"foo_5","status":"close","docs.count":null,"store.size":null}]`
+	stats := map[string]string{
+		"foo_1": `"foo_1":{"primaries":{"docs":{"count":40,"deleted":0}},"total":{"docs":{"count":40,"deleted":0}}}`,
+		"foo_2": `"foo_2":{"primaries":{"docs":{"count":30,"deleted":0}},"total":{"docs":{"count":30,"deleted":0}}}`,
+		"foo_3": `"foo_3":{"primaries":{"docs":{"count":20,"deleted":0}},"total":{"docs":{"count":20,"deleted":0}}}`,
+		"foo_4": `"foo_4":{"primaries":{"docs":{"count":10,"deleted":0}},"total":{"docs":{"count":10,"deleted":0}}}`,
+	}
+	// the batch of the smaller indices fails
+	failing := map[string]bool{"foo_3": true}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_cat/indices" {
+			fmt.Fprintln(w, catIndices)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/_stats") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		names := strings.Split(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_stats"), ",")
+		if len(names) > 2 {
+			t.Errorf("Stats of %d indices requested in one batch: %s", len(names), r.URL.Path)
+		}
+		var indices []string
+		for _, name := range names {
+			if failing[name] {
+				http.Error(w, "timed out", http.StatusGatewayTimeout)
+				return
+			}
+			index, ok := stats[name]
+			if !ok {
+				t.Errorf("Stats of unexpected index %q requested", name)
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			indices = append(indices, index)
+		}
+		fmt.Fprintf(w, `{"indices":{%s}}`, strings.Join(indices, ","))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 2, false, false)
+	expected := `
+# HELP elasticsearch_index_stats_failed_batches Number of batches of indices whose stats couldn't be fetched in the last scrape, the index stats are partial if positive
+# TYPE elasticsearch_index_stats_failed_batches gauge
+elasticsearch_index_stats_failed_batches 1
+# HELP elasticsearch_index_stats_up Was the last scrape of the ElasticSearch index endpoint successful.
+# TYPE elasticsearch_index_stats_up gauge
+elasticsearch_index_stats_up 1
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_1"} 40
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_2"} 30
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_index_stats_failed_batches", "elasticsearch_index_stats_up", "elasticsearch_indices_docs_primary"); err != nil {
+		t.Errorf("Unexpected batched index metrics: %s", err)
+	}
+
+	// the collector is only down if every batch failed
+	failing["foo_1"] = true
+	expected = `
+# HELP elasticsearch_index_stats_up Was the last scrape of the ElasticSearch index endpoint successful.
+# TYPE elasticsearch_index_stats_up gauge
+elasticsearch_index_stats_up 0
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_stats_up"); err != nil {
+		t.Errorf("Unexpected up metric: %s", err)
+	}
+}
+
+func TestIndicesShardSegmentsMemory(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/foo_1 -d '{"settings":{"number_of_shards":2,"number_of_replicas":0}}'
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk.json
+	//  curl "http://localhost:9200/_all/_stats?level=shards&filter_path=indices.*.shards.*.routing,indices.*.shards.*.segments.memory_in_bytes"
+	out := `{"indices":{"foo_1":{"shards":{"0":[{"routing":{"state":"STARTED","primary":true,"node":"0hHcEFK1S7qMlk8hQCm7wQ","relocating_node":null},"segments":{"memory_in_bytes":49152}}],"1":[{"routing":{"state":"STARTED","primary":true,"node":"Xn1qcbFcQdShCM3GNQoKFw","relocating_node":null},"segments":{"memory_in_bytes":1048576}}]}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("level") != "shards" {
+			t.Errorf("Index stats requested without shard level")
+		}
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, true, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_index_shard_segments_memory_bytes Memory used by the segments of this shard
+# TYPE elasticsearch_index_shard_segments_memory_bytes gauge
+elasticsearch_index_shard_segments_memory_bytes{cluster="unknown_cluster",index="foo_1",node="0hHcEFK1S7qMlk8hQCm7wQ",primary="true",shard="0"} 49152
+elasticsearch_index_shard_segments_memory_bytes{cluster="unknown_cluster",index="foo_1",node="Xn1qcbFcQdShCM3GNQoKFw",primary="true",shard="1"} 1.048576e+06
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_shard_segments_memory_bytes"); err != nil {
+		t.Errorf("Unexpected shard segments memory metrics: %s", err)
+	}
+}
+
+func TestIndicesSegmentsMemory(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.total.segments"
+	tcs := map[string]struct {
+		out      string
+		expected string
+	}{
+		"7.10.2": {
+			out: `{"indices":{"foo_1":{"total":{"segments":{"count":5,"memory_in_bytes":17084,"terms_memory_in_bytes":12352,"stored_fields_memory_in_bytes":2440,"term_vectors_memory_in_bytes":0,"norms_memory_in_bytes":320,"points_memory_in_bytes":0,"doc_values_memory_in_bytes":1972,"index_writer_memory_in_bytes":0,"version_map_memory_in_bytes":0,"fixed_bit_set_memory_in_bytes":0,"max_unsafe_auto_id_timestamp":-1,"file_sizes":{}}}}}}`,
+			expected: `
+# HELP elasticsearch_indices_segment_doc_values_memory_bytes_total Current size of doc values with all shards on all nodes in bytes
+# TYPE elasticsearch_indices_segment_doc_values_memory_bytes_total gauge
+elasticsearch_indices_segment_doc_values_memory_bytes_total{cluster="unknown_cluster",index="foo_1"} 1972
+# HELP elasticsearch_indices_segment_fields_memory_bytes_total Current size of fields with all shards on all nodes in bytes
+# TYPE elasticsearch_indices_segment_fields_memory_bytes_total gauge
+elasticsearch_indices_segment_fields_memory_bytes_total{cluster="unknown_cluster",index="foo_1"} 2440
+# HELP elasticsearch_indices_segment_norms_memory_bytes_total Current size of norms with all shards on all nodes in bytes
+# TYPE elasticsearch_indices_segment_norms_memory_bytes_total gauge
+elasticsearch_indices_segment_norms_memory_bytes_total{cluster="unknown_cluster",index="foo_1"} 320
+# HELP elasticsearch_indices_segment_points_memory_bytes_total Current size of points with all shards on all nodes in bytes
+# TYPE elasticsearch_indices_segment_points_memory_bytes_total gauge
+elasticsearch_indices_segment_points_memory_bytes_total{cluster="unknown_cluster",index="foo_1"} 0
+# HELP elasticsearch_indices_segment_terms_memory_total Current number of terms with all shards on all nodes in bytes
+# TYPE elasticsearch_indices_segment_terms_memory_total gauge
+elasticsearch_indices_segment_terms_memory_total{cluster="unknown_cluster",index="foo_1"} 12352
+`,
+		},
+		"8.6.2": {
+			out:      `{"indices":{"foo_1":{"total":{"segments":{"count":5,"memory_in_bytes":0,"index_writer_memory_in_bytes":0,"version_map_memory_in_bytes":0,"fixed_bit_set_memory_in_bytes":0,"max_unsafe_auto_id_timestamp":-1,"file_sizes":{}}}}}}`,
+			expected: ``,
+		},
+	}
+	for ver, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, tc.out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(tc.expected),
+			"elasticsearch_indices_segment_doc_values_memory_bytes_total",
+			"elasticsearch_indices_segment_fields_memory_bytes_total",
+			"elasticsearch_indices_segment_norms_memory_bytes_total",
+			"elasticsearch_indices_segment_points_memory_bytes_total",
+			"elasticsearch_indices_segment_terms_memory_total",
+		); err != nil {
+			t.Errorf("[%s] Unexpected segments memory metrics: %s", ver, err)
+		}
+	}
+}
+
+func TestIndicesMergesAutoThrottle(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/foo_1 -H 'Content-Type: application/json' -d '{"settings":{"number_of_shards":2,"number_of_replicas":1}}'
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.primaries.merges,indices.*.total.merges"
+	out := `{"indices":{"foo_1":{"primaries":{"merges":{"current":0,"current_docs":0,"current_size_in_bytes":0,"total":4,"total_time_in_millis":312,"total_docs":5021,"total_size_in_bytes":3146252,"total_stopped_time_in_millis":0,"total_throttled_time_in_millis":0,"total_auto_throttle_in_bytes":41943040}},"total":{"merges":{"current":0,"current_docs":0,"current_size_in_bytes":0,"total":8,"total_time_in_millis":640,"total_docs":10042,"total_size_in_bytes":6292504,"total_stopped_time_in_millis":0,"total_throttled_time_in_millis":0,"total_auto_throttle_in_bytes":83886080}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	expected := `
+# HELP elasticsearch_index_merges_auto_throttle_bytes Current rate merges of the index are auto-throttled to in bytes per second, summed up across its shards
+# TYPE elasticsearch_index_merges_auto_throttle_bytes gauge
+elasticsearch_index_merges_auto_throttle_bytes{aggregation="primaries",cluster="unknown_cluster",index="foo_1"} 4.194304e+07
+elasticsearch_index_merges_auto_throttle_bytes{aggregation="total",cluster="unknown_cluster",index="foo_1"} 8.388608e+07
+`
+	// the metric is the same with and without the aggregation label mode
+	for _, aggregation := range []bool{false, true} {
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, aggregation, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_merges_auto_throttle_bytes"); err != nil {
+			t.Errorf("Unexpected merges auto-throttle metrics with aggregation=%t: %s", aggregation, err)
+		}
+	}
+}
+
+func TestIndicesRefreshExternal(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  curl -XPUT http://localhost:9200/foo_1 -H 'Content-Type: application/json' -d '{"settings":{"number_of_shards":2,"number_of_replicas":1}}'
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.primaries.refresh,indices.*.total.refresh"
+	out := `{"indices":{"foo_1":{"primaries":{"refresh":{"total":24,"total_time_in_millis":180,"external_total":14,"external_total_time_in_millis":195,"listeners":0}},"total":{"refresh":{"total":48,"total_time_in_millis":362,"external_total":28,"external_total_time_in_millis":391,"listeners":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	for aggregation, expected := range map[bool]string{
+		false: `
+# HELP elasticsearch_index_stats_refresh_external_total Total external refresh count, which make changes visible to searches
+# TYPE elasticsearch_index_stats_refresh_external_total counter
+elasticsearch_index_stats_refresh_external_total{cluster="unknown_cluster",index="foo_1"} 28
+`,
+		true: `
+# HELP elasticsearch_index_stats_refresh_external_total Total external refresh count, which make changes visible to searches
+# TYPE elasticsearch_index_stats_refresh_external_total counter
+elasticsearch_index_stats_refresh_external_total{aggregation="primaries",cluster="unknown_cluster",index="foo_1"} 14
+elasticsearch_index_stats_refresh_external_total{aggregation="total",cluster="unknown_cluster",index="foo_1"} 28
+`,
+	} {
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, aggregation, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_index_stats_refresh_external_total"); err != nil {
+			t.Errorf("Unexpected external refresh metrics with aggregation=%t: %s", aggregation, err)
+		}
+	}
+}
+
+func TestIndicesStoreSize(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPUT http://localhost:9200/foo_1 -d '{"settings":{"number_of_shards":1,"number_of_replicas":2}}'
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.*.store"
+	out := `{"indices":{"foo_1":{"primaries":{"store":{"size_in_bytes":5242880,"reserved_in_bytes":0}},"total":{"store":{"size_in_bytes":15728640,"reserved_in_bytes":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	// the replicas take up the difference of the total and the primary store size
+	expected := `
+# HELP elasticsearch_indices_store_size_bytes_primary Current total size of stored index data in bytes with only primary shards on all nodes
+# TYPE elasticsearch_indices_store_size_bytes_primary gauge
+elasticsearch_indices_store_size_bytes_primary{cluster="unknown_cluster",index="foo_1"} 5.24288e+06
+# HELP elasticsearch_indices_store_size_bytes_total Current total size of stored index data in bytes with all shards on all nodes
+# TYPE elasticsearch_indices_store_size_bytes_total gauge
+elasticsearch_indices_store_size_bytes_total{cluster="unknown_cluster",index="foo_1"} 1.572864e+07
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected),
+		"elasticsearch_indices_store_size_bytes_primary", "elasticsearch_indices_store_size_bytes_total"); err != nil {
+		t.Errorf("Unexpected store size metrics: %s", err)
+	}
+}
+
+func TestIndicesFilterPath(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  curl -XPUT http://localhost:9200/foo_1 -d '{"settings":{"number_of_shards":1,"number_of_replicas":0}}'
+	//  curl "http://localhost:9200/_all/_stats?level=shards&filter_path=_shards,...,indices.*.shards.*.recovery"
+	// with the stats of all sections but the docs left out
+	out := `{"_shards":{"total":1,"successful":1,"failed":0},"_all":{"primaries":{"docs":{"count":2,"deleted":0}},"total":{"docs":{"count":2,"deleted":0}}},"indices":{"foo_1":{"primaries":{"docs":{"count":2,"deleted":0}},"total":{"docs":{"count":2,"deleted":0}},"shards":{"0":[{"routing":{"state":"STARTED","primary":true,"node":"0hHcEFK1S7qMlk8hQCm7wQ","relocating_node":null},"docs":{"count":2,"deleted":0}}]}}}}`
+	indexFilterPath := "_shards,_all.primaries,_all.total,indices.*.primaries,indices.*.total"
+	shardFilterPath := ",indices.*.shards.*.routing,indices.*.shards.*.docs,indices.*.shards.*.store,indices.*.shards.*.indexing," +
+		"indices.*.shards.*.get,indices.*.shards.*.search,indices.*.shards.*.merges,indices.*.shards.*.refresh," +
+		"indices.*.shards.*.flush,indices.*.shards.*.warmer,indices.*.shards.*.query_cache,indices.*.shards.*.fielddata," +
+		"indices.*.shards.*.completion,indices.*.shards.*.segments,indices.*.shards.*.translog," +
+		"indices.*.shards.*.request_cache,indices.*.shards.*.recovery"
+	for _, shards := range []bool{false, true} {
+		filterPath := indexFilterPath
+		if shards {
+			filterPath += shardFilterPath
+		}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.URL.Query().Get("filter_path"); got != filterPath {
+				t.Errorf("[shards=%t] Wrong filter path: %s", shards, got)
+			}
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, shards, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		expected := `
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_1"} 2
+`
+		if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_indices_docs_primary"); err != nil {
+			t.Errorf("[shards=%t] Unexpected filtered index stats: %s", shards, err)
+		}
+	}
+}
+
+func TestIndexStatsSections(t *testing.T) {
+	// every decoded section of the shard stats has to pass the filter path
+	fields := jsonFields(reflect.TypeOf(IndexStatsIndexDetailResponse{}))
+	if !reflect.DeepEqual(fields, indexStatsSections) {
+		t.Errorf("Index stats sections %v don't match the fields of the response %v", indexStatsSections, fields)
+	}
+}
+
+func TestIndicesSystemIndices(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  docker run -d -p 5601:5601 kibana:7.10.0
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk_1.json
+	//  curl "http://localhost:9200/_cat/indices/.*?format=json&h=index,health&expand_wildcards=all"
+	//  curl "http://localhost:9200/_all/_stats?expand_wildcards=open,hidden&filter_path=indices.*.primaries.docs"
+	health := `[{"index":".kibana_1","health":"green"},{"index":".kibana_task_manager_1","health":"green"},{"index":".tasks","health":"yellow"}]`
+	stats := `{"indices":{"foo_1":{"primaries":{"docs":{"count":10,"deleted":0}}},".kibana_1":{"primaries":{"docs":{"count":4,"deleted":0}}},".kibana_task_manager_1":{"primaries":{"docs":{"count":6,"deleted":0}}},".tasks":{"primaries":{"docs":{"count":1,"deleted":0}}}}}`
+	systemHealth := `
+# HELP elasticsearch_system_index_health Whether the health of the system index is the given status (green, yellow or red)
+# TYPE elasticsearch_system_index_health gauge
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_1",status="green"} 1
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_1",status="red"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_1",status="yellow"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_task_manager_1",status="green"} 1
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_task_manager_1",status="red"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".kibana_task_manager_1",status="yellow"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".tasks",status="green"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".tasks",status="red"} 0
+elasticsearch_system_index_health{cluster="elasticsearch",index=".tasks",status="yellow"} 1
+`
+	tcs := map[string]struct {
+		includeSystem bool
+		expandHidden  string
+		expected      string
+	}{
+		"excluded": {false, "", `
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="elasticsearch",index="foo_1"} 10
+`},
+		"included": {true, "open,hidden", `
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="elasticsearch",index=".kibana_1"} 4
+elasticsearch_indices_docs_primary{cluster="elasticsearch",index=".kibana_task_manager_1"} 6
+elasticsearch_indices_docs_primary{cluster="elasticsearch",index=".tasks"} 1
+elasticsearch_indices_docs_primary{cluster="elasticsearch",index="foo_1"} 10
+` + systemHealth},
+	}
+	for name, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/_cat/indices/.*":
+				if !tc.includeSystem {
+					t.Errorf("[%s] System index health requested", name)
+				}
+				fmt.Fprintln(w, health)
+			case "/_all/_stats":
+				if got := r.URL.Query().Get("expand_wildcards"); got != tc.expandHidden {
+					t.Errorf("[%s] Wrong expand wildcards: %q", name, got)
+				}
+				fmt.Fprintln(w, stats)
+			default:
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, tc.includeSystem, false)
+		i.lastClusterInfo = &clusterinfo.Response{
+			ClusterName: "elasticsearch",
+			Version:     clusterinfo.VersionInfo{Number: semver.MustParse("7.10.0")},
+		}
+		if err := testutil.CollectAndCompare(i, strings.NewReader(tc.expected),
+			"elasticsearch_indices_docs_primary", "elasticsearch_system_index_health"); err != nil {
+			t.Errorf("[%s] Unexpected system index metrics: %s", name, err)
+		}
+	}
+
+	// the index names of the health are hashed like those of the stats
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_cat/indices/.*" {
+			fmt.Fprintln(w, `[{"index":".tasks","health":"yellow"}]`)
+			return
+		}
+		fmt.Fprintln(w, `{"indices":{}}`)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeHashed, 0, false, nil, 0, true, false)
+	expected := fmt.Sprintf(`
+# HELP elasticsearch_system_index_health Whether the health of the system index is the given status (green, yellow or red)
+# TYPE elasticsearch_system_index_health gauge
+elasticsearch_system_index_health{cluster="unknown_cluster",index="%[1]s",status="green"} 0
+elasticsearch_system_index_health{cluster="unknown_cluster",index="%[1]s",status="red"} 0
+elasticsearch_system_index_health{cluster="unknown_cluster",index="%[1]s",status="yellow"} 1
+`, hashIndexName(".tasks"))
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_system_index_health"); err != nil {
+		t.Errorf("Unexpected hashed system index health: %s", err)
+	}
+}
+
+// largeIndexStats returns an index stats response with level=shards of n
+// indices with two shards each
+func largeIndexStats(n int) []byte {
+	index := IndexStatsIndexResponse{Shards: map[string][]IndexStatsIndexShardsDetailResponse{
+		"0": {{}, {}},
+		"1": {{}, {}},
+	}}
+	isr := indexStatsResponse{Indices: make(map[string]IndexStatsIndexResponse)}
+	for i := 0; i < n; i++ {
+		isr.Indices[fmt.Sprintf("logs-%06d", i)] = index
+	}
+	b, err := json.Marshal(isr)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// BenchmarkIndexStats compares decoding the whole index stats at once with
+// streaming them index by index, which only ever holds a single index
+func BenchmarkIndexStats(b *testing.B) {
+	out := largeIndexStats(2000)
+	b.Run("whole", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			var isr indexStatsResponse
+			if err := json.NewDecoder(bytes.NewReader(out)).Decode(&isr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if _, err := decodeIndexStats(bytes.NewReader(out), func(string, IndexStatsIndexResponse) {}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// licenseStatuses are the values of the status label of the license status
+var licenseStatuses = []string{"active", "expired", "invalid"}
+
+// License information struct
+type License struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	disableUnavailable bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	supported *prometheus.Desc
+	expiry    *prometheus.Desc
+	status    *prometheus.Desc
+	maxNodes  *prometheus.Desc
+}
+
+// NewLicense defines License Prometheus metrics. If disableUnavailable is true,
+// the collector is reported as down if the cluster doesn't provide licensing.
+func NewLicense(logger log.Logger, client *http.Client, url *url.URL, disableUnavailable bool) *License {
+	return &License{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		disableUnavailable: disableUnavailable,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "license", "up"),
+			Help: "Was the last scrape of the ElasticSearch license endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "license", "total_scrapes"),
+			Help: "Current total ElasticSearch license scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "license", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		supported: newSupportedDesc("license"),
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "expiry_timestamp_seconds"),
+			"Timestamp of the expiry of the license, omitted for licenses which don't expire",
+			nil, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "status"),
+			"Whether the license of the given type has the given status",
+			[]string{"type", "status"}, nil,
+		),
+		maxNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "license", "max_nodes"),
+			"Maximum number of nodes the license allows, omitted for licenses limited by resource units",
+			nil, nil,
+		),
+	}
+}
+
+// Describe add License metrics descriptions
+func (l *License) Describe(ch chan<- *prometheus.Desc) {
+	ch <- l.supported
+	ch <- l.expiry
+	ch <- l.status
+	ch <- l.maxNodes
+	ch <- l.up.Desc()
+	ch <- l.totalScrapes.Desc()
+	ch <- l.jsonParseFailures.Desc()
+}
+
+func (l *License) fetchAndDecodeLicense() (licenseResponse, error) {
+	var lr licenseResponse
+
+	u := *l.url
+	u.Path = path.Join(u.Path, "/_license")
+	err := fetchOptional(l.client, &u, &lr)
+	if err == errFeatureUnavailable {
+		// releases before 6.0 only provide the license with X-Pack installed
+		u = *l.url
+		u.Path = path.Join(u.Path, "/_xpack/license")
+		err = fetchOptional(l.client, &u, &lr)
+	}
+	if errors.Is(err, errJSONParse) {
+		l.jsonParseFailures.Inc()
+	}
+	return lr, err
+}
+
+// Collect gets License metric values
+func (l *License) Collect(ch chan<- prometheus.Metric) {
+	l.totalScrapes.Inc()
+	defer func() {
+		ch <- l.up
+		ch <- l.totalScrapes
+		ch <- l.jsonParseFailures
+	}()
+
+	lr, err := l.fetchAndDecodeLicense()
+	collectSupported(ch, l.supported, err)
+	if err == errFeatureUnavailable {
+		if l.disableUnavailable {
+			l.up.Set(0)
+			_ = level.Debug(l.logger).Log(
+				"msg", "license not available",
+			)
+			return
+		}
+		// the OSS distribution has no license at all
+		l.up.Set(1)
+		return
+	}
+	if err != nil {
+		l.up.Set(0)
+		_ = level.Warn(l.logger).Log(
+			"msg", "failed to fetch and decode license",
+			"err", err,
+		)
+		return
+	}
+	l.up.Set(1)
+
+	for _, status := range licenseStatuses {
+		var value float64
+		if lr.License.Status == status {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			l.status,
+			prometheus.GaugeValue,
+			value,
+			lr.License.Type, status,
+		)
+	}
+	if lr.License.ExpiryDateInMillis != nil {
+		ch <- prometheus.MustNewConstMetric(
+			l.expiry,
+			prometheus.GaugeValue,
+			float64(*lr.License.ExpiryDateInMillis)/1000,
+		)
+	}
+	if lr.License.MaxNodes != nil {
+		ch <- prometheus.MustNewConstMetric(
+			l.maxNodes,
+			prometheus.GaugeValue,
+			float64(*lr.License.MaxNodes),
+		)
+	}
+}
@@ -0,0 +1,20 @@
+package collector
+
+// licenseResponse is a representation of the Elasticsearch license info
+type licenseResponse struct {
+	License licenseDetailResponse `json:"license"`
+}
+
+// licenseDetailResponse defines the installed license. Basic licenses don't
+// expire and enterprise licenses are limited by resource units instead of
+// nodes, so the expiry date and the maximum number of nodes are optional.
+type licenseDetailResponse struct {
+	Status             string `json:"status"`
+	UID                string `json:"uid"`
+	Type               string `json:"type"`
+	IssueDateInMillis  int64  `json:"issue_date_in_millis"`
+	ExpiryDateInMillis *int64 `json:"expiry_date_in_millis"`
+	MaxNodes           *int64 `json:"max_nodes"`
+	IssuedTo           string `json:"issued_to"`
+	Issuer             string `json:"issuer"`
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollectorsMalformedResponse(t *testing.T) {
+	// a proxy in front of Elasticsearch answering with an error page, and
+	// a response cut off in the middle of the body
+	bodies := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"html":      {"text/html", `<html><head><title>502 Bad Gateway</title></head><body><h1>502 Bad Gateway</h1></body></html>`},
+		"truncated": {"application/json", `{"cluster_name":"elasticsearch","nodes":{"VsUTVmTvRZi4Oc8hsD8dNQ":{"name":"es01","indices":{"docs":{"count":`},
+	}
+	for bn, b := range bodies {
+		b := b
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", b.contentType)
+			fmt.Fprint(w, b.body)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		for cn, c := range testCollectors() {
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(c.collector(u))
+			// a panic in Collect would abort the test
+			mfs, err := registry.Gather()
+			if err != nil {
+				t.Errorf("[%s/%s] Failed to gather metrics: %s", cn, bn, err)
+				continue
+			}
+			var found bool
+			for _, mf := range mfs {
+				if mf.GetName() != c.up {
+					continue
+				}
+				found = true
+				if v := mf.GetMetric()[0].GetGauge().GetValue(); v != 0 {
+					t.Errorf("[%s/%s] Collector should be down, got %s %v", cn, bn, c.up, v)
+				}
+			}
+			if !found {
+				t.Errorf("[%s/%s] Missing metric %s", cn, bn, c.up)
+			}
+		}
+	}
+}
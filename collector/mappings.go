@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// totalFieldsLimitSetting limits the number of fields in the mapping of an index
+const totalFieldsLimitSetting = "index.mapping.total_fields.limit"
+
+// Mappings information struct
+type Mappings struct {
+	logger  log.Logger
+	client  *http.Client
+	url     *url.URL
+	indices []string
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	fieldsCount      *prometheus.Desc
+	totalFieldsLimit *prometheus.Desc
+}
+
+// NewMappings defines Mappings Prometheus metrics. The mappings of the indices
+// matching the index patterns are read, all indices if indices is empty.
+func NewMappings(logger log.Logger, client *http.Client, url *url.URL, indices []string) *Mappings {
+	return &Mappings{
+		logger:  logger,
+		client:  client,
+		url:     url,
+		indices: indices,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "mappings", "up"),
+			Help: "Was the last scrape of the ElasticSearch mappings endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "mappings", "total_scrapes"),
+			Help: "Current total ElasticSearch mappings scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "mappings", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+		fieldsCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "mapping_fields_count"),
+			"Number of fields in the mapping of the index, counted like index.mapping.total_fields.limit including objects and multi-fields",
+			[]string{"index"}, nil,
+		),
+		totalFieldsLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "mapping_total_fields_limit"),
+			"Maximum number of fields in the mapping of the index (index.mapping.total_fields.limit)",
+			[]string{"index"}, nil,
+		),
+	}
+}
+
+// Describe add Mappings metrics descriptions
+func (m *Mappings) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.fieldsCount
+	ch <- m.totalFieldsLimit
+	ch <- m.up.Desc()
+	ch <- m.totalScrapes.Desc()
+	ch <- m.jsonParseFailures.Desc()
+}
+
+func (m *Mappings) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := m.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(m.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		m.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+// indexPath returns the index part of the request paths, like Elasticsearch
+// the patterns can exclude indices with a leading -
+func (m *Mappings) indexPath() string {
+	if len(m.indices) == 0 {
+		return "_all"
+	}
+	return strings.Join(m.indices, ",")
+}
+
+func (m *Mappings) fetchAndDecodeMappings() (mappingsResponse, error) {
+	var mr mappingsResponse
+
+	u := *m.url
+	u.Path = path.Join(u.Path, m.indexPath(), "_mapping")
+	err := m.getAndParseURL(&u, &mr)
+	return mr, err
+}
+
+func (m *Mappings) fetchAndDecodeMappingSettings() (mappingSettingsResponse, error) {
+	var msr mappingSettingsResponse
+
+	u := *m.url
+	u.Path = path.Join(u.Path, m.indexPath(), "_settings", totalFieldsLimitSetting)
+	q := u.Query()
+	// the limit is only part of the settings if it was changed
+	q.Set("include_defaults", "true")
+	q.Set("flat_settings", "true")
+	u.RawQuery = q.Encode()
+	err := m.getAndParseURL(&u, &msr)
+	return msr, err
+}
+
+// Collect gets Mappings metric values
+func (m *Mappings) Collect(ch chan<- prometheus.Metric) {
+	m.totalScrapes.Inc()
+	defer func() {
+		ch <- m.up
+		ch <- m.totalScrapes
+		ch <- m.jsonParseFailures
+	}()
+
+	mr, err := m.fetchAndDecodeMappings()
+	if err != nil {
+		m.up.Set(0)
+		_ = level.Warn(m.logger).Log(
+			"msg", "failed to fetch and decode mappings",
+			"err", err,
+		)
+		return
+	}
+	msr, err := m.fetchAndDecodeMappingSettings()
+	if err != nil {
+		m.up.Set(0)
+		_ = level.Warn(m.logger).Log(
+			"msg", "failed to fetch and decode mapping settings",
+			"err", err,
+		)
+		return
+	}
+	m.up.Set(1)
+
+	for index, mapping := range mr {
+		fields := countMappingFields(mapping.Mappings.Properties) + len(mapping.Mappings.Runtime)
+		ch <- prometheus.MustNewConstMetric(m.fieldsCount, prometheus.GaugeValue, float64(fields), index)
+	}
+	for index, settings := range msr {
+		limit, ok := settings.Settings[totalFieldsLimitSetting]
+		if !ok {
+			limit = settings.Defaults[totalFieldsLimitSetting]
+		}
+		if v, ok := parseSettingValue(limit); ok {
+			ch <- prometheus.MustNewConstMetric(m.totalFieldsLimit, prometheus.GaugeValue, v, index)
+		}
+	}
+}
+
+// countMappingFields counts the fields like Elasticsearch does for the total
+// fields limit: objects and nested fields count as well as their properties,
+// multi-fields count as separate fields.
+func countMappingFields(properties map[string]mappingPropertyResponse) int {
+	var count int
+	for _, property := range properties {
+		count++
+		count += countMappingFields(property.Properties)
+		count += countMappingFields(property.Fields)
+	}
+	return count
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"encoding/json"
+)
+
+// mappingsResponse is a representation of the Elasticsearch mappings of each index
+type mappingsResponse map[string]indexMappingsResponse
+
+// indexMappingsResponse defines the mappings of an index
+type indexMappingsResponse struct {
+	Mappings mappingResponse `json:"mappings"`
+}
+
+// mappingResponse defines the fields of a mapping. Releases before 7.0
+// nest the mapping in its type, the fields of all types are merged then.
+type mappingResponse struct {
+	Properties map[string]mappingPropertyResponse `json:"properties"`
+	Runtime    map[string]json.RawMessage         `json:"runtime"`
+}
+
+// UnmarshalJSON decodes typeless mappings as well as the mappings of
+// releases before 7.0, which are keyed by their type
+func (m *mappingResponse) UnmarshalJSON(data []byte) error {
+	type mapping mappingResponse
+	var typeless mapping
+	if err := json.Unmarshal(data, &typeless); err != nil {
+		return err
+	}
+	if typeless.Properties != nil || typeless.Runtime != nil {
+		*m = mappingResponse(typeless)
+		return nil
+	}
+	var types map[string]mapping
+	if err := json.Unmarshal(data, &types); err != nil {
+		// e.g. the _meta or dynamic_templates of a typeless mapping without fields
+		return nil
+	}
+	for _, t := range types {
+		for name, property := range t.Properties {
+			if m.Properties == nil {
+				m.Properties = make(map[string]mappingPropertyResponse)
+			}
+			m.Properties[name] = property
+		}
+	}
+	return nil
+}
+
+// mappingPropertyResponse defines a field of a mapping, objects and nested
+// fields have properties and multi-fields have fields
+type mappingPropertyResponse struct {
+	Type       string                             `json:"type"`
+	Properties map[string]mappingPropertyResponse `json:"properties"`
+	Fields     map[string]mappingPropertyResponse `json:"fields"`
+}
+
+// mappingSettingsResponse is a representation of the mapping settings of
+// each index, including the defaults
+type mappingSettingsResponse map[string]struct {
+	Settings map[string]string `json:"settings"`
+	Defaults map[string]string `json:"defaults"`
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMappings(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/foo_1 --header "Content-Type: application/json" -d @mapping.json
+	//  curl -XPUT http://localhost:9200/foo_2 --header "Content-Type: application/json" -d '{"settings":{"index.mapping.total_fields.limit":2000}}'
+	//  curl http://localhost:9200/_all/_mapping
+	//  curl "http://localhost:9200/_all/_settings/index.mapping.total_fields.limit?include_defaults=true&flat_settings=true"
+	settings := `{"foo_1":{"settings":{},"defaults":{"index.mapping.total_fields.limit":"1000"}},"foo_2":{"settings":{"index.mapping.total_fields.limit":"2000"},"defaults":{}}}`
+	tcs := map[string]struct {
+		mappings string
+		fields   int
+	}{
+		// with a multi-field, an object with a nested field and a runtime field
+		"7.12.0": {`{"foo_1":{"mappings":{"properties":{"@timestamp":{"type":"date"},"message":{"type":"text","fields":{"keyword":{"type":"keyword","ignore_above":256}}},"user":{"properties":{"name":{"type":"keyword"},"address":{"type":"nested","properties":{"city":{"type":"keyword"},"zip":{"type":"keyword"}}}}}},"runtime":{"day_of_week":{"type":"keyword"}}}},"foo_2":{"mappings":{}}}`, 9},
+		// without runtime fields, the mapping is nested in its type
+		"6.8.8": {`{"foo_1":{"mappings":{"_doc":{"properties":{"@timestamp":{"type":"date"},"message":{"type":"text","fields":{"keyword":{"type":"keyword","ignore_above":256}}},"user":{"properties":{"name":{"type":"keyword"},"address":{"type":"nested","properties":{"city":{"type":"keyword"},"zip":{"type":"keyword"}}}}}}}}},"foo_2":{"mappings":{}}}`, 8},
+	}
+	for ver, tc := range tcs {
+		tc := tc
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/_all/_mapping":
+				fmt.Fprintln(w, tc.mappings)
+			case "/_all/_settings/index.mapping.total_fields.limit":
+				fmt.Fprintln(w, settings)
+			default:
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		expected := fmt.Sprintf(`
+# HELP elasticsearch_index_mapping_fields_count Number of fields in the mapping of the index, counted like index.mapping.total_fields.limit including objects and multi-fields
+# TYPE elasticsearch_index_mapping_fields_count gauge
+elasticsearch_index_mapping_fields_count{index="foo_1"} %d
+elasticsearch_index_mapping_fields_count{index="foo_2"} 0
+# HELP elasticsearch_index_mapping_total_fields_limit Maximum number of fields in the mapping of the index (index.mapping.total_fields.limit)
+# TYPE elasticsearch_index_mapping_total_fields_limit gauge
+elasticsearch_index_mapping_total_fields_limit{index="foo_1"} 1000
+elasticsearch_index_mapping_total_fields_limit{index="foo_2"} 2000
+# HELP elasticsearch_mappings_up Was the last scrape of the ElasticSearch mappings endpoint successful.
+# TYPE elasticsearch_mappings_up gauge
+elasticsearch_mappings_up 1
+`, tc.fields)
+		m := NewMappings(log.NewNopLogger(), http.DefaultClient, u, nil)
+		if err := testutil.CollectAndCompare(m, strings.NewReader(expected),
+			"elasticsearch_index_mapping_fields_count", "elasticsearch_index_mapping_total_fields_limit", "elasticsearch_mappings_up"); err != nil {
+			t.Errorf("[%s] Unexpected mapping metrics: %s", ver, err)
+		}
+	}
+}
+
+func TestMappingsIndices(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprintln(w, `{}`)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	m := NewMappings(log.NewNopLogger(), http.DefaultClient, u, []string{"logs-*", "-logs-debug-*"})
+	if err := testutil.CollectAndCompare(m, strings.NewReader(""), "elasticsearch_index_mapping_fields_count"); err != nil {
+		t.Errorf("Unexpected mapping metrics: %s", err)
+	}
+	expected := []string{"/logs-*,-logs-debug-*/_mapping", "/logs-*,-logs-debug-*/_settings/index.mapping.total_fields.limit"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected requests to %v, got %v", expected, paths)
+	}
+}
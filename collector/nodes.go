@@ -3,15 +3,39 @@ package collector
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Values of the resolve parameter of NewNodes
+const (
+	// NodeResolveRequest requests the stats of the configured node on every
+	// scrape. Behind a load balancer, _local is whichever node answered.
+	NodeResolveRequest = "request"
+	// NodeResolveStable resolves the configured node to a node id once and
+	// requests the stats of this node id, so the node label doesn't change
+	// between scrapes behind a load balancer
+	NodeResolveStable = "stable"
+)
+
+// dataTiers are the node roles of the data tiers of a tiered architecture
+var dataTiers = map[string]bool{
+	"data_hot":    true,
+	"data_warm":   true,
+	"data_cold":   true,
+	"data_frozen": true,
+}
+
 func getRoles(node NodeStatsNodeResponse) map[string]bool {
 	// default settings (2.x) and map, which roles to consider
 	roles := map[string]bool{
@@ -48,6 +72,164 @@ func getRoles(node NodeStatsNodeResponse) map[string]bool {
 	return roles
 }
 
+// nodeRoleChangeTTL is how long the role changes of a node which isn't
+// returned by its cluster anymore are kept
+const nodeRoleChangeTTL = time.Hour
+
+// nodeRoleChangeTracker remembers the roles of every node of every queried URL
+// to count role changes between scrapes
+type nodeRoleChangeTracker struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeRoleState
+}
+
+type nodeRoleState struct {
+	roles    string
+	changes  float64
+	lastSeen time.Time
+}
+
+// nodeRoleChanges is shared by all Nodes collectors, as a new collector is created for every scrape
+var nodeRoleChanges = newNodeRoleChangeTracker()
+
+func newNodeRoleChangeTracker() *nodeRoleChangeTracker {
+	return &nodeRoleChangeTracker{
+		nodes: make(map[string]*nodeRoleState),
+	}
+}
+
+// observe records the roles of the node with the given key, made of the URL
+// and the node id, and returns the count of changes of its roles between scrapes
+func (t *nodeRoleChangeTracker) observe(key string, node NodeStatsNodeResponse, now time.Time) float64 {
+	var enabled []string
+	for role, isEnabled := range getRoles(node) {
+		if isEnabled {
+			enabled = append(enabled, role)
+		}
+	}
+	sort.Strings(enabled)
+	roles := strings.Join(enabled, ",")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.nodes[key]
+	if !ok {
+		state = &nodeRoleState{roles: roles}
+		t.nodes[key] = state
+	}
+	if state.roles != roles {
+		state.changes++
+		state.roles = roles
+	}
+	state.lastSeen = now
+	return state.changes
+}
+
+// expire forgets the nodes which weren't seen within nodeRoleChangeTTL
+func (t *nodeRoleChangeTracker) expire(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, state := range t.nodes {
+		if now.Sub(state.lastSeen) > nodeRoleChangeTTL {
+			delete(t.nodes, key)
+		}
+	}
+}
+
+// nodesInfoCache keeps the nodes info of every queried URL, as it only changes
+// on restarts and would otherwise be fetched on every scrape
+type nodesInfoCache struct {
+	mu      sync.Mutex
+	entries map[string]nodesInfoCacheEntry
+}
+
+type nodesInfoCacheEntry struct {
+	fetched time.Time
+	info    nodesInfoResponse
+}
+
+// nodesInfos is shared by all Nodes collectors, as a new collector is created for every scrape
+var nodesInfos = newNodesInfoCache()
+
+func newNodesInfoCache() *nodesInfoCache {
+	return &nodesInfoCache{
+		entries: make(map[string]nodesInfoCacheEntry),
+	}
+}
+
+// get returns the cached nodes info of key if it isn't older than maxAge,
+// otherwise it calls fetch and caches the result
+func (c *nodesInfoCache) get(key string, maxAge time.Duration, fetch func() (nodesInfoResponse, error)) (nodesInfoResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && time.Since(entry.fetched) < maxAge {
+		return entry.info, nil
+	}
+	info, err := fetch()
+	if err != nil {
+		return info, err
+	}
+	c.entries[key] = nodesInfoCacheEntry{fetched: time.Now(), info: info}
+	return info, nil
+}
+
+// resolvedNodeCache keeps the node id the configured node of every queried URL
+// was resolved to
+type resolvedNodeCache struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// resolvedNodes is shared by all Nodes collectors, as a new collector is created for every scrape
+var resolvedNodes = newResolvedNodeCache()
+
+func newResolvedNodeCache() *resolvedNodeCache {
+	return &resolvedNodeCache{
+		ids: make(map[string]string),
+	}
+}
+
+// get returns the resolved node id of key, or calls resolve and caches its result
+func (c *resolvedNodeCache) get(key string, resolve func() (string, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, ok := c.ids[key]; ok {
+		return id, nil
+	}
+	id, err := resolve()
+	if err != nil {
+		return "", err
+	}
+	c.ids[key] = id
+	return id, nil
+}
+
+// forget removes the resolved node id of key, e.g. when the node left the
+// cluster, so it's resolved again on the next scrape
+func (c *resolvedNodeCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, key)
+}
+
+// nodeStatsSections are the fields of the node stats which are decoded, all
+// other sections like ingest or adaptive_selection are filtered out by
+// Elasticsearch. Has to be kept in sync with NodeStatsNodeResponse.
+var nodeStatsSections = []string{
+	"name", "host", "timestamp", "transport_address", "hostname", "roles", "attributes",
+	"indices", "os", "network", "fs", "thread_pool", "jvm", "breakers", "http",
+	"transport", "process", "script", "discovery",
+}
+
+// nodeStatsFilterPath returns the filter_path of the node stats request
+func nodeStatsFilterPath() string {
+	paths := []string{"cluster_name"}
+	for _, section := range nodeStatsSections {
+		paths = append(paths, "nodes.*."+section)
+	}
+	return strings.Join(paths, ",")
+}
+
 func createRoleMetric(role string) *nodeMetric {
 	return &nodeMetric{
 		Type: prometheus.GaugeValue,
@@ -156,6 +338,26 @@ type Nodes struct {
 	url    *url.URL
 	all    bool
 	node   string
+	// resolve is one of the NodeResolve values
+	resolve string
+	// balanceAttribute is the node attribute the nodes are counted by
+	balanceAttribute string
+
+	roleChanges       *nodeRoleChangeTracker
+	roleChangesTotal  *prometheus.Desc
+	infos             *nodesInfoCache
+	resolved          *resolvedNodeCache
+	buildInfoInterval time.Duration
+	buildInfo         *prometheus.Desc
+	dataTier          *prometheus.Desc
+	nodeVersions      *prometheus.Desc
+	nodesPerAttribute *prometheus.Desc
+	isMaster          *prometheus.Desc
+
+	discoveryClusterStateQueue      *prometheus.Desc
+	discoveryPublishedClusterStates *prometheus.Desc
+	discoveryClusterStateUpdates    *prometheus.Desc
+	discoveryClusterStateUpdateTime *prometheus.Desc
 
 	up                              prometheus.Gauge
 	totalScrapes, jsonParseFailures prometheus.Counter
@@ -168,14 +370,78 @@ type Nodes struct {
 	filesystemIODeviceMetrics []*filesystemIODeviceMetric
 }
 
-// NewNodes defines Nodes Prometheus metrics
-func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, node string) *Nodes {
+// NewNodes defines Nodes Prometheus metrics. The resolve parameter is one of
+// the NodeResolve values and defaults to NodeResolveRequest. The build info of
+// the nodes is refreshed every buildInfoInterval. With all nodes, the nodes are
+// counted by the value of their balanceAttribute, unless it's empty.
+func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, node string, resolve string, buildInfoInterval time.Duration, balanceAttribute string) *Nodes {
+	if all || resolve != NodeResolveStable {
+		resolve = NodeResolveRequest
+	}
 	return &Nodes{
-		logger: logger,
-		client: client,
-		url:    url,
-		all:    all,
-		node:   node,
+		logger:  logger,
+		client:  client,
+		url:     url,
+		all:     all,
+		node:    node,
+		resolve: resolve,
+
+		balanceAttribute: balanceAttribute,
+
+		roleChanges:       nodeRoleChanges,
+		infos:             nodesInfos,
+		resolved:          resolvedNodes,
+		buildInfoInterval: buildInfoInterval,
+		roleChangesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "node_role_changes_total"),
+			"Count of changes of the roles of a node between scrapes",
+			defaultRoleLabels, nil,
+		),
+		buildInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "build_info"),
+			"Build information of the node, always 1",
+			append(defaultRoleLabels, "build_hash", "build_flavor", "build_type", "version"), nil,
+		),
+		dataTier: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "data_tier"),
+			"Data tier of the node, always 1",
+			append(defaultRoleLabels, "tier"), nil,
+		),
+		nodeVersions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "node_versions"),
+			"Number of nodes per Elasticsearch version, only exported with all nodes",
+			[]string{"version"}, nil,
+		),
+		nodesPerAttribute: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "nodes_per_attribute"),
+			"Number of nodes per value of the node attribute, nodes without the attribute are omitted, only exported with all nodes",
+			[]string{"attribute", "value"}, nil,
+		),
+		isMaster: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "is_master"),
+			"Whether the node is the elected master of the cluster",
+			defaultRoleLabels, nil,
+		),
+		discoveryClusterStateQueue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "discovery", "cluster_state_queue"),
+			"Number of cluster states received by the node which aren't applied yet, by state (pending or committed)",
+			append(defaultNodeLabels, "state"), nil,
+		),
+		discoveryPublishedClusterStates: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "discovery", "published_cluster_states_total"),
+			"Total number of cluster states received by the node, by type (full, compatible_diff or incompatible_diff)",
+			append(defaultNodeLabels, "type"), nil,
+		),
+		discoveryClusterStateUpdates: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "discovery", "cluster_state_updates_total"),
+			"Total number of cluster state updates computed by the node as master, by result (unchanged, success or failure)",
+			append(defaultNodeLabels, "result"), nil,
+		),
+		discoveryClusterStateUpdateTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "discovery", timeUnitName("cluster_state_update_seconds")),
+			"Total time spent in each stage of the cluster state updates computed by the node as master in seconds, by result",
+			append(defaultNodeLabels, "result", "stage"), nil,
+		),
 
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: prometheus.BuildFQName(namespace, "node_stats", "up"),
@@ -506,12 +772,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "get_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("get_time_seconds")),
 					"Total get time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Get.Time) / 1000
+					return millisToTimeUnit(node.Indices.Get.Time)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -530,12 +796,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "get_missing_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("get_missing_time_seconds")),
 					"Total time of get missing in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Get.MissingTime) / 1000
+					return millisToTimeUnit(node.Indices.Get.MissingTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -554,12 +820,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "get_exists_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("get_exists_time_seconds")),
 					"Total time get exists in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Get.ExistsTime) / 1000
+					return millisToTimeUnit(node.Indices.Get.ExistsTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -578,12 +844,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_refresh", "time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_refresh", timeUnitName("time_seconds_total")),
 					"Total time spent refreshing in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Refresh.TotalTime) / 1000
+					return millisToTimeUnit(node.Indices.Refresh.TotalTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -602,12 +868,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "search_query_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("search_query_time_seconds")),
 					"Total search query time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Search.QueryTime) / 1000
+					return millisToTimeUnit(node.Indices.Search.QueryTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -626,12 +892,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "search_fetch_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("search_fetch_time_seconds")),
 					"Total search fetch time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Search.FetchTime) / 1000
+					return millisToTimeUnit(node.Indices.Search.FetchTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -662,12 +928,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "search_suggest_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("search_suggest_time_seconds")),
 					"Total suggest time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Search.SuggestTime) / 1000
+					return millisToTimeUnit(node.Indices.Search.SuggestTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -686,12 +952,36 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "search_scroll_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("search_scroll_time_seconds")),
 					"Total scroll time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Search.ScrollTime) / 1000
+					return millisToTimeUnit(node.Indices.Search.ScrollTime)
+				},
+				Labels: defaultNodeLabelValues,
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "indices", "search_scroll_current"),
+					"Current number of open scrolls, leaked scrolls keep growing",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Indices.Search.ScrollCurrent)
+				},
+				Labels: defaultNodeLabelValues,
+			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "indices", "search_open_contexts"),
+					"Current number of open search contexts, including the ones of scrolls and point in time searches",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Indices.Search.OpenContext)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -703,7 +993,7 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Docs.Count)
+					return intToFloat64(logger, "elasticsearch_indices_docs", node.Indices.Docs.Count)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -734,12 +1024,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "store_throttle_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("store_throttle_time_seconds_total")),
 					"Throttle time for index store in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Store.ThrottleTime) / 1000
+					return millisToTimeUnit(node.Indices.Store.ThrottleTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -890,12 +1180,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "flush_time_seconds"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("flush_time_seconds")),
 					"Cumulative flush time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Flush.Time) / 1000
+					return millisToTimeUnit(node.Indices.Flush.Time)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -914,24 +1204,24 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices", "warmer_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices", timeUnitName("warmer_time_seconds_total")),
 					"Total warmer time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Warmer.TotalTime) / 1000
+					return millisToTimeUnit(node.Indices.Warmer.TotalTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_indexing", "index_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_indexing", timeUnitName("index_time_seconds_total")),
 					"Cumulative index time in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Indexing.IndexTime) / 1000
+					return millisToTimeUnit(node.Indices.Indexing.IndexTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -950,12 +1240,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_indexing", "delete_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_indexing", timeUnitName("delete_time_seconds_total")),
 					"Total time indexing delete in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Indexing.DeleteTime) / 1000
+					return millisToTimeUnit(node.Indices.Indexing.DeleteTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -989,12 +1279,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_indexing", "throttle_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_indexing", timeUnitName("throttle_time_seconds_total")),
 					"Cumulative indexing throttling time",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Indexing.ThrottleTime) / 1000
+					return millisToTimeUnit(node.Indices.Indexing.ThrottleTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -1061,24 +1351,24 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_merges", "total_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_merges", timeUnitName("total_time_seconds_total")),
 					"Total time spent merging in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Merges.TotalTime) / 1000
+					return millisToTimeUnit(node.Indices.Merges.TotalTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "indices_merges", "total_throttled_time_seconds_total"),
+					prometheus.BuildFQName(namespace, "indices_merges", timeUnitName("total_throttled_time_seconds_total")),
 					"Total throttled time of merges in seconds",
 					defaultNodeLabels, nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Indices.Merges.TotalThrottledTime) / 1000
+					return millisToTimeUnit(node.Indices.Merges.TotalThrottledTime)
 				},
 				Labels: defaultNodeLabelValues,
 			},
@@ -1124,6 +1414,22 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 					return append(defaultNodeLabelValues(cluster, node), "heap")
 				},
 			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "jvm_heap", "used_ratio"),
+					"Ratio of the JVM heap currently used to the maximum heap",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					// the maximum isn't known while the node starts up
+					if node.JVM.Mem.HeapMax == 0 {
+						return 0
+					}
+					return float64(node.JVM.Mem.HeapUsed) / float64(node.JVM.Mem.HeapMax)
+				},
+				Labels: defaultNodeLabelValues,
+			},
 			{
 				Type: prometheus.GaugeValue,
 				Desc: prometheus.NewDesc(
@@ -1423,12 +1729,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "process", "cpu_time_seconds_sum"),
+					prometheus.BuildFQName(namespace, "process", timeUnitName("cpu_time_seconds_sum")),
 					"Process CPU time in seconds",
 					append(defaultNodeLabels, "type"), nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Process.CPU.Total) / 1000
+					return millisToTimeUnit(node.Process.CPU.Total)
 				},
 				Labels: func(cluster string, node NodeStatsNodeResponse) []string {
 					return append(defaultNodeLabelValues(cluster, node), "total")
@@ -1437,12 +1743,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "process", "cpu_time_seconds_sum"),
+					prometheus.BuildFQName(namespace, "process", timeUnitName("cpu_time_seconds_sum")),
 					"Process CPU time in seconds",
 					append(defaultNodeLabels, "type"), nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Process.CPU.Sys) / 1000
+					return millisToTimeUnit(node.Process.CPU.Sys)
 				},
 				Labels: func(cluster string, node NodeStatsNodeResponse) []string {
 					return append(defaultNodeLabelValues(cluster, node), "sys")
@@ -1451,12 +1757,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "process", "cpu_time_seconds_sum"),
+					prometheus.BuildFQName(namespace, "process", timeUnitName("cpu_time_seconds_sum")),
 					"Process CPU time in seconds",
 					append(defaultNodeLabels, "type"), nil,
 				),
 				Value: func(node NodeStatsNodeResponse) float64 {
-					return float64(node.Process.CPU.User) / 1000
+					return millisToTimeUnit(node.Process.CPU.User)
 				},
 				Labels: func(cluster string, node NodeStatsNodeResponse) []string {
 					return append(defaultNodeLabelValues(cluster, node), "user")
@@ -1510,6 +1816,54 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 				},
 				Labels: defaultNodeLabelValues,
 			},
+			{
+				Type: prometheus.GaugeValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "transport", "server_open_connections"),
+					"Current number of inbound transport connections",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Transport.ServerOpen)
+				},
+				Labels: defaultNodeLabelValues,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "script", "compilations_total"),
+					"Count of script compilations",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Script.Compilations)
+				},
+				Labels: defaultNodeLabelValues,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "script", "cache_evictions_total"),
+					"Count of script cache evictions",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Script.CacheEvictions)
+				},
+				Labels: defaultNodeLabelValues,
+			},
+			{
+				Type: prometheus.CounterValue,
+				Desc: prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "script", "compilation_limit_triggered_total"),
+					"Count of script compilations rejected by the compilation rate limit",
+					defaultNodeLabels, nil,
+				),
+				Value: func(node NodeStatsNodeResponse) float64 {
+					return float64(node.Script.CompilationLimitTriggered)
+				},
+				Labels: defaultNodeLabelValues,
+			},
 		},
 		gcCollectionMetrics: []*gcCollectionMetric{
 			{
@@ -1529,12 +1883,12 @@ func NewNodes(logger log.Logger, client *http.Client, url *url.URL, all bool, no
 			{
 				Type: prometheus.CounterValue,
 				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, "jvm_gc", "collection_seconds_sum"),
+					prometheus.BuildFQName(namespace, "jvm_gc", timeUnitName("collection_seconds_sum")),
 					"GC run time in seconds",
 					append(defaultNodeLabels, "gc"), nil,
 				),
 				Value: func(gcStats NodeStatsJVMGCCollectorResponse) float64 {
-					return float64(gcStats.CollectionTime) / 1000
+					return millisToTimeUnit(gcStats.CollectionTime)
 				},
 				Labels: func(cluster string, node NodeStatsNodeResponse, collector string) []string {
 					return append(defaultNodeLabelValues(cluster, node), collector)
@@ -1793,25 +2147,138 @@ func (c *Nodes) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range c.filesystemIODeviceMetrics {
 		ch <- metric.Desc
 	}
+	ch <- c.roleChangesTotal
+	ch <- c.buildInfo
+	ch <- c.dataTier
+	ch <- c.nodeVersions
+	if c.all && c.balanceAttribute != "" {
+		ch <- c.nodesPerAttribute
+	}
+	ch <- c.isMaster
+	ch <- c.discoveryClusterStateQueue
+	ch <- c.discoveryPublishedClusterStates
+	ch <- c.discoveryClusterStateUpdates
+	ch <- c.discoveryClusterStateUpdateTime
 	ch <- c.up.Desc()
 	ch <- c.totalScrapes.Desc()
 	ch <- c.jsonParseFailures.Desc()
 }
 
-func (c *Nodes) fetchAndDecodeNodeStats() (nodeStatsResponse, error) {
-	var nsr nodeStatsResponse
-
+// fetchAndDecodeNodeStats streams the stats of the nodes, fn is called with
+// every node as soon as it's decoded, so the stats of all nodes are never held
+// in memory at once. It returns the number of decoded nodes.
+func (c *Nodes) fetchAndDecodeNodeStats(node string, fn func(cluster, id string, node NodeStatsNodeResponse)) (int, error) {
 	u := *c.url
 
 	if c.all {
 		u.Path = path.Join(u.Path, "/_nodes/stats")
 	} else {
-		u.Path = path.Join(u.Path, "_nodes", c.node, "stats")
+		u.Path = path.Join(u.Path, "_nodes", node, "stats")
+	}
+	q := u.Query()
+	q.Set("filter_path", nodeStatsFilterPath())
+	u.RawQuery = q.Encode()
+
+	res, err := c.client.Get(u.String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cluster health from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(c.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	n, err := decodeNodeStats(res.Body, fn)
+	if err != nil {
+		c.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return n, err
+	}
+	return n, nil
+}
+
+// decodeNodeStats decodes the node stats response from r node by node and
+// calls fn with every node. Nodes listed before the cluster name are held
+// back until it's known.
+func decodeNodeStats(r io.Reader, fn func(cluster, id string, node NodeStatsNodeResponse)) (int, error) {
+	type pendingNode struct {
+		id   string
+		node NodeStatsNodeResponse
+	}
+	var (
+		nsr     nodeStatsResponse
+		known   bool
+		pending []pendingNode
+		n       int
+	)
+	dec := json.NewDecoder(r)
+	err := decodeMembers(dec, func(key string) error {
+		switch key {
+		case "cluster_name":
+			if err := dec.Decode(&nsr.ClusterName); err != nil {
+				return err
+			}
+			known = true
+			for _, p := range pending {
+				fn(nsr.ClusterName, p.id, p.node)
+			}
+			pending = nil
+			return nil
+		case "nodes":
+			return decodeMembers(dec, func(id string) error {
+				var node NodeStatsNodeResponse
+				if err := dec.Decode(&node); err != nil {
+					return err
+				}
+				n++
+				if !known {
+					pending = append(pending, pendingNode{id: id, node: node})
+					return nil
+				}
+				fn(nsr.ClusterName, id, node)
+				return nil
+			})
+		}
+		return skipValue(dec)
+	})
+	if err != nil {
+		return n, err
+	}
+	// the response has no cluster name at all
+	for _, p := range pending {
+		fn(nsr.ClusterName, p.id, p.node)
+	}
+	return n, nil
+}
+
+func (c *Nodes) fetchAndDecodeNodesInfo(node string) (nodesInfoResponse, error) {
+	var nir nodesInfoResponse
+
+	u := *c.url
+
+	if c.all {
+		u.Path = path.Join(u.Path, "/_nodes")
+	} else {
+		u.Path = path.Join(u.Path, "_nodes", node)
 	}
+	q := u.Query()
+	q.Set("filter_path", "cluster_name,nodes.*.name,nodes.*.host,nodes.*.version,nodes.*.build_*")
+	u.RawQuery = q.Encode()
 
 	res, err := c.client.Get(u.String())
 	if err != nil {
-		return nsr, fmt.Errorf("failed to get cluster health from %s://%s:%s%s: %s",
+		return nir, fmt.Errorf("failed to get nodes info from %s://%s:%s%s: %s",
 			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
 	}
 
@@ -1826,14 +2293,138 @@ func (c *Nodes) fetchAndDecodeNodeStats() (nodeStatsResponse, error) {
 	}()
 
 	if res.StatusCode != http.StatusOK {
-		return nsr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+		return nir, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&nsr); err != nil {
+	if err := json.NewDecoder(res.Body).Decode(&nir); err != nil {
 		c.jsonParseFailures.Inc()
-		return nsr, err
+		countJSONParseError(u.Path)
+		return nir, err
+	}
+	return nir, nil
+}
+
+func (c *Nodes) fetchAndDecodeMasterNode() (masterNodeResponse, error) {
+	var mnr masterNodeResponse
+
+	u := *c.url
+	u.Path = path.Join(u.Path, "/_cluster/state/master_node")
+
+	res, err := c.client.Get(u.String())
+	if err != nil {
+		return mnr, fmt.Errorf("failed to get master node from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(c.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return mnr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&mnr); err != nil {
+		c.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return mnr, err
+	}
+	return mnr, nil
+}
+
+// masterNode returns the id of the elected master node, which is fetched once
+// per scrape for all nodes. A failure doesn't affect the node stats, so it's
+// only logged and an empty id is returned.
+func (c *Nodes) masterNode() string {
+	mnr, err := c.fetchAndDecodeMasterNode()
+	if err != nil {
+		_ = level.Warn(c.logger).Log(
+			"msg", "failed to fetch and decode master node",
+			"err", err,
+		)
+		return ""
+	}
+	return mnr.MasterNode
+}
+
+// resolveNode returns the node id the configured node is resolved to. If the
+// node matches several nodes, the lowest node id is chosen to be deterministic.
+func (c *Nodes) resolveNode() (string, error) {
+	nir, err := c.fetchAndDecodeNodesInfo(c.node)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for id := range nir.Nodes {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no node matches %s", c.node)
+	}
+	sort.Strings(ids)
+	_ = level.Info(c.logger).Log(
+		"msg", "resolved node",
+		"node", c.node,
+		"id", ids[0],
+		"name", nir.Nodes[ids[0]].Name,
+	)
+	return ids[0], nil
+}
+
+// nodeSelector returns the node to request the stats and info of, which is
+// the configured node or the node id it was resolved to
+func (c *Nodes) nodeSelector() (string, error) {
+	if c.resolve != NodeResolveStable {
+		return c.node, nil
+	}
+	return c.resolved.get(c.url.String()+"/"+c.node, c.resolveNode)
+}
+
+// collectBuildInfo sends the build info of the nodes from the cached nodes info,
+// and with all nodes the number of nodes per version to detect mixed versions
+// during rolling upgrades. A failure doesn't affect the node stats, so it's only logged.
+func (c *Nodes) collectBuildInfo(ch chan<- prometheus.Metric, node string) {
+	key := fmt.Sprintf("%s/%t/%s", c.url.String(), c.all, node)
+	nir, err := c.infos.get(key, c.buildInfoInterval, func() (nodesInfoResponse, error) {
+		return c.fetchAndDecodeNodesInfo(node)
+	})
+	if err != nil {
+		_ = level.Warn(c.logger).Log(
+			"msg", "failed to fetch and decode nodes info",
+			"err", err,
+		)
+		return
+	}
+	for _, node := range nir.Nodes {
+		ch <- prometheus.MustNewConstMetric(
+			c.buildInfo,
+			prometheus.GaugeValue,
+			1,
+			nir.ClusterName, node.Host, node.Name, node.BuildHash, node.BuildFlavor, node.BuildType, node.Version,
+		)
+	}
+
+	if !c.all {
+		return
+	}
+	versions := make(map[string]int)
+	for _, node := range nir.Nodes {
+		versions[node.Version]++
+	}
+	for version, count := range versions {
+		ch <- prometheus.MustNewConstMetric(
+			c.nodeVersions,
+			prometheus.GaugeValue,
+			float64(count),
+			version,
+		)
 	}
-	return nsr, nil
 }
 
 // Collect gets nodes metric values
@@ -1845,7 +2436,46 @@ func (c *Nodes) Collect(ch chan<- prometheus.Metric) {
 		ch <- c.jsonParseFailures
 	}()
 
-	nodeStatsResp, err := c.fetchAndDecodeNodeStats()
+	node, err := c.nodeSelector()
+	if err != nil {
+		c.up.Set(0)
+		_ = level.Warn(c.logger).Log(
+			"msg", "failed to resolve node",
+			"node", c.node,
+			"err", err,
+		)
+		return
+	}
+
+	master := c.masterNode()
+	attributeValues := make(map[string]int)
+
+	// the stats of every node are sent as soon as the node is decoded
+	n, err := c.fetchAndDecodeNodeStats(node, func(cluster, id string, node NodeStatsNodeResponse) {
+		ch <- prometheus.MustNewConstMetric(
+			c.roleChangesTotal,
+			prometheus.CounterValue,
+			c.roleChanges.observe(c.url.String()+"/"+id, node, time.Now()),
+			cluster, node.Host, node.Name,
+		)
+		c.collectNode(ch, cluster, node)
+		if value, ok := node.Attributes[c.balanceAttribute]; ok {
+			attributeValues[value]++
+		}
+		if master == "" {
+			return
+		}
+		var isMaster float64
+		if id == master {
+			isMaster = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.isMaster,
+			prometheus.GaugeValue,
+			isMaster,
+			cluster, node.Host, node.Name,
+		)
+	})
 	if err != nil {
 		c.up.Set(0)
 		_ = level.Warn(c.logger).Log(
@@ -1855,91 +2485,186 @@ func (c *Nodes) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 	c.up.Set(1)
+	// the resolved node left the cluster, resolve it again on the next scrape
+	if c.resolve == NodeResolveStable && n == 0 {
+		c.resolved.forget(c.url.String() + "/" + c.node)
+	}
+	c.roleChanges.expire(time.Now())
 
-	for _, node := range nodeStatsResp.Nodes {
-		// Handle the node labels metric
-		roles := getRoles(node)
-
-		for _, role := range []string{"master", "data", "client", "ingest"} {
-			if roles[role] {
-				metric := createRoleMetric(role)
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(node),
-					metric.Labels(nodeStatsResp.ClusterName, node)...,
-				)
-			}
+	if c.all && c.balanceAttribute != "" {
+		for value, count := range attributeValues {
+			ch <- prometheus.MustNewConstMetric(
+				c.nodesPerAttribute,
+				prometheus.GaugeValue,
+				float64(count),
+				c.balanceAttribute, value,
+			)
 		}
+	}
 
-		for _, metric := range c.nodeMetrics {
+	c.collectBuildInfo(ch, node)
+}
+
+// collectNode sends the stats of a single node
+func (c *Nodes) collectNode(ch chan<- prometheus.Metric, cluster string, node NodeStatsNodeResponse) {
+	// Handle the node labels metric
+	roles := getRoles(node)
+
+	for _, role := range []string{"master", "data", "client", "ingest"} {
+		if roles[role] {
+			metric := createRoleMetric(role)
 			ch <- prometheus.MustNewConstMetric(
 				metric.Desc,
 				metric.Type,
 				metric.Value(node),
-				metric.Labels(nodeStatsResp.ClusterName, node)...,
+				metric.Labels(cluster, node)...,
 			)
 		}
+	}
 
-		// GC Stats
-		for collector, gcStats := range node.JVM.GC.Collectors {
-			for _, metric := range c.gcCollectionMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(gcStats),
-					metric.Labels(nodeStatsResp.ClusterName, node, collector)...,
-				)
-			}
+	for _, role := range node.Roles {
+		if dataTiers[role] {
+			ch <- prometheus.MustNewConstMetric(
+				c.dataTier,
+				prometheus.GaugeValue,
+				1,
+				cluster, node.Host, node.Name, role,
+			)
 		}
+	}
 
-		// Breaker stats
-		for breaker, bstats := range node.Breakers {
-			for _, metric := range c.breakerMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(bstats),
-					metric.Labels(nodeStatsResp.ClusterName, node, breaker)...,
-				)
-			}
+	for _, metric := range c.nodeMetrics {
+		ch <- prometheus.MustNewConstMetric(
+			metric.Desc,
+			metric.Type,
+			metric.Value(node),
+			metric.Labels(cluster, node)...,
+		)
+	}
+
+	// GC Stats
+	for collector, gcStats := range node.JVM.GC.Collectors {
+		for _, metric := range c.gcCollectionMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(gcStats),
+				metric.Labels(cluster, node, collector)...,
+			)
 		}
+	}
 
-		// Thread Pool stats
-		for pool, pstats := range node.ThreadPool {
-			for _, metric := range c.threadPoolMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(pstats),
-					metric.Labels(nodeStatsResp.ClusterName, node, pool)...,
-				)
-			}
+	// Breaker stats
+	for breaker, bstats := range node.Breakers {
+		for _, metric := range c.breakerMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(bstats),
+				metric.Labels(cluster, node, breaker)...,
+			)
 		}
+	}
 
-		// File System Data Stats
-		for _, fsDataStats := range node.FS.Data {
-			for _, metric := range c.filesystemDataMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(fsDataStats),
-					metric.Labels(nodeStatsResp.ClusterName, node, fsDataStats.Mount, fsDataStats.Path)...,
-				)
-			}
+	// Thread Pool stats
+	for pool, pstats := range node.ThreadPool {
+		for _, metric := range c.threadPoolMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(pstats),
+				metric.Labels(cluster, node, pool)...,
+			)
 		}
+	}
 
-		// File System IO Device Stats
-		for _, fsIODeviceStats := range node.FS.IOStats.Devices {
-			for _, metric := range c.filesystemIODeviceMetrics {
-				ch <- prometheus.MustNewConstMetric(
-					metric.Desc,
-					metric.Type,
-					metric.Value(fsIODeviceStats),
-					metric.Labels(nodeStatsResp.ClusterName, node, fsIODeviceStats.DeviceName)...,
-				)
-			}
+	// File System Data Stats
+	for _, fsDataStats := range node.FS.Data {
+		for _, metric := range c.filesystemDataMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(fsDataStats),
+				metric.Labels(cluster, node, fsDataStats.Mount, fsDataStats.Path)...,
+			)
 		}
+	}
 
+	// File System IO Device Stats
+	for _, fsIODeviceStats := range node.FS.IOStats.Devices {
+		for _, metric := range c.filesystemIODeviceMetrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(fsIODeviceStats),
+				metric.Labels(cluster, node, fsIODeviceStats.DeviceName)...,
+			)
+		}
+	}
+
+	if node.Discovery != nil {
+		c.collectDiscovery(ch, cluster, node)
+	}
+}
+
+// collectDiscovery sends the discovery stats of the node, as far as its
+// release reports them
+func (c *Nodes) collectDiscovery(ch chan<- prometheus.Metric, cluster string, node NodeStatsNodeResponse) {
+	labels := defaultNodeLabelValues(cluster, node)
+	discovery := node.Discovery
+	if queue := discovery.ClusterStateQueue; queue != nil {
+		for state, value := range map[string]int64{
+			"pending":   queue.Pending,
+			"committed": queue.Committed,
+		} {
+			ch <- prometheus.MustNewConstMetric(
+				c.discoveryClusterStateQueue,
+				prometheus.GaugeValue,
+				float64(value),
+				append(labels, state)...,
+			)
+		}
+	}
+	if published := discovery.PublishedClusterStates; published != nil {
+		for typ, value := range map[string]int64{
+			"full":              published.FullStates,
+			"compatible_diff":   published.CompatibleDiffs,
+			"incompatible_diff": published.IncompatibleDiffs,
+		} {
+			ch <- prometheus.MustNewConstMetric(
+				c.discoveryPublishedClusterStates,
+				prometheus.CounterValue,
+				float64(value),
+				append(labels, typ)...,
+			)
+		}
+	}
+	for result, update := range discovery.ClusterStateUpdate {
+		ch <- prometheus.MustNewConstMetric(
+			c.discoveryClusterStateUpdates,
+			prometheus.CounterValue,
+			float64(update.Count),
+			append(labels, result)...,
+		)
+		for stage, millis := range map[string]*int64{
+			"computation":          update.ComputationTimeMillis,
+			"publication":          update.PublicationTimeMillis,
+			"context_construction": update.ContextConstructionTimeMillis,
+			"commit":               update.CommitTimeMillis,
+			"completion":           update.CompletionTimeMillis,
+			"master_apply":         update.MasterApplyTimeMillis,
+			"notification":         update.NotificationTimeMillis,
+		} {
+			// updates which didn't change the cluster state skip most stages
+			if millis == nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.discoveryClusterStateUpdateTime,
+				prometheus.CounterValue,
+				millisToTimeUnit(*millis),
+				append(labels, result, stage)...,
+			)
+		}
 	}
 }
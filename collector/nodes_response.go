@@ -4,8 +4,8 @@ import "encoding/json"
 
 // nodeStatsResponse is a representation of a Elasticsearch Node Stats
 type nodeStatsResponse struct {
-	ClusterName string `json:"cluster_name"`
-	Nodes       map[string]NodeStatsNodeResponse
+	ClusterName string                           `json:"cluster_name"`
+	Nodes       map[string]NodeStatsNodeResponse `json:"nodes"`
 }
 
 // NodeStatsNodeResponse defines node stats information structure for nodes
@@ -27,6 +27,48 @@ type NodeStatsNodeResponse struct {
 	HTTP             map[string]int                             `json:"http"`
 	Transport        NodeStatsTransportResponse                 `json:"transport"`
 	Process          NodeStatsProcessResponse                   `json:"process"`
+	Script           NodeStatsScriptResponse                    `json:"script"`
+	Discovery        *NodeStatsDiscoveryResponse                `json:"discovery"`
+}
+
+// NodeStatsDiscoveryResponse is a representation of the discovery stats of a
+// node. Releases before 7.0 only report the cluster state queue and releases
+// before 7.16 don't report the cluster state update timings.
+type NodeStatsDiscoveryResponse struct {
+	ClusterStateQueue      *NodeStatsDiscoveryClusterStateQueueResponse            `json:"cluster_state_queue"`
+	PublishedClusterStates *NodeStatsDiscoveryPublishedClusterStatesResponse       `json:"published_cluster_states"`
+	ClusterStateUpdate     map[string]NodeStatsDiscoveryClusterStateUpdateResponse `json:"cluster_state_update"`
+}
+
+// NodeStatsDiscoveryClusterStateQueueResponse defines the cluster states received
+// by the node which aren't applied yet
+type NodeStatsDiscoveryClusterStateQueueResponse struct {
+	Total     int64 `json:"total"`
+	Pending   int64 `json:"pending"`
+	Committed int64 `json:"committed"`
+}
+
+// NodeStatsDiscoveryPublishedClusterStatesResponse defines the cluster states
+// received by the node, in full or as diff
+type NodeStatsDiscoveryPublishedClusterStatesResponse struct {
+	FullStates        int64 `json:"full_states"`
+	IncompatibleDiffs int64 `json:"incompatible_diffs"`
+	CompatibleDiffs   int64 `json:"compatible_diffs"`
+}
+
+// NodeStatsDiscoveryClusterStateUpdateResponse defines the cluster state updates
+// of the master service by result (unchanged, success or failure) with the time
+// spent in each stage. Unchanged updates only have a computation and a
+// notification stage.
+type NodeStatsDiscoveryClusterStateUpdateResponse struct {
+	Count                         int64  `json:"count"`
+	ComputationTimeMillis         *int64 `json:"computation_time_millis"`
+	PublicationTimeMillis         *int64 `json:"publication_time_millis"`
+	ContextConstructionTimeMillis *int64 `json:"context_construction_time_millis"`
+	CommitTimeMillis              *int64 `json:"commit_time_millis"`
+	CompletionTimeMillis          *int64 `json:"completion_time_millis"`
+	MasterApplyTimeMillis         *int64 `json:"master_apply_time_millis"`
+	NotificationTimeMillis        *int64 `json:"notification_time_millis"`
 }
 
 // NodeStatsBreakersResponse is a representation of a statistics about the field data circuit breaker
@@ -219,17 +261,18 @@ type NodeStatsIndicesGetResponse struct {
 
 // NodeStatsIndicesSearchResponse defines node stats search information structure for indices
 type NodeStatsIndicesSearchResponse struct {
-	OpenContext  int64 `json:"open_contexts"`
-	QueryTotal   int64 `json:"query_total"`
-	QueryTime    int64 `json:"query_time_in_millis"`
-	QueryCurrent int64 `json:"query_current"`
-	FetchTotal   int64 `json:"fetch_total"`
-	FetchTime    int64 `json:"fetch_time_in_millis"`
-	FetchCurrent int64 `json:"fetch_current"`
-	SuggestTotal int64 `json:"suggest_total"`
-	SuggestTime  int64 `json:"suggest_time_in_millis"`
-	ScrollTotal  int64 `json:"scroll_total"`
-	ScrollTime   int64 `json:"scroll_time_in_millis"`
+	OpenContext   int64 `json:"open_contexts"`
+	QueryTotal    int64 `json:"query_total"`
+	QueryTime     int64 `json:"query_time_in_millis"`
+	QueryCurrent  int64 `json:"query_current"`
+	FetchTotal    int64 `json:"fetch_total"`
+	FetchTime     int64 `json:"fetch_time_in_millis"`
+	FetchCurrent  int64 `json:"fetch_current"`
+	SuggestTotal  int64 `json:"suggest_total"`
+	SuggestTime   int64 `json:"suggest_time_in_millis"`
+	ScrollTotal   int64 `json:"scroll_total"`
+	ScrollTime    int64 `json:"scroll_time_in_millis"`
+	ScrollCurrent int64 `json:"scroll_current"`
 }
 
 // NodeStatsIndicesFlushResponse defines node stats flush information structure for indices
@@ -298,6 +341,13 @@ type NodeStatsOSCPULoadResponse struct {
 	Load15 float64 `json:"15m"`
 }
 
+// NodeStatsScriptResponse is a representation of the script compilation and cache statistics
+type NodeStatsScriptResponse struct {
+	Compilations              int64 `json:"compilations"`
+	CacheEvictions            int64 `json:"cache_evictions"`
+	CompilationLimitTriggered int64 `json:"compilation_limit_triggered"`
+}
+
 // NodeStatsProcessResponse is a representation of a process statistics, memory consumption, cpu usage, open file descriptors
 type NodeStatsProcessResponse struct {
 	Timestamp int64                       `json:"timestamp"`
@@ -376,3 +426,27 @@ type ClusterHealthResponse struct {
 	TimedOut                bool   `json:"timed_out"`
 	UnassignedShards        int64  `json:"unassigned_shards"`
 }
+
+// masterNodeResponse is a representation of the cluster state limited to the
+// id of the elected master node
+type masterNodeResponse struct {
+	MasterNode string `json:"master_node"`
+}
+
+// nodesInfoResponse is a representation of the Elasticsearch Nodes Info, limited
+// to the build details of each node
+type nodesInfoResponse struct {
+	ClusterName string                           `json:"cluster_name"`
+	Nodes       map[string]NodesInfoNodeResponse `json:"nodes"`
+}
+
+// NodesInfoNodeResponse defines the build information of a node. The build
+// flavor and type are only reported since 6.3.
+type NodesInfoNodeResponse struct {
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Version     string `json:"version"`
+	BuildFlavor string `json:"build_flavor"`
+	BuildType   string `json:"build_type"`
+	BuildHash   string `json:"build_hash"`
+}
@@ -1,15 +1,23 @@
 package collector
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestNodesStats(t *testing.T) {
@@ -44,8 +52,12 @@ func TestNodesStats(t *testing.T) {
 				t.Fatalf("Failed to parse URL: %s", err)
 			}
 			u.User = url.UserPassword("elastic", "changeme")
-			c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local")
-			nsr, err := c.fetchAndDecodeNodeStats()
+			c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+			nsr := nodeStatsResponse{Nodes: make(map[string]NodeStatsNodeResponse)}
+			_, err = c.fetchAndDecodeNodeStats("_local", func(cluster, id string, node NodeStatsNodeResponse) {
+				nsr.ClusterName = cluster
+				nsr.Nodes[id] = node
+			})
 			if err != nil {
 				t.Fatalf("Failed to fetch or decode node stats: %s", err)
 			}
@@ -122,6 +134,732 @@ func TestNodesStats(t *testing.T) {
 	}
 }
 
+func TestNodesRoleChanges(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 -e node.master=true -e node.data=true elasticsearch:VERSION
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.http"
+	//  (restart the node with -e node.data=false)
+	tcs := []string{
+		`{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"http":{"current_open":1,"total_opened":1}},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"],"http":{"current_open":1,"total_opened":1}}}}`,
+		`{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","ingest"],"http":{"current_open":1,"total_opened":1}},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"],"http":{"current_open":1,"total_opened":1}}}}`,
+	}
+	var out string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	tracker := newNodeRoleChangeTracker()
+	var c *Nodes
+	for _, out = range tcs {
+		// a new collector is created for every scrape
+		c = NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+		c.roleChanges = tracker
+		testutil.CollectAndCount(c)
+	}
+
+	expected := `
+# HELP elasticsearch_exporter_node_role_changes_total Count of changes of the roles of a node between scrapes
+# TYPE elasticsearch_exporter_node_role_changes_total counter
+elasticsearch_exporter_node_role_changes_total{cluster="elasticsearch",host="127.0.0.1",name="es01"} 1
+elasticsearch_exporter_node_role_changes_total{cluster="elasticsearch",host="127.0.0.2",name="es02"} 0
+`
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_exporter_node_role_changes_total"); err != nil {
+		t.Errorf("Unexpected node role changes: %s", err)
+	}
+
+	// the nodes of another cluster are tracked separately
+	other := `{"cluster_name":"other","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.3","roles":["master","data","ingest"],"http":{"current_open":1,"total_opened":1}}}}`
+	ots := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, other)
+	}))
+	defer ots.Close()
+	ou, err := url.Parse(ots.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c = NewNodes(log.NewNopLogger(), http.DefaultClient, ou, true, "_local", NodeResolveRequest, 0, "")
+	c.roleChanges = tracker
+	expected = `
+# HELP elasticsearch_exporter_node_role_changes_total Count of changes of the roles of a node between scrapes
+# TYPE elasticsearch_exporter_node_role_changes_total counter
+elasticsearch_exporter_node_role_changes_total{cluster="other",host="127.0.0.3",name="es01"} 0
+`
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(c)
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_exporter_node_role_changes_total"); err != nil {
+		t.Errorf("Unexpected node role changes of the other cluster: %s", err)
+	}
+
+	tracker.expire(time.Now().Add(nodeRoleChangeTTL + time.Minute))
+	if len(tracker.nodes) != 0 {
+		t.Errorf("Expected the unseen nodes to be forgotten, got %d", len(tracker.nodes))
+	}
+}
+
+func TestNodesThreadPoolCompletedCounter(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.thread_pool.search"
+	out := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"thread_pool":{"search":{"threads":13,"queue":0,"active":1,"rejected":0,"largest":13,"completed":1892}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, ""))
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %s", err)
+	}
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() != "elasticsearch_thread_pool_completed_count" {
+			continue
+		}
+		found = true
+		if mf.GetType() != dto.MetricType_COUNTER {
+			t.Errorf("Thread pool completed count should be a counter, got %s", mf.GetType())
+		}
+		if v := mf.GetMetric()[0].GetCounter().GetValue(); v != 1892 {
+			t.Errorf("Wrong thread pool completed count: %v", v)
+		}
+	}
+	if !found {
+		t.Errorf("Missing thread pool completed count")
+	}
+}
+
+func TestNodesThreadPoolLargest(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.thread_pool.search,nodes.*.thread_pool.write"
+	out := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"thread_pool":{"search":{"threads":7,"queue":0,"active":0,"rejected":0,"largest":13,"completed":1892},"write":{"threads":8,"queue":0,"active":0,"rejected":0,"largest":8,"completed":421}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	// the search pool shrank again after reaching 13 threads
+	expected := `
+# HELP elasticsearch_thread_pool_largest_count Thread Pool largest threads count
+# TYPE elasticsearch_thread_pool_largest_count gauge
+elasticsearch_thread_pool_largest_count{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="search"} 13
+elasticsearch_thread_pool_largest_count{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="write"} 8
+# HELP elasticsearch_thread_pool_threads_count Thread Pool current threads count
+# TYPE elasticsearch_thread_pool_threads_count gauge
+elasticsearch_thread_pool_threads_count{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="search"} 7
+elasticsearch_thread_pool_threads_count{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="write"} 8
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_thread_pool_largest_count", "elasticsearch_thread_pool_threads_count"); err != nil {
+		t.Errorf("Unexpected thread pool metrics: %s", err)
+	}
+}
+
+func TestNodesSearchContexts(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.2
+	//  curl -XPOST "http://localhost:9200/foo_1/_search?scroll=10m" (without clearing the scrolls)
+	//  curl -XPOST "http://localhost:9200/foo_1/_pit?keep_alive=10m"
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.indices.search"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"indices":{"search":{"open_contexts":5,"query_total":148,"query_time_in_millis":412,"query_current":0,"fetch_total":146,"fetch_time_in_millis":57,"fetch_current":0,"scroll_total":12,"scroll_time_in_millis":3605193,"scroll_current":4,"suggest_total":0,"suggest_time_in_millis":0,"suggest_current":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	// the point in time search holds a search context, but isn't a scroll
+	expected := `
+# HELP elasticsearch_indices_search_open_contexts Current number of open search contexts, including the ones of scrolls and point in time searches
+# TYPE elasticsearch_indices_search_open_contexts gauge
+elasticsearch_indices_search_open_contexts{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 5
+# HELP elasticsearch_indices_search_scroll_current Current number of open scrolls, leaked scrolls keep growing
+# TYPE elasticsearch_indices_search_scroll_current gauge
+elasticsearch_indices_search_scroll_current{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 4
+# HELP elasticsearch_indices_search_scroll_time_seconds Total scroll time in seconds
+# TYPE elasticsearch_indices_search_scroll_time_seconds counter
+elasticsearch_indices_search_scroll_time_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 3605.193
+# HELP elasticsearch_indices_search_scroll_total Total number of scrolls
+# TYPE elasticsearch_indices_search_scroll_total counter
+elasticsearch_indices_search_scroll_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 12
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_indices_search_open_contexts", "elasticsearch_indices_search_scroll_current",
+		"elasticsearch_indices_search_scroll_time_seconds", "elasticsearch_indices_search_scroll_total"); err != nil {
+		t.Errorf("Unexpected search context metrics: %s", err)
+	}
+}
+
+func TestNodesHeapUsedRatio(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e ES_JAVA_OPTS="-Xms1g -Xmx1g" elasticsearch:7.10.2
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.jvm.mem"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"jvm":{"mem":{"heap_used_in_bytes":858993459,"heap_used_percent":80,"heap_committed_in_bytes":1073741824,"heap_max_in_bytes":1073741824,"non_heap_used_in_bytes":157286400,"non_heap_committed_in_bytes":167772160}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %s", err)
+	}
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() != "elasticsearch_jvm_heap_used_ratio" {
+			continue
+		}
+		found = true
+		if v := mf.GetMetric()[0].GetGauge().GetValue(); math.Abs(v-0.8) > 1e-9 {
+			t.Errorf("Wrong heap used ratio: %v", v)
+		}
+	}
+	if !found {
+		t.Errorf("Missing heap used ratio")
+	}
+}
+
+func TestNodesDiscovery(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.discovery"
+	tcs := map[string]struct {
+		out      string
+		expected string
+	}{
+		"7.17.9": {`{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"discovery":{"cluster_state_queue":{"total":3,"pending":1,"committed":2},"published_cluster_states":{"full_states":2,"incompatible_diffs":1,"compatible_diffs":87},"cluster_state_update":{"unchanged":{"count":41,"computation_time_millis":125,"notification_time_millis":3},"success":{"count":89,"computation_time_millis":403,"publication_time_millis":2871,"context_construction_time_millis":58,"commit_time_millis":1204,"completion_time_millis":1390,"master_apply_time_millis":611,"notification_time_millis":17},"failure":{"count":0,"computation_time_millis":0,"publication_time_millis":0,"context_construction_time_millis":0,"commit_time_millis":0,"completion_time_millis":0,"master_apply_time_millis":0,"notification_time_millis":0}}}}}}`, `
+# HELP elasticsearch_discovery_cluster_state_queue Number of cluster states received by the node which aren't applied yet, by state (pending or committed)
+# TYPE elasticsearch_discovery_cluster_state_queue gauge
+elasticsearch_discovery_cluster_state_queue{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",state="committed"} 2
+elasticsearch_discovery_cluster_state_queue{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",state="pending"} 1
+# HELP elasticsearch_discovery_cluster_state_update_seconds Total time spent in each stage of the cluster state updates computed by the node as master in seconds, by result
+# TYPE elasticsearch_discovery_cluster_state_update_seconds counter
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="commit"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="completion"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="computation"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="context_construction"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="master_apply"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="notification"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure",stage="publication"} 0
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="commit"} 1.204
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="completion"} 1.39
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="computation"} 0.403
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="context_construction"} 0.058
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="master_apply"} 0.611
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="notification"} 0.017
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success",stage="publication"} 2.871
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="unchanged",stage="computation"} 0.125
+elasticsearch_discovery_cluster_state_update_seconds{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="unchanged",stage="notification"} 0.003
+# HELP elasticsearch_discovery_cluster_state_updates_total Total number of cluster state updates computed by the node as master, by result (unchanged, success or failure)
+# TYPE elasticsearch_discovery_cluster_state_updates_total counter
+elasticsearch_discovery_cluster_state_updates_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="failure"} 0
+elasticsearch_discovery_cluster_state_updates_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="success"} 89
+elasticsearch_discovery_cluster_state_updates_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",result="unchanged"} 41
+# HELP elasticsearch_discovery_published_cluster_states_total Total number of cluster states received by the node, by type (full, compatible_diff or incompatible_diff)
+# TYPE elasticsearch_discovery_published_cluster_states_total counter
+elasticsearch_discovery_published_cluster_states_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="compatible_diff"} 87
+elasticsearch_discovery_published_cluster_states_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="full"} 2
+elasticsearch_discovery_published_cluster_states_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",type="incompatible_diff"} 1
+`},
+		// only the cluster state queue of zen discovery
+		"6.8.23": {`{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"discovery":{"cluster_state_queue":{"total":0,"pending":0,"committed":0}}}}}`, `
+# HELP elasticsearch_discovery_cluster_state_queue Number of cluster states received by the node which aren't applied yet, by state (pending or committed)
+# TYPE elasticsearch_discovery_cluster_state_queue gauge
+elasticsearch_discovery_cluster_state_queue{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",state="committed"} 0
+elasticsearch_discovery_cluster_state_queue{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01",state="pending"} 0
+`},
+		// no discovery stats at all
+		"5.6.16": {`{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"]}}}`, ``},
+	}
+	for ver, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+		c.infos = newNodesInfoCache()
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(tc.expected),
+			"elasticsearch_discovery_cluster_state_queue", "elasticsearch_discovery_cluster_state_update_seconds",
+			"elasticsearch_discovery_cluster_state_updates_total", "elasticsearch_discovery_published_cluster_states_total"); err != nil {
+			t.Errorf("[%s] Unexpected discovery metrics: %s", ver, err)
+		}
+	}
+}
+
+func TestNodesIsMaster(t *testing.T) {
+	// Testcase created using:
+	//  docker-compose up -d  # two master eligible nodes
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles"
+	//  curl http://localhost:9200/_cluster/state/master_node
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"]},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"]}}}`
+	master := `{"cluster_name":"elasticsearch","master_node":"Xn1qcbFcQdShCM3GNQoKFw"}`
+	var masterRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_nodes/stats":
+			fmt.Fprintln(w, stats)
+		case "/_cluster/state/master_node":
+			masterRequests++
+			fmt.Fprintln(w, master)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	expected := `
+# HELP elasticsearch_node_is_master Whether the node is the elected master of the cluster
+# TYPE elasticsearch_node_is_master gauge
+elasticsearch_node_is_master{cluster="elasticsearch",host="127.0.0.1",name="es01"} 0
+elasticsearch_node_is_master{cluster="elasticsearch",host="127.0.0.2",name="es02"} 1
+`
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_node_is_master"); err != nil {
+		t.Errorf("Unexpected master metrics: %s", err)
+	}
+	// the master is the same for all nodes
+	if masterRequests != 1 {
+		t.Errorf("Wrong number of master node requests: %d", masterRequests)
+	}
+}
+
+func TestNodesPerAttribute(t *testing.T) {
+	// Testcase created using:
+	//  docker-compose up -d  # nodes started with -E node.attr.zone=zone-N
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.attributes"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"attributes":{"zone":"zone-a"}},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"],"attributes":{"zone":"zone-a"}},"kTvP4Ub2QmSZsLd0e3JrHw":{"name":"es03","host":"127.0.0.3","roles":["master","data","ingest"],"attributes":{"zone":"zone-b"}},"a8Lx2cQnRWeF0yN5mZtV7g":{"name":"es04","host":"127.0.0.4","roles":["data"],"attributes":{"zone":"zone-c"}},"Pq3sK9dBTtG1wXhY6oUi4A":{"name":"es05","host":"127.0.0.5","roles":["ingest"],"attributes":{}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	// the node without the attribute isn't counted
+	expected := `
+# HELP elasticsearch_cluster_nodes_per_attribute Number of nodes per value of the node attribute, nodes without the attribute are omitted, only exported with all nodes
+# TYPE elasticsearch_cluster_nodes_per_attribute gauge
+elasticsearch_cluster_nodes_per_attribute{attribute="zone",value="zone-a"} 2
+elasticsearch_cluster_nodes_per_attribute{attribute="zone",value="zone-b"} 1
+elasticsearch_cluster_nodes_per_attribute{attribute="zone",value="zone-c"} 1
+`
+	for _, all := range []bool{true, false} {
+		c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, all, "_local", NodeResolveRequest, 0, "zone")
+		c.infos = newNodesInfoCache()
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		want := expected
+		if !all {
+			want = ""
+		}
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "elasticsearch_cluster_nodes_per_attribute"); err != nil {
+			t.Errorf("Unexpected nodes per attribute with all=%t: %s", all, err)
+		}
+	}
+}
+
+func TestNodesBuildInfo(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_nodes?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.version,nodes.*.build_*"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"]},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"]}}}`
+	info := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","version":"7.6.2","build_flavor":"default","build_type":"docker","build_hash":"ef48eb35cf30adf4db14086e8aabd07ef6fb113f"},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","version":"6.2.4","build_hash":"ccec39f"}}}`
+	var infoRequests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_nodes/stats":
+			fmt.Fprintln(w, stats)
+		case "/_nodes":
+			infoRequests++
+			fmt.Fprintln(w, info)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	infos := newNodesInfoCache()
+	expected := `
+# HELP elasticsearch_node_build_info Build information of the node, always 1
+# TYPE elasticsearch_node_build_info gauge
+elasticsearch_node_build_info{build_flavor="",build_hash="ccec39f",build_type="",cluster="elasticsearch",host="127.0.0.2",name="es02",version="6.2.4"} 1
+elasticsearch_node_build_info{build_flavor="default",build_hash="ef48eb35cf30adf4db14086e8aabd07ef6fb113f",build_type="docker",cluster="elasticsearch",host="127.0.0.1",name="es01",version="7.6.2"} 1
+`
+	for i := 0; i < 2; i++ {
+		// a new collector is created for every scrape
+		c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, time.Hour, "")
+		c.infos = infos
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_node_build_info"); err != nil {
+			t.Errorf("Unexpected node build info: %s", err)
+		}
+	}
+	if infoRequests != 1 {
+		t.Errorf("Nodes info should be cached between scrapes, got %d requests", infoRequests)
+	}
+}
+
+func TestNodesVersions(t *testing.T) {
+	// Testcase created during a rolling upgrade using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.9.3
+	//  docker run -d elasticsearch:7.10.0
+	//  curl "http://localhost:9200/_nodes?filter_path=cluster_name,nodes.*.name,nodes.*.version,nodes.*.build_*"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"]},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","host":"127.0.0.2","roles":["master","data","ingest"]},"F3kWhpSbQvGHBZ3T8BgWBw":{"name":"es03","host":"127.0.0.3","roles":["master","data","ingest"]}}}`
+	info := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","version":"7.10.0","build_flavor":"default","build_type":"docker","build_hash":"51e9d6f22758d0374a0f3f5c6e8f3a7997850f96"},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es02","version":"7.9.3","build_flavor":"default","build_type":"docker","build_hash":"c4138e51121ef06a6404866cddc601906fe5c868"},"F3kWhpSbQvGHBZ3T8BgWBw":{"name":"es03","version":"7.9.3","build_flavor":"default","build_type":"docker","build_hash":"c4138e51121ef06a6404866cddc601906fe5c868"}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_nodes/stats", "/_nodes/_local/stats":
+			fmt.Fprintln(w, stats)
+		case "/_nodes", "/_nodes/_local":
+			fmt.Fprintln(w, info)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	for all, expected := range map[bool]string{
+		true: `
+# HELP elasticsearch_cluster_node_versions Number of nodes per Elasticsearch version, only exported with all nodes
+# TYPE elasticsearch_cluster_node_versions gauge
+elasticsearch_cluster_node_versions{version="7.10.0"} 1
+elasticsearch_cluster_node_versions{version="7.9.3"} 2
+`,
+		// the versions of the local node don't tell anything about the cluster
+		false: ``,
+	} {
+		c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, all, "_local", NodeResolveRequest, 0, "")
+		c.infos = newNodesInfoCache()
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(c)
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_cluster_node_versions"); err != nil {
+			t.Errorf("[all=%t] Unexpected node versions: %s", all, err)
+		}
+	}
+}
+
+func TestNodesResolveStable(t *testing.T) {
+	// Testcase created using:
+	//  docker-compose up -d # with two coordinating only nodes behind a load balancer
+	//  curl "http://localhost:9200/_nodes/_local/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles"
+	//  curl "http://localhost:9200/_nodes/_local?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.version,nodes.*.build_*"
+	stats := map[string]string{
+		"0hHcEFK1S7qMlk8hQCm7wQ": `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es-coord-1","host":"127.0.0.1","roles":["data_hot"]}}}`,
+		"Xn1qcbFcQdShCM3GNQoKFw": `{"cluster_name":"elasticsearch","nodes":{"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es-coord-2","host":"127.0.0.2","roles":["data_hot"]}}}`,
+	}
+	info := map[string]string{
+		"0hHcEFK1S7qMlk8hQCm7wQ": `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es-coord-1","host":"127.0.0.1","version":"7.10.0"}}}`,
+		"Xn1qcbFcQdShCM3GNQoKFw": `{"cluster_name":"elasticsearch","nodes":{"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es-coord-2","host":"127.0.0.2","version":"7.10.0"}}}`,
+	}
+	// the load balancer answers with the other node on every request to an endpoint of _local
+	balanced := []string{"0hHcEFK1S7qMlk8hQCm7wQ", "Xn1qcbFcQdShCM3GNQoKFw"}
+	var requests map[string]int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 2 || parts[0] != "_nodes" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		id := parts[1]
+		if id == "_local" {
+			id = balanced[requests[r.URL.Path]%len(balanced)]
+			requests[r.URL.Path]++
+		}
+		if len(parts) == 3 && parts[2] == "stats" {
+			fmt.Fprintln(w, stats[id])
+			return
+		}
+		fmt.Fprintln(w, info[id])
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	hosts := map[string]string{"es-coord-1": "127.0.0.1", "es-coord-2": "127.0.0.2"}
+	for resolve, expected := range map[string][]string{
+		NodeResolveRequest: {"es-coord-1", "es-coord-2"},
+		NodeResolveStable:  {"es-coord-1", "es-coord-1"},
+	} {
+		requests = make(map[string]int)
+		resolved := newResolvedNodeCache()
+		for scrape, name := range expected {
+			// a new collector is created for every scrape
+			c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, false, "_local", resolve, 0, "")
+			c.infos = newNodesInfoCache()
+			c.resolved = resolved
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(c)
+			tier := fmt.Sprintf(`
+# HELP elasticsearch_node_data_tier Data tier of the node, always 1
+# TYPE elasticsearch_node_data_tier gauge
+elasticsearch_node_data_tier{cluster="elasticsearch",host="%s",name="%s",tier="data_hot"} 1
+`, hosts[name], name)
+			if err := testutil.GatherAndCompare(registry, strings.NewReader(tier), "elasticsearch_node_data_tier"); err != nil {
+				t.Errorf("[%s] Unexpected node in scrape %d: %s", resolve, scrape, err)
+			}
+		}
+	}
+}
+
+func TestNodesDataTier(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e node.roles=master,data_hot,data_content elasticsearch:7.10.0
+	//  docker run -d -e node.roles=data_warm elasticsearch:7.10.0
+	//  docker run -d -e node.roles=master elasticsearch:7.10.0
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es-hot","host":"127.0.0.1","roles":["data_content","data_hot","master"]},"Xn1qcbFcQdShCM3GNQoKFw":{"name":"es-warm","host":"127.0.0.2","roles":["data_warm"]},"Ft3QcNh9Sm2uC0HV5cPzVA":{"name":"es-master","host":"127.0.0.3","roles":["master"]}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	expected := `
+# HELP elasticsearch_node_data_tier Data tier of the node, always 1
+# TYPE elasticsearch_node_data_tier gauge
+elasticsearch_node_data_tier{cluster="elasticsearch",host="127.0.0.1",name="es-hot",tier="data_hot"} 1
+elasticsearch_node_data_tier{cluster="elasticsearch",host="127.0.0.2",name="es-warm",tier="data_warm"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "elasticsearch_node_data_tier"); err != nil {
+		t.Errorf("Unexpected node data tiers: %s", err)
+	}
+}
+
+func TestNodesScript(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.6.2
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.script"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"script":{"compilations":182,"cache_evictions":57,"compilation_limit_triggered":12}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	expected := `
+# HELP elasticsearch_script_cache_evictions_total Count of script cache evictions
+# TYPE elasticsearch_script_cache_evictions_total counter
+elasticsearch_script_cache_evictions_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 57
+# HELP elasticsearch_script_compilation_limit_triggered_total Count of script compilations rejected by the compilation rate limit
+# TYPE elasticsearch_script_compilation_limit_triggered_total counter
+elasticsearch_script_compilation_limit_triggered_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 12
+# HELP elasticsearch_script_compilations_total Count of script compilations
+# TYPE elasticsearch_script_compilations_total counter
+elasticsearch_script_compilations_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 182
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_script_compilations_total", "elasticsearch_script_cache_evictions_total", "elasticsearch_script_compilation_limit_triggered_total"); err != nil {
+		t.Errorf("Unexpected script metrics: %s", err)
+	}
+}
+
+func TestNodesTransport(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.6.2
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,nodes.*.host,nodes.*.roles,nodes.*.transport"
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"transport":{"server_open":26,"rx_count":1536,"rx_size_in_bytes":4413262,"tx_count":1536,"tx_size_in_bytes":3302741}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	expected := `
+# HELP elasticsearch_transport_rx_size_bytes_total Total number of bytes received
+# TYPE elasticsearch_transport_rx_size_bytes_total counter
+elasticsearch_transport_rx_size_bytes_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 4.413262e+06
+# HELP elasticsearch_transport_server_open_connections Current number of inbound transport connections
+# TYPE elasticsearch_transport_server_open_connections gauge
+elasticsearch_transport_server_open_connections{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 26
+# HELP elasticsearch_transport_tx_size_bytes_total Total number of bytes sent
+# TYPE elasticsearch_transport_tx_size_bytes_total counter
+elasticsearch_transport_tx_size_bytes_total{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 3.302741e+06
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_transport_rx_size_bytes_total", "elasticsearch_transport_server_open_connections", "elasticsearch_transport_tx_size_bytes_total"); err != nil {
+		t.Errorf("Unexpected transport metrics: %s", err)
+	}
+}
+
+func TestNodesFilterPath(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  curl "http://localhost:9200/_nodes/stats?filter_path=cluster_name,nodes.*.name,...,nodes.*.discovery"
+	// with the stats of all sections but the transport left out
+	stats := `{"cluster_name":"elasticsearch","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01","host":"127.0.0.1","roles":["master","data","ingest"],"transport":{"server_open":26,"rx_count":1536,"rx_size_in_bytes":4413262,"tx_count":1536,"tx_size_in_bytes":3302741}}}}`
+	filterPath := "cluster_name,nodes.*.name,nodes.*.host,nodes.*.timestamp,nodes.*.transport_address,nodes.*.hostname,nodes.*.roles,nodes.*.attributes," +
+		"nodes.*.indices,nodes.*.os,nodes.*.network,nodes.*.fs,nodes.*.thread_pool,nodes.*.jvm,nodes.*.breakers,nodes.*.http," +
+		"nodes.*.transport,nodes.*.process,nodes.*.script,nodes.*.discovery"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_nodes/stats" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("filter_path"); got != filterPath {
+			t.Errorf("Wrong filter path: %s", got)
+		}
+		fmt.Fprintln(w, stats)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	c := NewNodes(log.NewNopLogger(), http.DefaultClient, u, true, "_local", NodeResolveRequest, 0, "")
+	c.infos = newNodesInfoCache()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	expected := `
+# HELP elasticsearch_node_stats_up Was the last scrape of the ElasticSearch nodes endpoint successful.
+# TYPE elasticsearch_node_stats_up gauge
+elasticsearch_node_stats_up 1
+# HELP elasticsearch_transport_server_open_connections Current number of inbound transport connections
+# TYPE elasticsearch_transport_server_open_connections gauge
+elasticsearch_transport_server_open_connections{cluster="elasticsearch",es_client_node="false",es_data_node="true",es_ingest_node="true",es_master_node="true",host="127.0.0.1",name="es01"} 26
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"elasticsearch_node_stats_up", "elasticsearch_transport_server_open_connections"); err != nil {
+		t.Errorf("Unexpected filtered node stats: %s", err)
+	}
+}
+
+func TestNodeStatsSections(t *testing.T) {
+	// every decoded field of the node stats has to pass the filter path
+	fields := jsonFields(reflect.TypeOf(NodeStatsNodeResponse{}))
+	if !reflect.DeepEqual(fields, nodeStatsSections) {
+		t.Errorf("Node stats sections %v don't match the fields of the response %v", nodeStatsSections, fields)
+	}
+}
+
+// jsonFields returns the JSON names of the fields of the struct type t in
+// their order, including those of embedded structs
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			fields = append(fields, jsonFields(embedded)...)
+			continue
+		}
+		fields = append(fields, strings.Split(field.Tag.Get("json"), ",")[0])
+	}
+	return fields
+}
+
 type basicAuth struct {
 	User string
 	Pass string
@@ -160,3 +898,59 @@ func (h *basicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	h.Next.ServeHTTP(w, r)
 }
+
+func TestDecodeNodeStatsOrder(t *testing.T) {
+	// the cluster name is known to every node, wherever it's listed
+	for name, out := range map[string]string{
+		"cluster first": `{"_nodes":{"total":2,"successful":2,"failed":0},"cluster_name":"elasticsearch","nodes":{"a":{"name":"es01"},"b":{"name":"es02"}}}`,
+		"cluster last":  `{"nodes":{"a":{"name":"es01"},"b":{"name":"es02"}},"cluster_name":"elasticsearch"}`,
+	} {
+		nodes := make(map[string]string)
+		n, err := decodeNodeStats(strings.NewReader(out), func(cluster, id string, node NodeStatsNodeResponse) {
+			if cluster != "elasticsearch" {
+				t.Errorf("[%s] Wrong cluster of %s: %q", name, id, cluster)
+			}
+			nodes[id] = node.Name
+		})
+		if err != nil {
+			t.Fatalf("[%s] Failed to decode node stats: %s", name, err)
+		}
+		if n != 2 || !reflect.DeepEqual(nodes, map[string]string{"a": "es01", "b": "es02"}) {
+			t.Errorf("[%s] Wrong nodes: %d %v", name, n, nodes)
+		}
+	}
+
+	if _, err := decodeNodeStats(strings.NewReader(`{"cluster_name":"elasticsearch","nodes":{"a":{"name":`), func(string, string, NodeStatsNodeResponse) {}); err == nil {
+		t.Errorf("Truncated node stats decoded without error")
+	}
+}
+
+// BenchmarkNodeStats compares decoding the whole node stats at once with
+// streaming them node by node, which only ever holds a single node
+func BenchmarkNodeStats(b *testing.B) {
+	nsr := nodeStatsResponse{ClusterName: "elasticsearch", Nodes: make(map[string]NodeStatsNodeResponse)}
+	for i := 0; i < 500; i++ {
+		nsr.Nodes[fmt.Sprintf("node-%04d", i)] = NodeStatsNodeResponse{Name: fmt.Sprintf("es%04d", i)}
+	}
+	out, err := json.Marshal(nsr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("whole", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			var nsr nodeStatsResponse
+			if err := json.NewDecoder(bytes.NewReader(out)).Decode(&nsr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if _, err := decodeNodeStats(bytes.NewReader(out), func(string, string, NodeStatsNodeResponse) {}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
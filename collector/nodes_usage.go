@@ -0,0 +1,197 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NodesUsage information struct
+type NodesUsage struct {
+	logger  log.Logger
+	client  *http.Client
+	url     *url.URL
+	actions map[string]bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	restActions  *prometheus.Desc
+	aggregations *prometheus.Desc
+}
+
+// NewNodesUsage defines Nodes Usage Prometheus metrics. Only the REST actions
+// named in actions are exported, all of them if actions is empty.
+func NewNodesUsage(logger log.Logger, client *http.Client, url *url.URL, actions []string) *NodesUsage {
+	var included map[string]bool
+	if len(actions) > 0 {
+		included = make(map[string]bool, len(actions))
+		for _, action := range actions {
+			included[action] = true
+		}
+	}
+	return &NodesUsage{
+		logger:  logger,
+		client:  client,
+		url:     url,
+		actions: included,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "nodes_usage", "up"),
+			Help: "Was the last scrape of the ElasticSearch nodes usage endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "nodes_usage", "total_scrapes"),
+			Help: "Current total ElasticSearch nodes usage scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "nodes_usage", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		restActions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "rest_actions_total"),
+			"Total number of calls of the REST action on the node since it started",
+			[]string{"node", "action"}, nil,
+		),
+		aggregations: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "aggregations_usage_total"),
+			"Total number of uses of the aggregation type on the node since it started, summed up across value sources",
+			[]string{"node", "aggregation"}, nil,
+		),
+	}
+}
+
+// Describe add Nodes Usage metrics descriptions
+func (nu *NodesUsage) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nu.restActions
+	ch <- nu.aggregations
+	ch <- nu.up.Desc()
+	ch <- nu.totalScrapes.Desc()
+	ch <- nu.jsonParseFailures.Desc()
+}
+
+func (nu *NodesUsage) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := nu.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(nu.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		nu.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+func (nu *NodesUsage) fetchAndDecodeNodesUsage() (nodesUsageResponse, error) {
+	var nur nodesUsageResponse
+
+	u := *nu.url
+	u.Path = path.Join(u.Path, "/_nodes/usage")
+	err := nu.getAndParseURL(&u, &nur)
+	return nur, err
+}
+
+// fetchAndDecodeNodeNames returns the names of the nodes by id, as the usage
+// of the nodes is only reported by id
+func (nu *NodesUsage) fetchAndDecodeNodeNames() (map[string]string, error) {
+	var nnr nodesNamesResponse
+
+	u := *nu.url
+	u.Path = path.Join(u.Path, "/_nodes")
+	q := u.Query()
+	q.Set("filter_path", "nodes.*.name")
+	u.RawQuery = q.Encode()
+	if err := nu.getAndParseURL(&u, &nnr); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(nnr.Nodes))
+	for id, node := range nnr.Nodes {
+		names[id] = node.Name
+	}
+	return names, nil
+}
+
+// Collect gets Nodes Usage metric values
+func (nu *NodesUsage) Collect(ch chan<- prometheus.Metric) {
+	nu.totalScrapes.Inc()
+	defer func() {
+		ch <- nu.up
+		ch <- nu.totalScrapes
+		ch <- nu.jsonParseFailures
+	}()
+
+	nur, err := nu.fetchAndDecodeNodesUsage()
+	if err != nil {
+		nu.up.Set(0)
+		_ = level.Warn(nu.logger).Log(
+			"msg", "failed to fetch and decode nodes usage",
+			"err", err,
+		)
+		return
+	}
+	names, err := nu.fetchAndDecodeNodeNames()
+	if err != nil {
+		nu.up.Set(0)
+		_ = level.Warn(nu.logger).Log(
+			"msg", "failed to fetch and decode node names",
+			"err", err,
+		)
+		return
+	}
+	nu.up.Set(1)
+
+	for id, node := range nur.Nodes {
+		// a node which joined in between is exported by its id
+		name, ok := names[id]
+		if !ok {
+			name = id
+		}
+		for action, count := range node.RestActions {
+			if nu.actions != nil && !nu.actions[action] {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(
+				nu.restActions,
+				prometheus.CounterValue,
+				float64(count),
+				name, action,
+			)
+		}
+		for aggregation, sources := range node.Aggregations {
+			var count int64
+			for _, c := range sources {
+				count += c
+			}
+			ch <- prometheus.MustNewConstMetric(
+				nu.aggregations,
+				prometheus.CounterValue,
+				float64(count),
+				name, aggregation,
+			)
+		}
+	}
+}
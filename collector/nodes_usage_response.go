@@ -0,0 +1,23 @@
+package collector
+
+// nodesUsageResponse is a representation of the Elasticsearch nodes usage API
+type nodesUsageResponse struct {
+	ClusterName string                           `json:"cluster_name"`
+	Nodes       map[string]nodeUsageNodeResponse `json:"nodes"`
+}
+
+// nodeUsageNodeResponse defines the usage of the features of a single node
+// since it started. The aggregations are counted by type and value source,
+// e.g. terms on keyword fields. They are only reported since 7.8.
+type nodeUsageNodeResponse struct {
+	RestActions  map[string]int64            `json:"rest_actions"`
+	Aggregations map[string]map[string]int64 `json:"aggregations"`
+}
+
+// nodesNamesResponse is a representation of the nodes info API filtered to the
+// names of the nodes
+type nodesNamesResponse struct {
+	Nodes map[string]struct {
+		Name string `json:"name"`
+	} `json:"nodes"`
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNodesUsage(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:7.10.0
+	//  curl http://localhost:9200/_nodes/usage
+	//  curl "http://localhost:9200/_nodes?filter_path=nodes.*.name"
+	usage := `{"_nodes":{"total":1,"successful":1,"failed":0},"cluster_name":"docker-cluster","nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"timestamp":1606305600000,"since":1606302000000,"rest_actions":{"nodes_usage_action":2,"search_action":148,"bulk_action":12,"cluster_health_action":31},"aggregations":{"terms":{"keyword":40,"long":2},"date_histogram":{"date":17}}}}}`
+	names := `{"nodes":{"0hHcEFK1S7qMlk8hQCm7wQ":{"name":"es01"}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_nodes/usage":
+			fmt.Fprintln(w, usage)
+		case "/_nodes":
+			fmt.Fprintln(w, names)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	aggregations := `
+# HELP elasticsearch_node_aggregations_usage_total Total number of uses of the aggregation type on the node since it started, summed up across value sources
+# TYPE elasticsearch_node_aggregations_usage_total counter
+elasticsearch_node_aggregations_usage_total{aggregation="date_histogram",node="es01"} 17
+elasticsearch_node_aggregations_usage_total{aggregation="terms",node="es01"} 42
+`
+	tcs := map[string]struct {
+		actions  []string
+		expected string
+	}{
+		"all": {nil, aggregations + `
+# HELP elasticsearch_node_rest_actions_total Total number of calls of the REST action on the node since it started
+# TYPE elasticsearch_node_rest_actions_total counter
+elasticsearch_node_rest_actions_total{action="bulk_action",node="es01"} 12
+elasticsearch_node_rest_actions_total{action="cluster_health_action",node="es01"} 31
+elasticsearch_node_rest_actions_total{action="nodes_usage_action",node="es01"} 2
+elasticsearch_node_rest_actions_total{action="search_action",node="es01"} 148
+`},
+		"included": {[]string{"search_action", "bulk_action"}, aggregations + `
+# HELP elasticsearch_node_rest_actions_total Total number of calls of the REST action on the node since it started
+# TYPE elasticsearch_node_rest_actions_total counter
+elasticsearch_node_rest_actions_total{action="bulk_action",node="es01"} 12
+elasticsearch_node_rest_actions_total{action="search_action",node="es01"} 148
+`},
+	}
+	for name, tc := range tcs {
+		c := NewNodesUsage(log.NewNopLogger(), http.DefaultClient, u, tc.actions)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(tc.expected),
+			"elasticsearch_node_aggregations_usage_total", "elasticsearch_node_rest_actions_total"); err != nil {
+			t.Errorf("[%s] Unexpected nodes usage metrics: %s", name, err)
+		}
+	}
+}
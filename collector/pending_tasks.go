@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unknownPendingTaskSource is the source label of pending tasks without a source
+const unknownPendingTaskSource = "unknown"
+
+// PendingTasks information struct
+type PendingTasks struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	bySource *prometheus.Desc
+}
+
+// NewPendingTasks defines Pending Tasks Prometheus metrics
+func NewPendingTasks(logger log.Logger, client *http.Client, url *url.URL) *PendingTasks {
+	return &PendingTasks{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "pending_tasks", "up"),
+			Help: "Was the last scrape of the ElasticSearch cluster pending tasks endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "pending_tasks", "total_scrapes"),
+			Help: "Current total ElasticSearch cluster pending tasks scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "pending_tasks", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		bySource: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cluster", "pending_tasks_by_source"),
+			"Number of cluster-level changes which have not yet been executed, by the kind of their source",
+			[]string{"source"}, nil,
+		),
+	}
+}
+
+// Describe add Pending Tasks metrics descriptions
+func (pt *PendingTasks) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pt.bySource
+	ch <- pt.up.Desc()
+	ch <- pt.totalScrapes.Desc()
+	ch <- pt.jsonParseFailures.Desc()
+}
+
+func (pt *PendingTasks) fetchAndDecodePendingTasks() (pendingTasksResponse, error) {
+	var ptr pendingTasksResponse
+
+	u := *pt.url
+	u.Path = path.Join(u.Path, "/_cluster/pending_tasks")
+	res, err := pt.client.Get(u.String())
+	if err != nil {
+		return ptr, fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(pt.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return ptr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&ptr); err != nil {
+		pt.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return ptr, err
+	}
+	return ptr, nil
+}
+
+// pendingTaskSource returns the kind of a pending task from its source, which
+// is followed by the affected index, shard or node, e.g. "create-index [logs],
+// cause [api]" or "shard-started StartedShardEntry{...}" become create-index
+// and shard-started. Only the kind is used to bound the label cardinality.
+func pendingTaskSource(source string) string {
+	source = strings.TrimSpace(source)
+	if i := strings.IndexAny(source, " [({,"); i >= 0 {
+		source = source[:i]
+	}
+	if source == "" {
+		return unknownPendingTaskSource
+	}
+	return strings.ToLower(source)
+}
+
+// countPendingTasksBySource returns the number of pending tasks by the kind of their source
+func countPendingTasksBySource(ptr pendingTasksResponse) map[string]int {
+	counts := make(map[string]int)
+	for _, task := range ptr.Tasks {
+		counts[pendingTaskSource(task.Source)]++
+	}
+	return counts
+}
+
+// Collect gets Pending Tasks metric values
+func (pt *PendingTasks) Collect(ch chan<- prometheus.Metric) {
+	pt.totalScrapes.Inc()
+	defer func() {
+		ch <- pt.up
+		ch <- pt.totalScrapes
+		ch <- pt.jsonParseFailures
+	}()
+
+	ptr, err := pt.fetchAndDecodePendingTasks()
+	if err != nil {
+		pt.up.Set(0)
+		_ = level.Warn(pt.logger).Log(
+			"msg", "failed to fetch and decode cluster pending tasks",
+			"err", err,
+		)
+		return
+	}
+	pt.up.Set(1)
+
+	for source, count := range countPendingTasksBySource(ptr) {
+		ch <- prometheus.MustNewConstMetric(
+			pt.bySource,
+			prometheus.GaugeValue,
+			float64(count),
+			source,
+		)
+	}
+}
@@ -0,0 +1,15 @@
+package collector
+
+// pendingTasksResponse is a representation of the Elasticsearch cluster pending tasks API
+type pendingTasksResponse struct {
+	Tasks []pendingTaskResponse `json:"tasks"`
+}
+
+// pendingTaskResponse defines a cluster state update waiting for the master
+type pendingTaskResponse struct {
+	InsertOrder       int64  `json:"insert_order"`
+	Priority          string `json:"priority"`
+	Source            string `json:"source"`
+	Executing         bool   `json:"executing"`
+	TimeInQueueMillis int64  `json:"time_in_queue_millis"`
+}
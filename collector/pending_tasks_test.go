@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPendingTasks(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 -e discovery.type=single-node elasticsearch:VERSION
+	//  (create indices, update mappings and restart nodes in a loop)
+	//  curl http://localhost:9200/_cluster/pending_tasks
+	tcs := map[string]string{
+		"7.10.2": `{"tasks":[{"insert_order":1042,"priority":"URGENT","source":"create-index [logs-2021.02.01], cause [auto(bulk api)]","executing":true,"time_in_queue_millis":86,"time_in_queue":"86ms"},{"insert_order":1043,"priority":"HIGH","source":"put-mapping [logs-2021.01.31/bG9ncy0yMDIxLjAxLjMx]","executing":false,"time_in_queue_millis":84,"time_in_queue":"84ms"},{"insert_order":1044,"priority":"HIGH","source":"put-mapping [logs-2021.01.30/bG9ncy0yMDIxLjAxLjMw]","executing":false,"time_in_queue_millis":80,"time_in_queue":"80ms"},{"insert_order":1045,"priority":"HIGH","source":"put-mapping [metrics-2021.01.31/bWV0cmljcy0yMDIx]","executing":false,"time_in_queue_millis":77,"time_in_queue":"77ms"},{"insert_order":1046,"priority":"URGENT","source":"shard-started StartedShardEntry{shardId [[logs-2021.02.01][0]], allocationId [dXpDbHNlRkNRZ3VjUHdOaw], primary term [1], message [after new shard recovery]}","executing":false,"time_in_queue_millis":52,"time_in_queue":"52ms"},{"insert_order":1047,"priority":"URGENT","source":"shard-started StartedShardEntry{shardId [[logs-2021.02.01][1]], allocationId [VmhXc0ZWdHBRbEN3aUd6dQ], primary term [1], message [after new shard recovery]}","executing":false,"time_in_queue_millis":51,"time_in_queue":"51ms"},{"insert_order":1048,"priority":"NORMAL","source":"cluster_reroute(reroute after starting shards)","executing":false,"time_in_queue_millis":12,"time_in_queue":"12ms"}]}`,
+	}
+	expected := `
+# HELP elasticsearch_cluster_pending_tasks_by_source Number of cluster-level changes which have not yet been executed, by the kind of their source
+# TYPE elasticsearch_cluster_pending_tasks_by_source gauge
+elasticsearch_cluster_pending_tasks_by_source{source="cluster_reroute"} 1
+elasticsearch_cluster_pending_tasks_by_source{source="create-index"} 1
+elasticsearch_cluster_pending_tasks_by_source{source="put-mapping"} 3
+elasticsearch_cluster_pending_tasks_by_source{source="shard-started"} 2
+# HELP elasticsearch_pending_tasks_up Was the last scrape of the ElasticSearch cluster pending tasks endpoint successful.
+# TYPE elasticsearch_pending_tasks_up gauge
+elasticsearch_pending_tasks_up 1
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewPendingTasks(log.NewNopLogger(), http.DefaultClient, u)
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+			"elasticsearch_cluster_pending_tasks_by_source", "elasticsearch_pending_tasks_up"); err != nil {
+			t.Errorf("[%s] Unexpected pending tasks metrics: %s", ver, err)
+		}
+	}
+}
+
+func TestPendingTaskSource(t *testing.T) {
+	for source, want := range map[string]string{
+		"put-mapping":                                   "put-mapping",
+		"put-mapping [logs/bG9ncw]":                     "put-mapping",
+		"create-index [logs], cause [api]":              "create-index",
+		"shard-started StartedShardEntry{...}":          "shard-started",
+		"cluster_reroute(api)":                          "cluster_reroute",
+		"node-join[{node-1}{abc} join existing leader]": "node-join",
+		"ILM-Execute":                                   "ilm-execute",
+		"":                                              "unknown",
+		"  ":                                            "unknown",
+	} {
+		if got := pendingTaskSource(source); got != want {
+			t.Errorf("Wrong source for %q: got %q, want %q", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RemoteInfo information struct
+type RemoteInfo struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	up                              prometheus.Gauge
+	unreachableClusters             prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+}
+
+// NewRemoteInfo defines Remote Cluster Info Prometheus metrics
+func NewRemoteInfo(logger log.Logger, client *http.Client, url *url.URL) *RemoteInfo {
+	return &RemoteInfo{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "remote_info", "up"),
+			Help: "Was the last scrape of the ElasticSearch remote cluster info endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "remote_info", "total_scrapes"),
+			Help: "Current total ElasticSearch remote cluster info scrapes.",
+		}),
+		unreachableClusters: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "remote_clusters", "unreachable_total"),
+			Help: "Number of configured remote clusters which are not connected",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "remote_info", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+	}
+}
+
+// Describe add Remote Cluster Info metrics descriptions
+func (ri *RemoteInfo) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ri.up.Desc()
+	ch <- ri.totalScrapes.Desc()
+	ch <- ri.unreachableClusters.Desc()
+	ch <- ri.jsonParseFailures.Desc()
+}
+
+func (ri *RemoteInfo) fetchAndDecodeRemoteInfo() (remoteInfoResponse, error) {
+	var rir remoteInfoResponse
+
+	u := *ri.url
+	u.Path = path.Join(u.Path, "/_remote/info")
+	res, err := ri.client.Get(u.String())
+	if err != nil {
+		return rir, fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(ri.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return rir, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&rir); err != nil {
+		ri.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return rir, err
+	}
+	return rir, nil
+}
+
+// countUnreachableClusters returns the number of remote clusters reporting connected=false
+func countUnreachableClusters(rir remoteInfoResponse) int {
+	var c int
+	for _, remote := range rir {
+		if !remote.Connected {
+			c++
+		}
+	}
+	return c
+}
+
+// Collect gets Remote Cluster Info metric values
+func (ri *RemoteInfo) Collect(ch chan<- prometheus.Metric) {
+	ri.totalScrapes.Inc()
+	defer func() {
+		ch <- ri.up
+		ch <- ri.totalScrapes
+		ch <- ri.jsonParseFailures
+		ch <- ri.unreachableClusters
+	}()
+
+	rir, err := ri.fetchAndDecodeRemoteInfo()
+	if err != nil {
+		ri.unreachableClusters.Set(0)
+		ri.up.Set(0)
+		_ = level.Warn(ri.logger).Log(
+			"msg", "failed to fetch and decode remote cluster info",
+			"err", err,
+		)
+		return
+	}
+	ri.up.Set(1)
+
+	ri.unreachableClusters.Set(float64(countUnreachableClusters(rir)))
+}
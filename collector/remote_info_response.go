@@ -0,0 +1,15 @@
+package collector
+
+// remoteInfoResponse is a representation of the Elasticsearch remote cluster info API
+// keyed by the alias of the remote cluster
+type remoteInfoResponse map[string]remoteClusterResponse
+
+// remoteClusterResponse defines the connection state of a single remote cluster
+type remoteClusterResponse struct {
+	Connected                bool   `json:"connected"`
+	Mode                     string `json:"mode"`
+	NumNodesConnected        int64  `json:"num_nodes_connected"`
+	InitialConnectTimeout    string `json:"initial_connect_timeout"`
+	SkipUnavailable          bool   `json:"skip_unavailable"`
+	MaxConnectionsPerCluster int64  `json:"max_connections_per_cluster"`
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRemoteInfo(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/_cluster/settings --header "Content-Type: application/json" -d '
+	//  {
+	//      "persistent": {
+	//          "cluster.remote.cluster_one.seeds": ["127.0.0.1:9300"],
+	//          "cluster.remote.cluster_two.seeds": ["10.0.0.2:9300"],
+	//          "cluster.remote.cluster_three.seeds": ["10.0.0.3:9300"]
+	//      }
+	//  }'
+	//  curl http://localhost:9200/_remote/info
+	tcs := map[string]string{
+		"7.6.2": `{"cluster_one":{"seeds":["127.0.0.1:9300"],"connected":true,"num_nodes_connected":1,"max_connections_per_cluster":3,"initial_connect_timeout":"30s","skip_unavailable":false},"cluster_two":{"seeds":["10.0.0.2:9300"],"connected":false,"num_nodes_connected":0,"max_connections_per_cluster":3,"initial_connect_timeout":"30s","skip_unavailable":false},"cluster_three":{"seeds":["10.0.0.3:9300"],"connected":false,"num_nodes_connected":0,"max_connections_per_cluster":3,"initial_connect_timeout":"30s","skip_unavailable":true}}`,
+	}
+	expected := `
+# HELP elasticsearch_remote_clusters_unreachable_total Number of configured remote clusters which are not connected
+# TYPE elasticsearch_remote_clusters_unreachable_total gauge
+elasticsearch_remote_clusters_unreachable_total 2
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewRemoteInfo(log.NewNopLogger(), http.DefaultClient, u)
+		rir, err := c.fetchAndDecodeRemoteInfo()
+		if err != nil {
+			t.Fatalf("Failed to fetch or decode remote info: %s", err)
+		}
+		if len(rir) != 3 {
+			t.Errorf("[%s] Wrong number of remote clusters: %d", ver, len(rir))
+		}
+		if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "elasticsearch_remote_clusters_unreachable_total"); err != nil {
+			t.Errorf("[%s] Unexpected unreachable remote clusters: %s", ver, err)
+		}
+	}
+}
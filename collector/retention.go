@@ -0,0 +1,265 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// RetentionMinInterval is the shortest interval the oldest documents are
+	// searched in, as the aggregations read the timestamps of every document
+	RetentionMinInterval = 5 * time.Minute
+
+	// retentionMaxIndices limits the buckets of the aggregation by index, it's
+	// the default of search.max_buckets in 7.x
+	retentionMaxIndices = 10000
+)
+
+// retentionCacheEntry is the result of the last search for the oldest documents
+type retentionCacheEntry struct {
+	fetched time.Time
+	oldest  map[string]float64
+	err     error
+	// done is closed when the running search finishes, nil if none runs
+	done chan struct{}
+}
+
+// retentionCache keeps the oldest documents of every queried URL, indices and
+// field, as a new collector is created for every scrape
+type retentionCache struct {
+	mu      sync.Mutex
+	entries map[string]*retentionCacheEntry
+}
+
+var retentionResults = newRetentionCache()
+
+func newRetentionCache() *retentionCache {
+	return &retentionCache{
+		entries: make(map[string]*retentionCacheEntry),
+	}
+}
+
+// get returns the cached oldest documents of key, or the error of the search,
+// if the search isn't older than maxAge. Otherwise it calls fetch and caches
+// the result. Only one search runs per key at a time, concurrent scrapes of
+// the key wait for its result, while other keys aren't blocked.
+func (c *retentionCache) get(key string, maxAge time.Duration, fetch func() (map[string]float64, error)) (map[string]float64, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &retentionCacheEntry{}
+		c.entries[key] = entry
+	}
+	if done := entry.done; done != nil {
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return entry.oldest, entry.err
+	}
+	if !entry.fetched.IsZero() && time.Since(entry.fetched) < maxAge {
+		defer c.mu.Unlock()
+		return entry.oldest, entry.err
+	}
+	done := make(chan struct{})
+	entry.done = done
+	c.mu.Unlock()
+
+	oldest, err := fetch()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a failed search isn't retried before maxAge either, as it's likely to
+	// time out again
+	entry.fetched = time.Now()
+	entry.oldest, entry.err = oldest, err
+	entry.done = nil
+	close(done)
+	return oldest, err
+}
+
+// Retention information struct
+type Retention struct {
+	logger         log.Logger
+	client         *http.Client
+	url            *url.URL
+	indices        []string
+	timestampField string
+	interval       time.Duration
+	cache          *retentionCache
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	oldestDocument *prometheus.Desc
+}
+
+// NewRetention defines Retention Prometheus metrics. The oldest document of
+// every index matching the index patterns, all indices if indices is empty, is
+// searched by the timestamp field at most once per interval.
+func NewRetention(logger log.Logger, client *http.Client, url *url.URL, indices []string, timestampField string, interval time.Duration) *Retention {
+	return &Retention{
+		logger:         logger,
+		client:         client,
+		url:            url,
+		indices:        indices,
+		timestampField: timestampField,
+		interval:       interval,
+		cache:          retentionResults,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "retention", "up"),
+			Help: "Was the last scrape of the ElasticSearch oldest documents successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "retention", "total_scrapes"),
+			Help: "Current total ElasticSearch oldest documents scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "retention", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		oldestDocument: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "oldest_document_timestamp_seconds"),
+			"Timestamp of the oldest document of the index by the configured timestamp field, omitted for indices without it",
+			[]string{"index"}, nil,
+		),
+	}
+}
+
+// Describe add Retention metrics descriptions
+func (r *Retention) Describe(ch chan<- *prometheus.Desc) {
+	ch <- r.oldestDocument
+	ch <- r.up.Desc()
+	ch <- r.totalScrapes.Desc()
+	ch <- r.jsonParseFailures.Desc()
+}
+
+// indexPath returns the index part of the request path, like Elasticsearch
+// the patterns can exclude indices with a leading -
+func (r *Retention) indexPath() string {
+	if len(r.indices) == 0 {
+		return "_all"
+	}
+	return strings.Join(r.indices, ",")
+}
+
+// retentionSearch returns the search for the minimum of field in every index
+// without any hits
+func retentionSearch(field string) retentionSearchRequest {
+	var s retentionSearchRequest
+	s.Aggs.Indices.Terms.Field = "_index"
+	s.Aggs.Indices.Terms.Size = retentionMaxIndices
+	s.Aggs.Indices.Aggs.Oldest.Min.Field = field
+	return s
+}
+
+func (r *Retention) fetchAndDecodeOldestDocuments() (retentionSearchResponse, error) {
+	var rsr retentionSearchResponse
+
+	u := *r.url
+	u.Path = path.Join(u.Path, r.indexPath(), "_search")
+	q := u.Query()
+	q.Set("ignore_unavailable", "true")
+	q.Set("allow_no_indices", "true")
+	// searches without hits are cached by the shards until the next refresh
+	q.Set("request_cache", "true")
+	u.RawQuery = q.Encode()
+
+	body, err := json.Marshal(retentionSearch(r.timestampField))
+	if err != nil {
+		return rsr, err
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return rsr, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return rsr, fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(r.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return rsr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&rsr); err != nil {
+		r.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return rsr, err
+	}
+	return rsr, nil
+}
+
+// fetchOldestDocuments returns the timestamp of the oldest document of every
+// index in seconds. Indices without the timestamp field are left out.
+func (r *Retention) fetchOldestDocuments() (map[string]float64, error) {
+	rsr, err := r.fetchAndDecodeOldestDocuments()
+	if err != nil {
+		return nil, err
+	}
+	oldest := make(map[string]float64)
+	for _, bucket := range rsr.Aggregations.Indices.Buckets {
+		if bucket.Oldest.Value == nil {
+			continue
+		}
+		// dates are aggregated in milliseconds since the epoch
+		oldest[bucket.Key] = *bucket.Oldest.Value / 1000
+	}
+	return oldest, nil
+}
+
+// Collect gets Retention metric values
+func (r *Retention) Collect(ch chan<- prometheus.Metric) {
+	r.totalScrapes.Inc()
+	defer func() {
+		ch <- r.up
+		ch <- r.totalScrapes
+		ch <- r.jsonParseFailures
+	}()
+
+	key := fmt.Sprintf("%s/%s/%s", r.url.String(), r.indexPath(), r.timestampField)
+	oldest, err := r.cache.get(key, r.interval, r.fetchOldestDocuments)
+	if err != nil {
+		r.up.Set(0)
+		_ = level.Warn(r.logger).Log(
+			"msg", "failed to fetch and decode oldest documents",
+			"err", err,
+		)
+		return
+	}
+	r.up.Set(1)
+
+	for index, timestamp := range oldest {
+		ch <- prometheus.MustNewConstMetric(
+			r.oldestDocument,
+			prometheus.GaugeValue,
+			timestamp,
+			index,
+		)
+	}
+}
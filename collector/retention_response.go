@@ -0,0 +1,40 @@
+package collector
+
+// retentionSearchRequest is the search for the oldest document of every index
+type retentionSearchRequest struct {
+	Size int `json:"size"`
+	Aggs struct {
+		Indices struct {
+			Terms struct {
+				Field string `json:"field"`
+				Size  int    `json:"size"`
+			} `json:"terms"`
+			Aggs struct {
+				Oldest struct {
+					Min struct {
+						Field string `json:"field"`
+					} `json:"min"`
+				} `json:"oldest"`
+			} `json:"aggs"`
+		} `json:"indices"`
+	} `json:"aggs"`
+}
+
+// retentionSearchResponse is a representation of the oldest document of every index
+type retentionSearchResponse struct {
+	Aggregations struct {
+		Indices struct {
+			Buckets []retentionIndexBucketResponse `json:"buckets"`
+		} `json:"indices"`
+	} `json:"aggregations"`
+}
+
+// retentionIndexBucketResponse defines the oldest document of an index, the
+// value is null if no document of the index has the timestamp field
+type retentionIndexBucketResponse struct {
+	Key      string `json:"key"`
+	DocCount int64  `json:"doc_count"`
+	Oldest   struct {
+		Value *float64 `json:"value"`
+	} `json:"oldest"`
+}
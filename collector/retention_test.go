@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRetention(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.10.0
+	//  curl -XPOST http://localhost:9200/logs-1/_doc --header "Content-Type: application/json" -d '{"@timestamp":"2020-11-01T00:00:00Z"}'
+	//  curl -XPOST http://localhost:9200/logs-2/_doc --header "Content-Type: application/json" -d '{"@timestamp":"2020-11-20T12:00:00Z"}'
+	//  curl -XPOST http://localhost:9200/logs-3/_doc --header "Content-Type: application/json" -d '{"message":"no timestamp"}'
+	//  curl -XPOST "http://localhost:9200/logs-*/_search?ignore_unavailable=true&allow_no_indices=true&request_cache=true" --header "Content-Type: application/json" -d @search.json
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/logs-*/_search" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		requests++
+		var search retentionSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if search.Aggs.Indices.Aggs.Oldest.Min.Field != "@timestamp" {
+			t.Errorf("Wrong timestamp field: %s", search.Aggs.Indices.Aggs.Oldest.Min.Field)
+		}
+		fmt.Fprintln(w, `{"took":3,"timed_out":false,"_shards":{"total":3,"successful":3,"skipped":0,"failed":0},"hits":{"total":{"value":3,"relation":"eq"},"max_score":null,"hits":[]},"aggregations":{"indices":{"doc_count_error_upper_bound":0,"sum_other_doc_count":0,"buckets":[{"key":"logs-1","doc_count":1,"oldest":{"value":1.6041888E12,"value_as_string":"2020-11-01T00:00:00.000Z"}},{"key":"logs-2","doc_count":1,"oldest":{"value":1.6058736E12,"value_as_string":"2020-11-20T12:00:00.000Z"}},{"key":"logs-3","doc_count":1,"oldest":{"value":null}}]}}}`)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	expected := `
+# HELP elasticsearch_index_oldest_document_timestamp_seconds Timestamp of the oldest document of the index by the configured timestamp field, omitted for indices without it
+# TYPE elasticsearch_index_oldest_document_timestamp_seconds gauge
+elasticsearch_index_oldest_document_timestamp_seconds{index="logs-1"} 1.6041888e+09
+elasticsearch_index_oldest_document_timestamp_seconds{index="logs-2"} 1.6058736e+09
+# HELP elasticsearch_retention_up Was the last scrape of the ElasticSearch oldest documents successful.
+# TYPE elasticsearch_retention_up gauge
+elasticsearch_retention_up 1
+`
+	cache := newRetentionCache()
+	for i := 0; i < 2; i++ {
+		r := NewRetention(log.NewNopLogger(), http.DefaultClient, u, []string{"logs-*"}, "@timestamp", time.Hour)
+		r.cache = cache
+		if err := testutil.CollectAndCompare(r, strings.NewReader(expected),
+			"elasticsearch_index_oldest_document_timestamp_seconds", "elasticsearch_retention_up"); err != nil {
+			t.Errorf("Unexpected retention metrics: %s", err)
+		}
+	}
+	// the second scrape is answered from the cache
+	if requests != 1 {
+		t.Errorf("Wrong number of searches: %d", requests)
+	}
+}
+
+func TestRetentionCache(t *testing.T) {
+	c := newRetentionCache()
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var searches int
+	slow := func() (map[string]float64, error) {
+		mu.Lock()
+		searches++
+		mu.Unlock()
+		close(started)
+		<-release
+		return map[string]float64{"logs-1": 1}, nil
+	}
+
+	// concurrent scrapes of a key share one search
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.get("slow", time.Hour, slow); err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		}()
+	}
+
+	// other keys aren't blocked by the running search
+	<-started
+	failed := errors.New("search timed out")
+	var failures int
+	fail := func() (map[string]float64, error) {
+		failures++
+		return nil, failed
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.get("failing", time.Hour, fail); err != failed {
+			t.Errorf("Expected the search error, got %v", err)
+		}
+	}
+	// a failed search isn't retried before the interval
+	if failures != 1 {
+		t.Errorf("Wrong number of failed searches: %d", failures)
+	}
+
+	close(release)
+	wg.Wait()
+	if searches != 1 {
+		t.Errorf("Wrong number of concurrent searches: %d", searches)
+	}
+}
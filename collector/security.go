@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Security information struct
+type Security struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	disableUnavailable bool
+
+	up                              prometheus.Gauge
+	securityEnabled                 prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	supported *prometheus.Desc
+}
+
+// NewSecurity defines Security Prometheus metrics. If disableUnavailable is
+// true, the collector is reported as down if the cluster doesn't provide X-Pack.
+func NewSecurity(logger log.Logger, client *http.Client, url *url.URL, disableUnavailable bool) *Security {
+	return &Security{
+		logger: logger,
+		client: client,
+		url:    url,
+
+		disableUnavailable: disableUnavailable,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "security_stats", "up"),
+			Help: "Was the last scrape of the ElasticSearch X-Pack info endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "security_stats", "total_scrapes"),
+			Help: "Current total ElasticSearch X-Pack info scrapes.",
+		}),
+		securityEnabled: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "security", "enabled"),
+			Help: "Whether security is enabled and available with the current license",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "security_stats", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		supported: newSupportedDesc("security"),
+	}
+}
+
+// Describe add Security metrics descriptions
+func (s *Security) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.supported
+	ch <- s.up.Desc()
+	ch <- s.totalScrapes.Desc()
+	ch <- s.securityEnabled.Desc()
+	ch <- s.jsonParseFailures.Desc()
+}
+
+func (s *Security) fetchAndDecodeXPackInfo() (xpackInfoResponse, error) {
+	var xir xpackInfoResponse
+
+	u := *s.url
+	u.Path = path.Join(u.Path, "/_xpack")
+	err := fetchOptional(s.client, &u, &xir)
+	if errors.Is(err, errJSONParse) {
+		s.jsonParseFailures.Inc()
+	}
+	return xir, err
+}
+
+// securityEnabled returns whether security is enabled in the settings and is
+// usable with the current license. Before 6.8 the basic license doesn't include
+// security even though it is enabled by default.
+func securityEnabled(xir xpackInfoResponse) bool {
+	return xir.Features.Security.Available && xir.Features.Security.Enabled
+}
+
+// Collect gets Security metric values
+func (s *Security) Collect(ch chan<- prometheus.Metric) {
+	s.totalScrapes.Inc()
+	defer func() {
+		ch <- s.up
+		ch <- s.totalScrapes
+		ch <- s.jsonParseFailures
+		ch <- s.securityEnabled
+	}()
+
+	xir, err := s.fetchAndDecodeXPackInfo()
+	collectSupported(ch, s.supported, err)
+	if err == errFeatureUnavailable && !s.disableUnavailable {
+		// the OSS distribution and releases without X-Pack don't know the
+		// endpoint, so there's no security feature at all
+		err = nil
+	}
+	if err == errFeatureUnavailable {
+		s.securityEnabled.Set(0)
+		s.up.Set(0)
+		_ = level.Debug(s.logger).Log(
+			"msg", "X-Pack info not available",
+		)
+		return
+	}
+	if err != nil {
+		s.securityEnabled.Set(0)
+		s.up.Set(0)
+		_ = level.Warn(s.logger).Log(
+			"msg", "failed to fetch and decode X-Pack info",
+			"err", err,
+		)
+		return
+	}
+	s.up.Set(1)
+
+	if securityEnabled(xir) {
+		s.securityEnabled.Set(1)
+	} else {
+		s.securityEnabled.Set(0)
+	}
+}
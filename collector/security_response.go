@@ -0,0 +1,18 @@
+package collector
+
+// xpackInfoResponse is a representation of the Elasticsearch X-Pack info API
+type xpackInfoResponse struct {
+	Features xpackInfoFeaturesResponse `json:"features"`
+}
+
+// xpackInfoFeaturesResponse defines the X-Pack features relevant to the exporter
+type xpackInfoFeaturesResponse struct {
+	Security xpackInfoFeatureResponse `json:"security"`
+}
+
+// xpackInfoFeatureResponse defines whether an X-Pack feature is available with
+// the current license and whether it is enabled in the settings
+type xpackInfoFeatureResponse struct {
+	Available bool `json:"available"`
+	Enabled   bool `json:"enabled"`
+}
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// shardStates are the values of the state label of the shards by state, the
+// lower case states of the cat shards API
+var shardStates = []string{"started", "relocating", "initializing", "unassigned"}
+
+// Shards information struct
+type Shards struct {
+	logger    log.Logger
+	client    *http.Client
+	url       *url.URL
+	aggregate bool
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	nodeShards    *prometheus.Desc
+	shardsByState *prometheus.Desc
+}
+
+// NewShards defines Shards Prometheus metrics. If aggregate is true, the shards
+// of every index are additionally counted by state, as a replacement for the
+// per shard index stats.
+func NewShards(logger log.Logger, client *http.Client, url *url.URL, aggregate bool) *Shards {
+	return &Shards{
+		logger:    logger,
+		client:    client,
+		url:       url,
+		aggregate: aggregate,
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "shards_stats", "up"),
+			Help: "Was the last scrape of the ElasticSearch cat shards endpoint successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "shards_stats", "total_scrapes"),
+			Help: "Current total ElasticSearch cat shards scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "shards_stats", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+
+		nodeShards: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "node", "shards_count"),
+			"Number of shards allocated to the node",
+			[]string{"node"}, nil,
+		),
+		shardsByState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index", "shards_by_state"),
+			"Number of primary and replica shards of the index by state (started, relocating, initializing or unassigned)",
+			[]string{"index", "state"}, nil,
+		),
+	}
+}
+
+// Describe add Shards metrics descriptions
+func (s *Shards) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.nodeShards
+	if s.aggregate {
+		ch <- s.shardsByState
+	}
+	ch <- s.up.Desc()
+	ch <- s.totalScrapes.Desc()
+	ch <- s.jsonParseFailures.Desc()
+}
+
+func (s *Shards) fetchAndDecodeCatShards() (catShardsResponse, error) {
+	var csr catShardsResponse
+
+	u := *s.url
+	u.Path = path.Join(u.Path, "/_cat/shards")
+	q := u.Query()
+	q.Set("format", "json")
+	q.Set("h", "index,shard,prirep,state,node")
+	u.RawQuery = q.Encode()
+
+	res, err := s.client.Get(u.String())
+	if err != nil {
+		return csr, fmt.Errorf("failed to get cat shards from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(s.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return csr, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&csr); err != nil {
+		s.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return csr, err
+	}
+	return csr, nil
+}
+
+// countShardsByNode returns the number of shards per node. Unassigned shards are
+// skipped and relocating shards are counted on their source node, like
+// cluster.max_shards_per_node does until the relocation has finished.
+func countShardsByNode(csr catShardsResponse) map[string]int {
+	counts := make(map[string]int)
+	for _, shard := range csr {
+		fields := strings.Fields(shard.Node)
+		if len(fields) == 0 {
+			continue
+		}
+		counts[fields[0]]++
+	}
+	return counts
+}
+
+// countShardsByState returns the number of shards of every index per state
+func countShardsByState(csr catShardsResponse) map[string]map[string]int {
+	counts := make(map[string]map[string]int)
+	for _, shard := range csr {
+		if counts[shard.Index] == nil {
+			counts[shard.Index] = make(map[string]int)
+		}
+		counts[shard.Index][strings.ToLower(shard.State)]++
+	}
+	return counts
+}
+
+// Collect gets Shards metric values
+func (s *Shards) Collect(ch chan<- prometheus.Metric) {
+	s.totalScrapes.Inc()
+	defer func() {
+		ch <- s.up
+		ch <- s.totalScrapes
+		ch <- s.jsonParseFailures
+	}()
+
+	csr, err := s.fetchAndDecodeCatShards()
+	if err != nil {
+		s.up.Set(0)
+		_ = level.Warn(s.logger).Log(
+			"msg", "failed to fetch and decode cat shards",
+			"err", err,
+		)
+		return
+	}
+	s.up.Set(1)
+
+	for node, count := range countShardsByNode(csr) {
+		ch <- prometheus.MustNewConstMetric(
+			s.nodeShards,
+			prometheus.GaugeValue,
+			float64(count),
+			node,
+		)
+	}
+
+	if !s.aggregate {
+		return
+	}
+	for index, states := range countShardsByState(csr) {
+		for _, state := range shardStates {
+			ch <- prometheus.MustNewConstMetric(
+				s.shardsByState,
+				prometheus.GaugeValue,
+				float64(states[state]),
+				index, state,
+			)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package collector
+
+// catShardsResponse is a representation of the _cat/shards API
+type catShardsResponse []catShardResponse
+
+// catShardResponse defines a single shard copy. Unassigned shards have no node,
+// relocating shards list the source and target node, e.g. "es01 -> 172.17.0.3 Nfj8yC7yTQOS0qIH8xVYkw es02".
+type catShardResponse struct {
+	Index  string `json:"index"`
+	Shard  string `json:"shard"`
+	Prirep string `json:"prirep"`
+	State  string `json:"state"`
+	Node   string `json:"node"`
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestShardsByNode(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl "http://localhost:9200/_cat/shards?format=json&h=index,shard,prirep,state,node"
+	tcs := map[string]string{
+		"7.3.0": `[{"index":"foo_1","shard":"0","prirep":"p","state":"STARTED","node":"es01"},{"index":"foo_1","shard":"0","prirep":"r","state":"STARTED","node":"es02"},{"index":"foo_1","shard":"1","prirep":"p","state":"RELOCATING","node":"es02 -> 172.17.0.4 Nfj8yC7yTQOS0qIH8xVYkw es03"},{"index":"foo_1","shard":"1","prirep":"r","state":"STARTED","node":"es01"},{"index":"foo_2","shard":"0","prirep":"p","state":"STARTED","node":"es03"},{"index":"foo_2","shard":"0","prirep":"r","state":"UNASSIGNED","node":null}]`,
+	}
+	expected := `
+# HELP elasticsearch_node_shards_count Number of shards allocated to the node
+# TYPE elasticsearch_node_shards_count gauge
+elasticsearch_node_shards_count{node="es01"} 2
+elasticsearch_node_shards_count{node="es02"} 2
+elasticsearch_node_shards_count{node="es03"} 1
+`
+	for ver, out := range tcs {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		s := NewShards(log.NewNopLogger(), http.DefaultClient, u, false)
+		csr, err := s.fetchAndDecodeCatShards()
+		if err != nil {
+			t.Fatalf("Failed to fetch or decode cat shards: %s", err)
+		}
+		var sum int
+		for _, count := range countShardsByNode(csr) {
+			sum += count
+		}
+		// all shards but the unassigned one
+		if sum != len(csr)-1 {
+			t.Errorf("[%s] Per node shard counts should sum up to the assigned shards, got %d", ver, sum)
+		}
+		if err := testutil.CollectAndCompare(s, strings.NewReader(expected), "elasticsearch_node_shards_count"); err != nil {
+			t.Errorf("[%s] Unexpected per node shard count: %s", ver, err)
+		}
+	}
+}
+
+func TestShardsAggregate(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:7.3.0
+	//  curl "http://localhost:9200/_cat/shards?format=json&h=index,shard,prirep,state,node"
+	out := `[{"index":"foo_1","shard":"0","prirep":"p","state":"STARTED","node":"es01"},{"index":"foo_1","shard":"0","prirep":"r","state":"STARTED","node":"es02"},{"index":"foo_1","shard":"1","prirep":"p","state":"RELOCATING","node":"es02 -> 172.17.0.4 Nfj8yC7yTQOS0qIH8xVYkw es03"},{"index":"foo_1","shard":"1","prirep":"r","state":"STARTED","node":"es01"},{"index":"foo_2","shard":"0","prirep":"p","state":"STARTED","node":"es03"},{"index":"foo_2","shard":"0","prirep":"r","state":"UNASSIGNED","node":null}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	nodeShards := `
+# HELP elasticsearch_node_shards_count Number of shards allocated to the node
+# TYPE elasticsearch_node_shards_count gauge
+elasticsearch_node_shards_count{node="es01"} 2
+elasticsearch_node_shards_count{node="es02"} 2
+elasticsearch_node_shards_count{node="es03"} 1
+`
+	shardsByState := `
+# HELP elasticsearch_index_shards_by_state Number of primary and replica shards of the index by state (started, relocating, initializing or unassigned)
+# TYPE elasticsearch_index_shards_by_state gauge
+elasticsearch_index_shards_by_state{index="foo_1",state="initializing"} 0
+elasticsearch_index_shards_by_state{index="foo_1",state="relocating"} 1
+elasticsearch_index_shards_by_state{index="foo_1",state="started"} 3
+elasticsearch_index_shards_by_state{index="foo_1",state="unassigned"} 0
+elasticsearch_index_shards_by_state{index="foo_2",state="initializing"} 0
+elasticsearch_index_shards_by_state{index="foo_2",state="relocating"} 0
+elasticsearch_index_shards_by_state{index="foo_2",state="started"} 1
+elasticsearch_index_shards_by_state{index="foo_2",state="unassigned"} 1
+`
+	// the per node shard counts are the same, only the aggregated output
+	// has the shards by state
+	tcs := map[string]struct {
+		aggregate bool
+		expected  string
+	}{
+		"full":       {false, nodeShards},
+		"aggregated": {true, nodeShards + shardsByState},
+	}
+	for name, tc := range tcs {
+		s := NewShards(log.NewNopLogger(), http.DefaultClient, u, tc.aggregate)
+		if err := testutil.CollectAndCompare(s, strings.NewReader(tc.expected),
+			"elasticsearch_node_shards_count", "elasticsearch_index_shards_by_state"); err != nil {
+			t.Errorf("[%s] Unexpected shard metrics: %s", name, err)
+		}
+	}
+}
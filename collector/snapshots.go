@@ -44,10 +44,13 @@ type Snapshots struct {
 	url    *url.URL
 
 	up                              prometheus.Gauge
+	restoresInProgress              prometheus.Gauge
 	totalScrapes, jsonParseFailures prometheus.Counter
 
 	snapshotMetrics   []*snapshotMetric
 	repositoryMetrics []*repositoryMetric
+	latestSuccessful  *prometheus.Desc
+	repositoryInfo    *prometheus.Desc
 }
 
 // NewSnapshots defines Snapshots Prometheus metrics
@@ -69,6 +72,10 @@ func NewSnapshots(logger log.Logger, client *http.Client, url *url.URL) *Snapsho
 			Name: prometheus.BuildFQName(namespace, "snapshot_stats", "json_parse_failures"),
 			Help: "Number of errors while parsing JSON.",
 		}),
+		restoresInProgress: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "snapshot", "restores_in_progress_total"),
+			Help: "Number of indices currently being restored from a snapshot",
+		}),
 		snapshotMetrics: []*snapshotMetric{
 			{
 				Type: prometheus.GaugeValue,
@@ -202,6 +209,16 @@ func NewSnapshots(logger log.Logger, client *http.Client, url *url.URL) *Snapsho
 				Labels: defaultSnapshotRepositoryLabelValues,
 			},
 		},
+		latestSuccessful: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshot", "latest_successful_timestamp_seconds"),
+			"Timestamp of the end of the latest SUCCESS snapshot, omitted if there is none",
+			defaultSnapshotRepositoryLabels, nil,
+		),
+		repositoryInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshot", "repository_info"),
+			"Constant metric with the type and the storage location settings of a snapshot repository as labels",
+			[]string{"repository", "type", "bucket", "region", "base_path"}, nil,
+		),
 	}
 }
 
@@ -210,6 +227,12 @@ func (s *Snapshots) Describe(ch chan<- *prometheus.Desc) {
 	for _, metric := range s.snapshotMetrics {
 		ch <- metric.Desc
 	}
+	for _, metric := range s.repositoryMetrics {
+		ch <- metric.Desc
+	}
+	ch <- s.latestSuccessful
+	ch <- s.repositoryInfo
+	ch <- s.restoresInProgress.Desc()
 	ch <- s.up.Desc()
 	ch <- s.totalScrapes.Desc()
 	ch <- s.jsonParseFailures.Desc()
@@ -238,21 +261,22 @@ func (s *Snapshots) getAndParseURL(u *url.URL, data interface{}) error {
 
 	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
 		s.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
 		return err
 	}
 	return nil
 }
 
-func (s *Snapshots) fetchAndDecodeSnapshotsStats() (map[string]SnapshotStatsResponse, error) {
-	mssr := make(map[string]SnapshotStatsResponse)
-
+func (s *Snapshots) fetchAndDecodeRepositories() (SnapshotRepositoriesResponse, error) {
 	u := *s.url
 	u.Path = path.Join(u.Path, "/_snapshot")
 	var srr SnapshotRepositoriesResponse
 	err := s.getAndParseURL(&u, &srr)
-	if err != nil {
-		return nil, err
-	}
+	return srr, err
+}
+
+func (s *Snapshots) fetchAndDecodeSnapshotsStats(srr SnapshotRepositoriesResponse) map[string]SnapshotStatsResponse {
+	mssr := make(map[string]SnapshotStatsResponse)
 	for repository := range srr {
 		u := *s.url
 		u.Path = path.Join(u.Path, "/_snapshot", repository, "/_all")
@@ -263,8 +287,33 @@ func (s *Snapshots) fetchAndDecodeSnapshotsStats() (map[string]SnapshotStatsResp
 		}
 		mssr[repository] = ssr
 	}
+	return mssr
+}
 
-	return mssr, nil
+func (s *Snapshots) fetchAndDecodeRecovery() (recoveryResponse, error) {
+	u := *s.url
+	u.Path = path.Join(u.Path, "/_recovery")
+	q := u.Query()
+	q.Set("active_only", "true")
+	u.RawQuery = q.Encode()
+	var rr recoveryResponse
+	err := s.getAndParseURL(&u, &rr)
+	return rr, err
+}
+
+// countSnapshotRestores returns the number of indices with at least one
+// shard recovering from a snapshot
+func countSnapshotRestores(rr recoveryResponse) int {
+	var c int
+	for _, index := range rr {
+		for _, shard := range index.Shards {
+			if shard.Type == "SNAPSHOT" {
+				c++
+				break
+			}
+		}
+	}
+	return c
 }
 
 // Collect gets Snapshots metric values
@@ -276,18 +325,42 @@ func (s *Snapshots) Collect(ch chan<- prometheus.Metric) {
 		ch <- s.jsonParseFailures
 	}()
 
-	// indices
-	snapshotsStatsResp, err := s.fetchAndDecodeSnapshotsStats()
+	srr, err := s.fetchAndDecodeRepositories()
 	if err != nil {
 		s.up.Set(0)
 		_ = level.Warn(s.logger).Log(
-			"msg", "failed to fetch and decode snapshot stats",
+			"msg", "failed to fetch and decode snapshot repositories",
 			"err", err,
 		)
 		return
 	}
 	s.up.Set(1)
 
+	// only the storage location settings are exported, never credentials
+	// like the access_key and secret_key of older S3 repositories
+	for repositoryName, repository := range srr {
+		ch <- prometheus.MustNewConstMetric(
+			s.repositoryInfo,
+			prometheus.GaugeValue,
+			1,
+			repositoryName, repository.Type, repository.Settings["bucket"], repository.Settings["region"], repository.Settings["base_path"],
+		)
+	}
+
+	snapshotsStatsResp := s.fetchAndDecodeSnapshotsStats(srr)
+
+	// the restores are omitted if the recovery data is unavailable
+	rr, err := s.fetchAndDecodeRecovery()
+	if err != nil {
+		_ = level.Warn(s.logger).Log(
+			"msg", "failed to fetch and decode recovery",
+			"err", err,
+		)
+	} else {
+		s.restoresInProgress.Set(float64(countSnapshotRestores(rr)))
+		ch <- s.restoresInProgress
+	}
+
 	// Snapshots stats
 	for repositoryName, snapshotStats := range snapshotsStatsResp {
 		for _, metric := range s.repositoryMetrics {
@@ -298,6 +371,14 @@ func (s *Snapshots) Collect(ch chan<- prometheus.Metric) {
 				metric.Labels(repositoryName)...,
 			)
 		}
+		if latest, ok := latestSuccessfulSnapshot(snapshotStats); ok {
+			ch <- prometheus.MustNewConstMetric(
+				s.latestSuccessful,
+				prometheus.GaugeValue,
+				float64(latest.EndTimeInMillis)/1000,
+				defaultSnapshotRepositoryLabelValues(repositoryName)...,
+			)
+		}
 		if len(snapshotStats.Snapshots) == 0 {
 			continue
 		}
@@ -313,3 +394,19 @@ func (s *Snapshots) Collect(ch chan<- prometheus.Metric) {
 		}
 	}
 }
+
+// latestSuccessfulSnapshot returns the snapshot with state SUCCESS which ended last
+func latestSuccessfulSnapshot(snapshotsStats SnapshotStatsResponse) (SnapshotStatDataResponse, bool) {
+	var latest SnapshotStatDataResponse
+	var found bool
+	for _, snap := range snapshotsStats.Snapshots {
+		if snap.State != "SUCCESS" {
+			continue
+		}
+		if !found || snap.EndTimeInMillis > latest.EndTimeInMillis {
+			latest = snap
+			found = true
+		}
+	}
+	return latest, found
+}
@@ -33,3 +33,19 @@ type SnapshotRepositoriesResponse map[string]struct {
 	Type     string            `json:"type"`
 	Settings map[string]string `json:"settings"`
 }
+
+// recoveryResponse is a representation of the active shard recoveries by index
+type recoveryResponse map[string]recoveryIndexResponse
+
+// recoveryIndexResponse defines the shard recoveries of an index
+type recoveryIndexResponse struct {
+	Shards []recoveryShardResponse `json:"shards"`
+}
+
+// recoveryShardResponse defines the type and stage of a shard recovery. Restores
+// from a snapshot have the type SNAPSHOT.
+type recoveryShardResponse struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Stage string `json:"stage"`
+}
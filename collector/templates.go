@@ -0,0 +1,243 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/blang/semver"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// composableTemplatesVersion is the first version with composable index
+// templates and component templates
+var composableTemplatesVersion = semver.MustParse("7.8.0")
+
+// Templates information struct
+type Templates struct {
+	logger log.Logger
+	client *http.Client
+	url    *url.URL
+
+	clusterInfoCh   chan *clusterinfo.Response
+	mu              sync.Mutex
+	lastClusterInfo *clusterinfo.Response
+
+	up                              prometheus.Gauge
+	totalScrapes, jsonParseFailures prometheus.Counter
+
+	indexTemplateVersion     *prometheus.Desc
+	componentTemplateVersion *prometheus.Desc
+	templatesCount           *prometheus.Desc
+}
+
+// NewTemplates defines Templates Prometheus metrics. The templates API is
+// chosen by the version of the cluster, which is received as a clusterinfo
+// consumer. Until it's known the legacy templates are read.
+func NewTemplates(logger log.Logger, client *http.Client, url *url.URL) *Templates {
+	templates := &Templates{
+		logger:        logger,
+		client:        client,
+		url:           url,
+		clusterInfoCh: make(chan *clusterinfo.Response),
+
+		up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, "templates", "up"),
+			Help: "Was the last scrape of the ElasticSearch templates endpoints successful.",
+		}),
+		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "templates", "total_scrapes"),
+			Help: "Current total ElasticSearch templates scrapes.",
+		}),
+		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, "templates", "json_parse_failures"),
+			Help: "Number of errors while parsing JSON.",
+		}),
+		indexTemplateVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "index_template", "version"),
+			"Version of the index template, only exported if set",
+			[]string{"name"}, nil,
+		),
+		componentTemplateVersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "component_template", "version"),
+			"Version of the component template, only exported if set",
+			[]string{"name"}, nil,
+		),
+		templatesCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "templates", "count"),
+			"Number of templates by type (index or component)",
+			[]string{"type"}, nil,
+		),
+	}
+
+	// start go routine to fetch clusterinfo updates and save them to lastClusterInfo,
+	// it ends once the retriever of the scrape closes the channel
+	go func() {
+		for ci := range templates.clusterInfoCh {
+			if ci != nil {
+				templates.mu.Lock()
+				templates.lastClusterInfo = ci
+				templates.mu.Unlock()
+			}
+		}
+	}()
+	return templates
+}
+
+// ClusterLabelUpdates returns a pointer to a channel to receive cluster info updates. It implements the
+// (not exported) clusterinfo.consumer interface
+func (t *Templates) ClusterLabelUpdates() *chan *clusterinfo.Response {
+	return &t.clusterInfoCh
+}
+
+// String implements the stringer interface. It is part of the clusterinfo.consumer interface
+func (t *Templates) String() string {
+	return namespace + "templates"
+}
+
+// Describe add Templates metrics descriptions
+func (t *Templates) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.indexTemplateVersion
+	ch <- t.componentTemplateVersion
+	ch <- t.templatesCount
+	ch <- t.up.Desc()
+	ch <- t.totalScrapes.Desc()
+	ch <- t.jsonParseFailures.Desc()
+}
+
+// composableTemplates returns whether the cluster supports composable index
+// templates, which replace the legacy templates
+func (t *Templates) composableTemplates() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastClusterInfo != nil && t.lastClusterInfo.Version.Number.GTE(composableTemplatesVersion)
+}
+
+func (t *Templates) getAndParseURL(u *url.URL, data interface{}) error {
+	res, err := t.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to get from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = level.Warn(t.logger).Log(
+				"msg", "failed to close http.Client",
+				"err", err,
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		t.jsonParseFailures.Inc()
+		countJSONParseError(u.Path)
+		return err
+	}
+	return nil
+}
+
+func (t *Templates) fetchAndDecodeIndexTemplates() (indexTemplatesResponse, error) {
+	u := *t.url
+	u.Path = path.Join(u.Path, "/_index_template")
+	var itr indexTemplatesResponse
+	err := t.getAndParseURL(&u, &itr)
+	return itr, err
+}
+
+func (t *Templates) fetchAndDecodeComponentTemplates() (componentTemplatesResponse, error) {
+	u := *t.url
+	u.Path = path.Join(u.Path, "/_component_template")
+	var ctr componentTemplatesResponse
+	err := t.getAndParseURL(&u, &ctr)
+	return ctr, err
+}
+
+func (t *Templates) fetchAndDecodeLegacyTemplates() (legacyTemplatesResponse, error) {
+	u := *t.url
+	u.Path = path.Join(u.Path, "/_template")
+	var ltr legacyTemplatesResponse
+	err := t.getAndParseURL(&u, &ltr)
+	return ltr, err
+}
+
+// templateVersions returns the versions of the index and component templates
+// keyed by their name. Templates without a version are included with nil.
+func (t *Templates) templateVersions() (index, component map[string]*int64, err error) {
+	index = make(map[string]*int64)
+	component = make(map[string]*int64)
+
+	if !t.composableTemplates() {
+		ltr, err := t.fetchAndDecodeLegacyTemplates()
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, template := range ltr {
+			index[name] = template.Version
+		}
+		return index, component, nil
+	}
+
+	itr, err := t.fetchAndDecodeIndexTemplates()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, template := range itr.IndexTemplates {
+		index[template.Name] = template.IndexTemplate.Version
+	}
+	ctr, err := t.fetchAndDecodeComponentTemplates()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, template := range ctr.ComponentTemplates {
+		component[template.Name] = template.ComponentTemplate.Version
+	}
+	return index, component, nil
+}
+
+// Collect gets Templates metric values
+func (t *Templates) Collect(ch chan<- prometheus.Metric) {
+	t.totalScrapes.Inc()
+	defer func() {
+		ch <- t.up
+		ch <- t.totalScrapes
+		ch <- t.jsonParseFailures
+	}()
+
+	index, component, err := t.templateVersions()
+	if err != nil {
+		t.up.Set(0)
+		_ = level.Warn(t.logger).Log(
+			"msg", "failed to fetch and decode templates",
+			"err", err,
+		)
+		return
+	}
+	t.up.Set(1)
+
+	t.collectVersions(ch, t.indexTemplateVersion, index)
+	t.collectVersions(ch, t.componentTemplateVersion, component)
+	ch <- prometheus.MustNewConstMetric(t.templatesCount, prometheus.GaugeValue, float64(len(index)), "index")
+	ch <- prometheus.MustNewConstMetric(t.templatesCount, prometheus.GaugeValue, float64(len(component)), "component")
+}
+
+// collectVersions exports the versions of all templates which have one
+func (t *Templates) collectVersions(ch chan<- prometheus.Metric, desc *prometheus.Desc, versions map[string]*int64) {
+	for name, version := range versions {
+		if version == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(*version), name)
+	}
+}
@@ -0,0 +1,34 @@
+package collector
+
+// indexTemplatesResponse is a representation of the composable index templates
+// of Elasticsearch 7.8 and later
+type indexTemplatesResponse struct {
+	IndexTemplates []indexTemplateResponse `json:"index_templates"`
+}
+
+// indexTemplateResponse defines a single composable index template
+type indexTemplateResponse struct {
+	Name          string                  `json:"name"`
+	IndexTemplate templateVersionResponse `json:"index_template"`
+}
+
+// componentTemplatesResponse is a representation of the component templates
+// of Elasticsearch 7.8 and later
+type componentTemplatesResponse struct {
+	ComponentTemplates []componentTemplateResponse `json:"component_templates"`
+}
+
+// componentTemplateResponse defines a single component template
+type componentTemplateResponse struct {
+	Name              string                  `json:"name"`
+	ComponentTemplate templateVersionResponse `json:"component_template"`
+}
+
+// legacyTemplatesResponse is a representation of the legacy index templates
+// keyed by their name
+type legacyTemplatesResponse map[string]templateVersionResponse
+
+// templateVersionResponse defines the optional version of a template
+type templateVersionResponse struct {
+	Version *int64 `json:"version"`
+}
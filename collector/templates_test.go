@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/go-kit/kit/log"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTemplates(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl -XPUT http://localhost:9200/_template/logs --header "Content-Type: application/json" -d '{"index_patterns":["logs-*"],"version":3}'
+	//  curl -XPUT http://localhost:9200/_template/metrics --header "Content-Type: application/json" -d '{"index_patterns":["metrics-*"]}'
+	//  curl http://localhost:9200/_template
+	// and for 7.8 and later:
+	//  curl -XPUT http://localhost:9200/_component_template/mappings --header "Content-Type: application/json" -d '{"template":{},"version":7}'
+	//  curl -XPUT http://localhost:9200/_index_template/logs --header "Content-Type: application/json" -d '{"index_patterns":["logs-*"],"composed_of":["mappings"],"version":3}'
+	//  curl -XPUT http://localhost:9200/_index_template/metrics --header "Content-Type: application/json" -d '{"index_patterns":["metrics-*"]}'
+	//  curl http://localhost:9200/_index_template
+	//  curl http://localhost:9200/_component_template
+	responses := map[string]string{
+		"/_template":           `{"logs":{"order":0,"version":3,"index_patterns":["logs-*"],"settings":{},"mappings":{},"aliases":{}},"metrics":{"order":0,"index_patterns":["metrics-*"],"settings":{},"mappings":{},"aliases":{}}}`,
+		"/_index_template":     `{"index_templates":[{"name":"logs","index_template":{"index_patterns":["logs-*"],"composed_of":["mappings"],"version":3}},{"name":"metrics","index_template":{"index_patterns":["metrics-*"],"composed_of":[]}}]}`,
+		"/_component_template": `{"component_templates":[{"name":"mappings","component_template":{"template":{},"version":7}}]}`,
+	}
+	tcs := map[string]struct {
+		version  string
+		expected string
+	}{
+		"legacy": {"6.8.8", `
+# HELP elasticsearch_component_template_version Version of the component template, only exported if set
+# TYPE elasticsearch_component_template_version gauge
+# HELP elasticsearch_index_template_version Version of the index template, only exported if set
+# TYPE elasticsearch_index_template_version gauge
+elasticsearch_index_template_version{name="logs"} 3
+# HELP elasticsearch_templates_count Number of templates by type (index or component)
+# TYPE elasticsearch_templates_count gauge
+elasticsearch_templates_count{type="component"} 0
+elasticsearch_templates_count{type="index"} 2
+`},
+		"composable": {"7.10.0", `
+# HELP elasticsearch_component_template_version Version of the component template, only exported if set
+# TYPE elasticsearch_component_template_version gauge
+elasticsearch_component_template_version{name="mappings"} 7
+# HELP elasticsearch_index_template_version Version of the index template, only exported if set
+# TYPE elasticsearch_index_template_version gauge
+elasticsearch_index_template_version{name="logs"} 3
+# HELP elasticsearch_templates_count Number of templates by type (index or component)
+# TYPE elasticsearch_templates_count gauge
+elasticsearch_templates_count{type="component"} 1
+elasticsearch_templates_count{type="index"} 2
+`},
+	}
+	for name, tc := range tcs {
+		var requested []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = append(requested, r.URL.Path)
+			fmt.Fprintln(w, responses[r.URL.Path])
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		c := NewTemplates(log.NewNopLogger(), http.DefaultClient, u)
+		*c.ClusterLabelUpdates() <- &clusterinfo.Response{
+			ClusterName: "elasticsearch",
+			Version:     clusterinfo.VersionInfo{Number: semver.MustParse(tc.version)},
+		}
+		// wait for the cluster info to be received
+		for i := 0; i < 100 && c.composableTemplates() != (name == "composable"); i++ {
+			time.Sleep(time.Millisecond)
+		}
+
+		if err := testutil.CollectAndCompare(c, strings.NewReader(tc.expected),
+			"elasticsearch_index_template_version", "elasticsearch_component_template_version", "elasticsearch_templates_count"); err != nil {
+			t.Errorf("[%s] Unexpected template metrics: %s", name, err)
+		}
+		if name == "legacy" && strings.Join(requested, ",") != "/_template" {
+			t.Errorf("[%s] Only the legacy templates should be requested, got %v", name, requested)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/bytesize"
+)
+
+// Values of TimeUnit
+const (
+	// TimeUnitSeconds exports time metrics in seconds
+	TimeUnitSeconds = "seconds"
+	// TimeUnitMillis exports time metrics as the raw milliseconds reported by
+	// Elasticsearch, for dashboards built against older exporter versions
+	TimeUnitMillis = "millis"
+)
+
+// TimeUnit is the unit of the time metrics which Elasticsearch reports in
+// milliseconds. It is set once on startup and applies to all collectors.
+var TimeUnit = TimeUnitSeconds
+
+// millisToTimeUnit converts a time reported by Elasticsearch in milliseconds
+// to the configured TimeUnit
+func millisToTimeUnit(millis int64) float64 {
+	if TimeUnit == TimeUnitMillis {
+		return float64(millis)
+	}
+	return float64(millis) / 1000
+}
+
+// timeUnitName replaces the seconds unit in the name of a time metric with
+// the configured TimeUnit, so the name always matches the exported values
+func timeUnitName(name string) string {
+	if TimeUnit == TimeUnitMillis {
+		return strings.Replace(name, "seconds", "millis", 1)
+	}
+	return name
+}
+
+// timeUnitHelp replaces the seconds unit in the help of a time metric with
+// the configured TimeUnit
+func timeUnitHelp(help string) string {
+	if TimeUnit == TimeUnitMillis {
+		return strings.Replace(help, "in seconds", "in milliseconds", 1)
+	}
+	return help
+}
+
+// parseMemorySize converts a memory size setting of Elasticsearch, which is
+// either a percentage of the heap (e.g. 60%) or a byte size (e.g. 2gb). A
+// percentage is returned as a ratio with isRatio set, a byte size in bytes.
+func parseMemorySize(setting string) (value float64, isRatio bool, err error) {
+	s := strings.TrimSpace(setting)
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to parse memory size %q: %s", setting, err)
+		}
+		return v / 100, true, nil
+	}
+	v, err := bytesize.Parse(s)
+	return v, false, err
+}
+
+// maxExactInt is the largest magnitude up to which float64 represents every
+// integer exactly
+const maxExactInt = 1 << 53
+
+// intToFloat64 converts a count reported by Elasticsearch to a sample value.
+// Beyond 2^53 float64 rounds to the nearest representable value, which is
+// logged as the exported value is off by the rounding.
+func intToFloat64(logger log.Logger, metric string, v int64) float64 {
+	f := float64(v)
+	if v > maxExactInt || v < -maxExactInt {
+		if exact, acc := big.NewFloat(f).Int64(); acc != big.Exact || exact != v {
+			_ = level.Warn(logger).Log(
+				"msg", "value exceeds the precision of float64, exporting the rounded value",
+				"metric", metric,
+				"value", v,
+				"exported", fmt.Sprintf("%.0f", f),
+			)
+		}
+	}
+	return f
+}
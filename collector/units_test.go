@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTimeUnit(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl -XPOST http://localhost:9200/foo_1/_bulk --data-binary @bulk.json
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.total.indexing"
+	out := `{"indices":{"foo_1":{"total":{"indexing":{"index_total":5012,"index_time_in_millis":4382,"index_current":0,"index_failed":0,"delete_total":0,"delete_time_in_millis":0,"delete_current":0,"noop_update_total":0,"is_throttled":false,"throttle_time_in_millis":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	defer func() {
+		TimeUnit = TimeUnitSeconds
+	}()
+
+	for _, tc := range []struct {
+		unit     string
+		name     string
+		expected string
+	}{
+		{TimeUnitSeconds, "elasticsearch_index_stats_indexing_index_time_seconds_total", `
+# HELP elasticsearch_index_stats_indexing_index_time_seconds_total Total indexing index time in seconds
+# TYPE elasticsearch_index_stats_indexing_index_time_seconds_total counter
+elasticsearch_index_stats_indexing_index_time_seconds_total{cluster="unknown_cluster",index="foo_1"} 4.382
+`},
+		{TimeUnitMillis, "elasticsearch_index_stats_indexing_index_time_millis_total", `
+# HELP elasticsearch_index_stats_indexing_index_time_millis_total Total indexing index time in seconds
+# TYPE elasticsearch_index_stats_indexing_index_time_millis_total counter
+elasticsearch_index_stats_indexing_index_time_millis_total{cluster="unknown_cluster",index="foo_1"} 4382
+`},
+	} {
+		// the unit applies to collectors created after setting it
+		TimeUnit = tc.unit
+		i := NewIndices(log.NewNopLogger(), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+		if err := testutil.CollectAndCompare(i, strings.NewReader(tc.expected), tc.name); err != nil {
+			t.Errorf("Unexpected time metric in %s: %s", tc.unit, err)
+		}
+	}
+}
+
+func TestDocsCountPrecision(t *testing.T) {
+	// Testcase created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION-alpine
+	//  curl "http://localhost:9200/_all/_stats?filter_path=indices.*.*.docs"
+	// with the doc counts raised beyond 2^53, which float64 can't represent exactly
+	out := `{"indices":{"foo_1":{"primaries":{"docs":{"count":9007199254740993,"deleted":0}},"total":{"docs":{"count":9007199254740992,"deleted":0}}}}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, out)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	var buf bytes.Buffer
+	i := NewIndices(log.NewLogfmtLogger(&buf), http.DefaultClient, u, false, false, IndexLabelModeFull, 0, false, nil, 0, false, false)
+	expected := `
+# HELP elasticsearch_indices_docs_primary Count of documents with only primary shards
+# TYPE elasticsearch_indices_docs_primary gauge
+elasticsearch_indices_docs_primary{cluster="unknown_cluster",index="foo_1"} 9.007199254740992e+15
+# HELP elasticsearch_indices_docs_total Total count of documents
+# TYPE elasticsearch_indices_docs_total gauge
+elasticsearch_indices_docs_total{cluster="unknown_cluster",index="foo_1"} 9.007199254740992e+15
+`
+	if err := testutil.CollectAndCompare(i, strings.NewReader(expected), "elasticsearch_indices_docs_primary", "elasticsearch_indices_docs_total"); err != nil {
+		t.Errorf("Unexpected doc count metrics: %s", err)
+	}
+
+	logged := buf.String()
+	if !strings.Contains(logged, "metric=elasticsearch_indices_docs_primary value=9007199254740993 exported=9007199254740992") {
+		t.Errorf("Missing warning about the rounded doc count, logged: %s", logged)
+	}
+	// 2^53 is exact
+	if strings.Contains(logged, "elasticsearch_indices_docs_total") {
+		t.Errorf("Unexpected warning about an exact doc count, logged: %s", logged)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// legacyCollectorFlags returns the optional collectors by name, enabled by
+// their own boolean flag es.<name>
+func legacyCollectorFlags() map[string]bool {
+	return map[string]bool{
+		"async_search":     *esExportAsyncSearch,
+		"cat_allocation":   *esExportCatAllocation,
+		"cluster_settings": *esExportClusterSettings,
+		"cluster_state":    *esExportClusterState,
+		"cluster_stats":    *esExportClusterStats,
+		"enrich":           *esExportEnrich,
+		"indices":          *esExportIndices,
+		"indices_settings": *esExportIndicesSettings,
+		"license":          *esExportLicense,
+		"mappings":         *esExportMappings,
+		"nodes_usage":      *esExportNodesUsage,
+		"pending_tasks":    *esExportPendingTasks,
+		"remote_info":      *esExportRemoteInfo,
+		"retention":        *esExportRetention,
+		"security":         *esExportSecurity,
+		"shards":           *esExportShards,
+		"snapshots":        *esExportSnapshots,
+		"templates":        *esExportTemplates,
+	}
+}
+
+// resolveCollectors applies the collector names of collector.enable and then
+// those of collector.disable to the collectors enabled by the legacy flags
+func resolveCollectors(legacy map[string]bool, enable, disable []string) (map[string]bool, error) {
+	collectors := make(map[string]bool, len(legacy))
+	for name, enabled := range legacy {
+		collectors[name] = enabled
+	}
+	for _, names := range []struct {
+		flag    string
+		names   []string
+		enabled bool
+	}{
+		{"collector.enable", enable, true},
+		{"collector.disable", disable, false},
+	} {
+		for _, name := range names.names {
+			if _, ok := collectors[name]; !ok {
+				return nil, fmt.Errorf("unknown collector %q in %s, valid collectors are %v", name, names.flag, collectorNames(legacy))
+			}
+			collectors[name] = names.enabled
+		}
+	}
+	return collectors, nil
+}
+
+// collectorNames returns the sorted names of the collectors
+func collectorNames(collectors map[string]bool) []string {
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newCollectorsHandler serves the optional collectors and whether they are enabled as JSON
+func newCollectorsHandler(logger log.Logger, collectors map[string]bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.MarshalIndent(collectors, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "failed handling writer",
+				"err", err,
+			)
+		}
+	}
+}
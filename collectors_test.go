@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestResolveCollectors(t *testing.T) {
+	legacy := map[string]bool{
+		"indices":   false,
+		"shards":    false,
+		"snapshots": true,
+		"templates": false,
+	}
+
+	collectors, err := resolveCollectors(legacy, []string{"indices", "templates"}, []string{"snapshots"})
+	if err != nil {
+		t.Fatalf("Failed to resolve collectors: %s", err)
+	}
+	expected := map[string]bool{
+		"indices":   true,
+		"shards":    false,
+		"snapshots": false,
+		"templates": true,
+	}
+	if !reflect.DeepEqual(collectors, expected) {
+		t.Errorf("Expected collectors %v, got %v", expected, collectors)
+	}
+	if !legacy["snapshots"] {
+		t.Errorf("The legacy flags were modified")
+	}
+
+	// disabling wins over enabling
+	collectors, err = resolveCollectors(legacy, []string{"shards"}, []string{"shards"})
+	if err != nil {
+		t.Fatalf("Failed to resolve collectors: %s", err)
+	}
+	if collectors["shards"] {
+		t.Errorf("Collector shards should be disabled")
+	}
+
+	for _, tc := range []struct {
+		enable, disable []string
+	}{
+		{[]string{"nodes"}, nil},
+		{nil, []string{"snapshot"}},
+	} {
+		if _, err := resolveCollectors(legacy, tc.enable, tc.disable); err == nil {
+			t.Errorf("Expected an error for unknown collectors in %v/%v", tc.enable, tc.disable)
+		}
+	}
+}
+
+func TestCollectorsHandler(t *testing.T) {
+	collectors := map[string]bool{"indices": true, "snapshots": false}
+	rec := httptest.NewRecorder()
+	newCollectorsHandler(log.NewNopLogger(), collectors)(rec, httptest.NewRequest(http.MethodGet, "/collectors", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", rec.Code)
+	}
+	var got map[string]bool
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode collectors: %s", err)
+	}
+	if !reflect.DeepEqual(got, collectors) {
+		t.Errorf("Expected collectors %v, got %v", collectors, got)
+	}
+}
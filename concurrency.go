@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// concurrencyLimitRoundTripper bounds the number of outstanding requests to
+// Elasticsearch. All collectors of a scrape share the HTTP client, so this
+// limits how many of them fetch concurrently.
+type concurrencyLimitRoundTripper struct {
+	next http.RoundTripper
+	sem  chan struct{}
+}
+
+func newConcurrencyLimitRoundTripper(next http.RoundTripper, limit int) http.RoundTripper {
+	return &concurrencyLimitRoundTripper{
+		next: next,
+		sem:  make(chan struct{}, limit),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *concurrencyLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case rt.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		<-rt.sem
+		return nil, err
+	}
+	// the request is outstanding until its body is read and closed
+	res.Body = &releasingBody{ReadCloser: res.Body, release: func() { <-rt.sem }}
+	return res, nil
+}
+
+// releasingBody frees the slot of a request once its body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
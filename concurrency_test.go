@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitRoundTripper(t *testing.T) {
+	const limit = 2
+	var outstanding, max int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&outstanding, 1)
+		defer atomic.AddInt32(&outstanding, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: newConcurrencyLimitRoundTripper(http.DefaultTransport, limit)}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(ts.URL + "/_nodes/stats")
+			if err != nil {
+				t.Errorf("Request failed: %s", err)
+				return
+			}
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if max > limit {
+		t.Errorf("Expected at most %d concurrent requests, got %d", limit, max)
+	}
+	if max < limit {
+		t.Errorf("Expected requests to run concurrently up to %d, got %d", limit, max)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// redacted replaces secrets in the effective configuration
+const redacted = "[redacted]"
+
+var (
+	// secretFlags are never shown by the config endpoint. Only paths of the
+	// private keys are configured, but they tell where to look for them.
+	secretFlags = map[string]bool{
+		"es.client-private-key": true,
+		"web.tls-key":           true,
+	}
+	// urlFlags are comma separated URLs which may contain credentials
+	urlFlags = map[string]bool{
+		"es.uri":       true,
+		"push.gateway": true,
+	}
+)
+
+// effectiveConfig returns the values of all flags of app after parsing, from
+// the command line, the environment or the defaults, with secrets redacted
+func effectiveConfig(app *kingpin.Application) map[string]string {
+	config := make(map[string]string)
+	for _, flag := range app.Model().Flags {
+		// flags added by kingpin itself
+		if flag.Name == "help" || flag.Name == "version" || flag.Hidden {
+			continue
+		}
+		value := flag.String()
+		switch {
+		case value == "":
+		case secretFlags[flag.Name]:
+			value = redacted
+		case urlFlags[flag.Name]:
+			value = redactURLs(value)
+		}
+		config[flag.Name] = value
+	}
+	return config
+}
+
+// redactURLs replaces the passwords in a comma separated list of URLs
+func redactURLs(value string) string {
+	urls := strings.Split(value, ",")
+	for i, raw := range urls {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			// the password may be in there, but can't be found
+			urls[i] = redacted
+			continue
+		}
+		if _, ok := u.User.Password(); !ok {
+			continue
+		}
+		user := u.User.Username()
+		u.User = nil
+		urls[i] = strings.Replace(u.String(), "://", "://"+url.PathEscape(user)+":"+redacted+"@", 1)
+	}
+	return strings.Join(urls, ",")
+}
+
+// newConfigHandler serves the effective configuration of app as JSON
+func newConfigHandler(logger log.Logger, app *kingpin.Application) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.MarshalIndent(effectiveConfig(app), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "failed handling writer",
+				"err", err,
+			)
+		}
+	}
+}
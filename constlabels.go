@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// parseConstLabels validates the labels of the const-label flag, which kingpin
+// already split into names and values, and returns them as prometheus.Labels
+func parseConstLabels(pairs map[string]string) (prometheus.Labels, error) {
+	labels := make(prometheus.Labels, len(pairs))
+	for name, value := range pairs {
+		// names starting with __ are reserved for internal use
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("empty value of label %q", name)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// wrapDefaultCollectors replaces the Go and process collectors, which the
+// default registry comes with, by ones registered with reg, so they have the
+// const labels of reg as well
+func wrapDefaultCollectors(reg prometheus.Registerer) {
+	goCollector := prometheus.NewGoCollector()
+	processCollector := prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})
+	prometheus.Unregister(goCollector)
+	prometheus.Unregister(processCollector)
+	reg.MustRegister(goCollector, processCollector)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseConstLabels(t *testing.T) {
+	for name, tc := range map[string]struct {
+		pairs map[string]string
+		valid bool
+	}{
+		"none":           {map[string]string{}, true},
+		"labels":         {map[string]string{"environment": "prod", "cluster_alias": "logs-eu"}, true},
+		"invalid name":   {map[string]string{"cluster-alias": "logs-eu"}, false},
+		"reserved name":  {map[string]string{"__name__": "foo"}, false},
+		"empty value":    {map[string]string{"environment": ""}, false},
+		"starting digit": {map[string]string{"1st": "prod"}, false},
+	} {
+		labels, err := parseConstLabels(tc.pairs)
+		if tc.valid != (err == nil) {
+			t.Errorf("[%s] Expected valid %v, got error %v", name, tc.valid, err)
+			continue
+		}
+		if tc.valid && len(labels) != len(tc.pairs) {
+			t.Errorf("[%s] Expected %d labels, got %v", name, len(tc.pairs), labels)
+		}
+	}
+}
+
+func TestRegisterCollectorsConstLabels(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintln(w, `{"name":"es01","cluster_name":"elasticsearch","cluster_uuid":"r1bT9sBrR7S9-CamE41Qqg","version":{"number":"7.10.0"}}`)
+		case "/_cluster/health":
+			fmt.Fprintln(w, `{"cluster_name":"elasticsearch","status":"green","number_of_nodes":1}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	registry := prometheus.NewRegistry()
+	labels := prometheus.Labels{"environment": "prod"}
+	if err := registerCollectors(ctx, log.NewNopLogger(), prometheus.WrapRegistererWith(labels, registry), http.DefaultClient, u, nil); err != nil {
+		t.Fatalf("Failed to register collectors: %s", err)
+	}
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %s", err)
+	}
+	var found bool
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			var environment string
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "environment" {
+					environment = l.GetValue()
+				}
+			}
+			if environment != "prod" {
+				t.Errorf("Metric %s without the const label, got environment=%q", mf.GetName(), environment)
+			}
+		}
+		if mf.GetName() == "elasticsearch_cluster_health_up" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Missing metric elasticsearch_cluster_health_up")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Values of es.scrape.fail-mode
+const (
+	// scrapeFailModePartial serves the metrics of all successful collectors
+	scrapeFailModePartial = "partial"
+	// scrapeFailModeStrict fails the whole scrape if any collector failed
+	scrapeFailModeStrict = "strict"
+)
+
+// optionalCollectorUps maps the collector label of elasticsearch_collector_supported
+// to the up metric of the collector
+var optionalCollectorUps = map[string]string{
+	"enrich":   "elasticsearch_enrich_up",
+	"license":  "elasticsearch_license_up",
+	"security": "elasticsearch_security_stats_up",
+}
+
+// failedCollectors returns the names of the up metrics reporting a failed
+// scrape. Every collector sets its up metric to 0 if fetching its stats failed.
+// Collectors of features the cluster doesn't support don't count, they're down
+// with es.disable-unavailable on every scrape.
+func failedCollectors(mfs []*dto.MetricFamily) []string {
+	unsupported := make(map[string]bool)
+	for _, mf := range mfs {
+		if mf.GetName() != "elasticsearch_collector_supported" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() != 0 {
+				continue
+			}
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "collector" {
+					unsupported[optionalCollectorUps[l.GetValue()]] = true
+				}
+			}
+		}
+	}
+
+	var failed []string
+	for _, mf := range mfs {
+		if !strings.HasSuffix(mf.GetName(), "_up") || mf.GetType() != dto.MetricType_GAUGE || unsupported[mf.GetName()] {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			if m.GetGauge().GetValue() == 0 {
+				failed = append(failed, mf.GetName())
+				break
+			}
+		}
+	}
+	return failed
+}
+
+// serveMetrics gathers the metrics and writes the ones matching include and
+// exclude to w. In strict mode a gather error or a failed collector results in
+// a 500 without a body. In partial mode the successfully gathered metrics are
+// served with a 200 and errors are only logged. The served metrics are renamed
+// by renames. The gathered metrics are returned unfiltered and not renamed.
+func serveMetrics(w http.ResponseWriter, r *http.Request, logger log.Logger, gatherer prometheus.Gatherer, include, exclude *regexp.Regexp, renames map[string]string, failMode string) []*dto.MetricFamily {
+	// the up metrics are checked before filtering, so excluding them doesn't hide failures
+	mfs, err := gatherer.Gather()
+	if failMode == scrapeFailModeStrict {
+		if failed := failedCollectors(mfs); err == nil && len(failed) > 0 {
+			err = fmt.Errorf("collectors failed: %s", strings.Join(failed, ", "))
+		}
+		if err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "failing scrape",
+				"err", err,
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+			return mfs
+		}
+	} else if err != nil {
+		_ = level.Warn(logger).Log(
+			"msg", "serving partial scrape",
+			"err", err,
+		)
+	}
+
+	gathered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return mfs, nil
+	})
+	// drop metrics excluded by es.metrics.include / es.metrics.exclude before exposition,
+	// they match the names before metric-rename-file is applied
+	h := promhttp.HandlerFor(newRenamingGatherer(newFilteredGatherer(gathered, include, exclude), renames), promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+	return mfs
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/justwatchcom/elasticsearch_exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestServeMetricsFailMode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "master_not_discovered_exception", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+
+	for failMode, expectedCode := range map[string]int{
+		scrapeFailModePartial: http.StatusOK,
+		scrapeFailModeStrict:  http.StatusInternalServerError,
+	} {
+		registry := prometheus.NewRegistry()
+		// one failing and one successful collector
+		registry.MustRegister(collector.NewClusterHealth(log.NewNopLogger(), http.DefaultClient, u, collector.ClusterHealthLevelCluster))
+		registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "elasticsearch_exporter_test",
+			Help: "Test metric of a successful collector",
+		}))
+
+		rec := httptest.NewRecorder()
+		serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil), log.NewNopLogger(), registry, nil, nil, nil, failMode)
+		body, err := ioutil.ReadAll(rec.Result().Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %s", err)
+		}
+
+		if rec.Code != expectedCode {
+			t.Errorf("[%s] Expected status %d, got %d", failMode, expectedCode, rec.Code)
+		}
+		switch failMode {
+		case scrapeFailModePartial:
+			if !strings.Contains(string(body), "elasticsearch_exporter_test 0") || !strings.Contains(string(body), "elasticsearch_cluster_health_up 0") {
+				t.Errorf("[%s] Metrics of the successful collector missing: %s", failMode, body)
+			}
+		case scrapeFailModeStrict:
+			if len(body) != 0 {
+				t.Errorf("[%s] Expected an empty body, got: %s", failMode, body)
+			}
+		}
+	}
+}
+
+func TestFailedCollectorsExcludedUp(t *testing.T) {
+	// failures are detected even if the up metrics are excluded from the exposition
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "elasticsearch_cluster_health_up",
+		Help: "Was the last scrape of the ElasticSearch cluster health endpoint successful.",
+	})
+	registry.MustRegister(up)
+	exclude, err := compileMetricNameRegexp(".*_up")
+	if err != nil {
+		t.Fatalf("Failed to compile exclude regexp: %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil), log.NewNopLogger(), registry, nil, exclude, nil, scrapeFailModeStrict)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestFailedCollectorsUnsupported(t *testing.T) {
+	// the OSS distribution doesn't know the enrich endpoint
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"no handler found for uri [/_enrich/_stats]"}`, http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector.NewEnrich(log.NewNopLogger(), http.DefaultClient, u, true))
+
+	rec := httptest.NewRecorder()
+	serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil), log.NewNopLogger(), registry, nil, nil, nil, scrapeFailModeStrict)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `elasticsearch_collector_supported{collector="enrich"} 0`) || !strings.Contains(body, "elasticsearch_enrich_up 0") {
+		t.Errorf("Unavailable feature not reported: %s", body)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var esActiveURIIndex = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: prometheus.BuildFQName("elasticsearch", "exporter", "active_uri_index"),
+		Help: "Index of the es.uri seed the exporter currently sends requests to",
+	},
+)
+
+// seedURIs holds the Elasticsearch URIs given in es.uri and remembers which
+// of them answered last. It's shared by all HTTP clients, as a new client is
+// created for every scrape.
+type seedURIs struct {
+	uris        []*url.URL
+	activeIndex prometheus.Gauge
+
+	mu     sync.Mutex
+	active int
+}
+
+// parseSeedURIs parses a comma separated list of Elasticsearch URIs
+func parseSeedURIs(list string, activeIndex prometheus.Gauge) (*seedURIs, error) {
+	var uris []*url.URL
+	for _, uri := range strings.Split(list, ",") {
+		uri = strings.TrimSpace(uri)
+		if uri == "" {
+			continue
+		}
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		uris = append(uris, u)
+	}
+	if len(uris) == 0 {
+		return nil, errors.New("no Elasticsearch URI given")
+	}
+	return &seedURIs{
+		uris:        uris,
+		activeIndex: activeIndex,
+	}, nil
+}
+
+// primary returns the first seed, which the collectors build their requests for
+func (s *seedURIs) primary() *url.URL {
+	return s.uris[0]
+}
+
+func (s *seedURIs) current() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+func (s *seedURIs) setActive(i int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = i
+	s.activeIndex.Set(float64(i))
+}
+
+// roundTripper returns a http.RoundTripper sending requests for the primary
+// seed to the active one and failing over to the next seed on connection errors
+func (s *seedURIs) roundTripper(next http.RoundTripper) http.RoundTripper {
+	return &failoverRoundTripper{
+		next:  next,
+		seeds: s,
+	}
+}
+
+type failoverRoundTripper struct {
+	next  http.RoundTripper
+	seeds *seedURIs
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *failoverRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	primary := rt.seeds.primary()
+	// requests for another target or with a body that can't be sent twice are passed through
+	if len(rt.seeds.uris) == 1 || req.URL.Host != primary.Host || (req.Body != nil && req.Body != http.NoBody) {
+		return rt.next.RoundTrip(req)
+	}
+
+	start := rt.seeds.current()
+	var err error
+	for n := 0; n < len(rt.seeds.uris); n++ {
+		i := (start + n) % len(rt.seeds.uris)
+		var res *http.Response
+		res, err = rt.next.RoundTrip(rewriteSeedRequest(req, primary, rt.seeds.uris[i]))
+		if err == nil {
+			rt.seeds.setActive(i)
+			return res, nil
+		}
+		// the client timeout applies to all seeds
+		if req.Context().Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// rewriteSeedRequest returns a copy of req for the primary seed sent to seed instead
+func rewriteSeedRequest(req *http.Request, primary, seed *url.URL) *http.Request {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = seed.Scheme
+	r.URL.Host = seed.Host
+	r.URL.Path = path.Join("/", seed.Path, strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(primary.Path, "/")))
+	r.Host = ""
+	if seed.User != nil {
+		password, _ := seed.User.Password()
+		r.SetBasicAuth(seed.User.Username(), password)
+	}
+	return r
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFailoverRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	// a port nobody listens on anymore
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %s", err)
+	}
+	down := "http://" + l.Addr().String() + "/es"
+	l.Close()
+
+	activeIndex := prometheus.NewGauge(prometheus.GaugeOpts{Name: "active_uri_index", Help: "active"})
+	seeds, err := parseSeedURIs(down+", "+ts.URL, activeIndex)
+	if err != nil {
+		t.Fatalf("Failed to parse seed URIs: %s", err)
+	}
+	if len(seeds.uris) != 2 {
+		t.Fatalf("Wrong number of seed URIs: %d", len(seeds.uris))
+	}
+	client := &http.Client{Transport: seeds.roundTripper(http.DefaultTransport)}
+
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(seeds.primary().String() + "/_cluster/health")
+		if err != nil {
+			t.Fatalf("Request should fail over to the second URI: %s", err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "/_cluster/health" {
+			t.Errorf("Wrong path on the second URI: %s", body)
+		}
+	}
+	if v := testutil.ToFloat64(activeIndex); v != 1 {
+		t.Errorf("Wrong active URI index: %v", v)
+	}
+}
+
+func TestParseSeedURIs(t *testing.T) {
+	activeIndex := prometheus.NewGauge(prometheus.GaugeOpts{Name: "active_uri_index", Help: "active"})
+	if _, err := parseSeedURIs(" , ", activeIndex); err == nil {
+		t.Errorf("An empty list of URIs should return an error")
+	}
+	if _, err := parseSeedURIs("http://localhost:9200,http://[::1", activeIndex); err == nil {
+		t.Errorf("An invalid URI should return an error")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// filteredGatherer wraps a prometheus.Gatherer and drops all metric families
+// whose name doesn't match include or does match exclude. A nil regexp
+// disables the respective check.
+type filteredGatherer struct {
+	gatherer prometheus.Gatherer
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+}
+
+func newFilteredGatherer(g prometheus.Gatherer, include, exclude *regexp.Regexp) prometheus.Gatherer {
+	if include == nil && exclude == nil {
+		return g
+	}
+	return &filteredGatherer{
+		gatherer: g,
+		include:  include,
+		exclude:  exclude,
+	}
+}
+
+// Gather implements the prometheus.Gatherer interface
+func (g *filteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+	filtered := make([]*dto.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		if g.include != nil && !g.include.MatchString(mf.GetName()) {
+			continue
+		}
+		if g.exclude != nil && g.exclude.MatchString(mf.GetName()) {
+			continue
+		}
+		filtered = append(filtered, mf)
+	}
+	return filtered, err
+}
+
+// compileMetricNameRegexp compiles expr anchored to the full metric name.
+// An empty expr returns a nil regexp.
+func compileMetricNameRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric name regexp %q: %s", expr, err)
+	}
+	return re, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestFilteredGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{
+		"elasticsearch_jvm_memory_used_bytes",
+		"elasticsearch_jvm_gc_collection_seconds_count",
+		"elasticsearch_os_load1",
+		"elasticsearch_cluster_health_up",
+	} {
+		registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: name,
+			Help: name,
+		}))
+	}
+
+	exclude, err := compileMetricNameRegexp("elasticsearch_jvm_.*")
+	if err != nil {
+		t.Fatalf("Failed to compile exclude regexp: %s", err)
+	}
+	include, err := compileMetricNameRegexp("elasticsearch_(jvm|os)_.*")
+	if err != nil {
+		t.Fatalf("Failed to compile include regexp: %s", err)
+	}
+
+	tcs := map[string]struct {
+		gatherer prometheus.Gatherer
+		want     []string
+	}{
+		"exclude": {
+			gatherer: newFilteredGatherer(registry, nil, exclude),
+			want:     []string{"elasticsearch_cluster_health_up", "elasticsearch_os_load1"},
+		},
+		"include": {
+			gatherer: newFilteredGatherer(registry, include, nil),
+			want: []string{
+				"elasticsearch_jvm_gc_collection_seconds_count",
+				"elasticsearch_jvm_memory_used_bytes",
+				"elasticsearch_os_load1",
+			},
+		},
+		"include and exclude": {
+			gatherer: newFilteredGatherer(registry, include, exclude),
+			want:     []string{"elasticsearch_os_load1"},
+		},
+	}
+	for name, tc := range tcs {
+		mfs, err := tc.gatherer.Gather()
+		if err != nil {
+			t.Fatalf("[%s] Failed to gather metrics: %s", name, err)
+		}
+		if len(mfs) != len(tc.want) {
+			t.Fatalf("[%s] Wrong number of metric families: got %d, want %d", name, len(mfs), len(tc.want))
+		}
+		for i, mf := range mfs {
+			if mf.GetName() != tc.want[i] {
+				t.Errorf("[%s] Wrong metric family: got %s, want %s", name, mf.GetName(), tc.want[i])
+			}
+		}
+	}
+}
+
+func TestCompileMetricNameRegexp(t *testing.T) {
+	re, err := compileMetricNameRegexp("")
+	if err != nil || re != nil {
+		t.Errorf("Empty expression should disable filtering")
+	}
+	re, err = compileMetricNameRegexp("elasticsearch_jvm")
+	if err != nil {
+		t.Fatalf("Failed to compile regexp: %s", err)
+	}
+	if re.MatchString("elasticsearch_jvm_memory_used_bytes") {
+		t.Errorf("Regexp should match the full metric name only")
+	}
+	if _, err := compileMetricNameRegexp("("); err == nil {
+		t.Errorf("Invalid regexp should return an error")
+	}
+}
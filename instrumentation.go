@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/justwatchcom/elasticsearch_exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	esRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    prometheus.BuildFQName("elasticsearch", "exporter", "request_duration_seconds"),
+			Help:    "Duration of the requests to Elasticsearch by endpoint",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	)
+	esRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: prometheus.BuildFQName("elasticsearch", "exporter", "requests_total"),
+			Help: "Count of requests to Elasticsearch by endpoint and status code",
+		},
+		[]string{"endpoint", "code"},
+	)
+)
+
+// instrumentedRoundTripper records the duration and status code of every
+// request to Elasticsearch, labeled with the normalized endpoint
+type instrumentedRoundTripper struct {
+	next     http.RoundTripper
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newInstrumentedRoundTripper(next http.RoundTripper, requests *prometheus.CounterVec, duration *prometheus.HistogramVec) http.RoundTripper {
+	return &instrumentedRoundTripper{
+		next:     next,
+		requests: requests,
+		duration: duration,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := collector.NormalizeEndpoint(req.URL.Path)
+	start := time.Now()
+	res, err := rt.next.RoundTrip(req)
+	rt.duration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		rt.requests.WithLabelValues(endpoint, "error").Inc()
+		return res, err
+	}
+	rt.requests.WithLabelValues(endpoint, strconv.Itoa(res.StatusCode)).Inc()
+	return res, nil
+}
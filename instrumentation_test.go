@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/justwatchcom/elasticsearch_exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentedRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"cluster_name":"elasticsearch","status":"green","timed_out":false,"number_of_nodes":1,"number_of_data_nodes":1}`)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL + "/es")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "requests"}, []string{"endpoint", "code"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "request_duration_seconds", Help: "duration"}, []string{"endpoint"})
+	client := &http.Client{
+		Transport: newInstrumentedRoundTripper(http.DefaultTransport, requests, duration),
+	}
+
+	c := collector.NewClusterHealth(log.NewNopLogger(), client, u, collector.ClusterHealthLevelCluster)
+	testutil.CollectAndCount(c)
+	testutil.CollectAndCount(c)
+
+	expected := `
+# HELP requests_total requests
+# TYPE requests_total counter
+requests_total{code="200",endpoint="/_cluster/health"} 2
+`
+	if err := testutil.CollectAndCompare(requests, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected request count: %s", err)
+	}
+	if n := testutil.CollectAndCount(duration); n != 1 {
+		t.Errorf("Wrong number of request duration histograms: %d", n)
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"time"
 
 	"context"
@@ -27,35 +32,178 @@ var (
 	metricsPath = kingpin.Flag("web.telemetry-path",
 		"Path under which to expose metrics.").
 		Default("/metrics").Envar("WEB_TELEMETRY_PATH").String()
+	webTLSCert = kingpin.Flag("web.tls-cert",
+		"Path to the PEM encoded certificate to serve the web interface and telemetry with TLS.").
+		Default("").Envar("WEB_TLS_CERT").String()
+	webTLSKey = kingpin.Flag("web.tls-key",
+		"Path to the PEM encoded private key of the web.tls-cert certificate.").
+		Default("").Envar("WEB_TLS_KEY").String()
+	webTLSClientCA = kingpin.Flag("web.tls-client-ca",
+		"Path to the PEM encoded CA certificate clients have to present a certificate signed by.").
+		Default("").Envar("WEB_TLS_CLIENT_CA").String()
+	webShutdownTimeout = kingpin.Flag("web.shutdown-timeout",
+		"Time to wait for in-flight scrapes to finish on shutdown before they are aborted.").
+		Default("5s").Envar("WEB_SHUTDOWN_TIMEOUT").Duration()
+	webExposeConfig = kingpin.Flag("web.expose-config",
+		"Serve the effective configuration with redacted secrets as JSON on /config.").
+		Default("false").Envar("WEB_EXPOSE_CONFIG").Bool()
+	webEnablePprof = kingpin.Flag("web.enable-pprof",
+		"Serve the Go runtime profiling data of net/http/pprof on /debug/pprof/.").
+		Default("false").Envar("WEB_ENABLE_PPROF").Bool()
+	constLabels = kingpin.Flag("const-label",
+		"Constant label added to every metric, specified as name=value. Can be repeated.").
+		Envar("CONST_LABEL").StringMap()
 	esURI = kingpin.Flag("es.uri",
-		"HTTP API address of an Elasticsearch node.").
+		"HTTP API address of an Elasticsearch node. Several comma separated addresses are tried in order until one is reachable.").
 		Default("http://localhost:9200").Envar("ES_URI").String()
+	esPreflight = kingpin.Flag("es.preflight",
+		"Check on startup that Elasticsearch is reachable at es.uri and log its version. The exporter exits if it isn't reachable.").
+		Default("false").Envar("ES_PREFLIGHT").Bool()
+	esPreflightSoft = kingpin.Flag("es.preflight.soft",
+		"Only log a failed es.preflight check instead of exiting.").
+		Default("false").Envar("ES_PREFLIGHT_SOFT").Bool()
 	esTimeout = kingpin.Flag("es.timeout",
-		"Timeout for trying to get stats from Elasticsearch.").
+		"Timeout for trying to get stats from Elasticsearch, if Prometheus doesn't send its scrape timeout.").
 		Default("5s").Envar("ES_TIMEOUT").Duration()
+	esFollowRedirects = kingpin.Flag("es.follow-redirects",
+		"Follow redirects of a proxy in front of Elasticsearch, attaching the credentials again only on the same scheme, host and port. Redirect responses fail the request otherwise.").
+		Default("false").Envar("ES_FOLLOW_REDIRECTS").Bool()
 	esAllNodes = kingpin.Flag("es.all",
 		"Export stats for all nodes in the cluster. If used, this flag will override the flag es.node.").
 		Default("false").Envar("ES_ALL").Bool()
 	esNode = kingpin.Flag("es.node",
 		"Node's name of which metrics should be exposed.").
 		Default("_local").Envar("ES_NODE").String()
+	esNodeResolve = kingpin.Flag("es.node.resolve",
+		"How es.node is resolved: request (on every scrape) or stable (once to a node id, for a load balancer in front of several nodes).").
+		Default(collector.NodeResolveRequest).Envar("ES_NODE_RESOLVE").Enum(collector.NodeResolveRequest, collector.NodeResolveStable)
+	esNodeBalanceAttribute = kingpin.Flag("es.node.balance-attribute",
+		"Node attribute (e.g. zone) to count the nodes by, to check the balance of the cluster. Requires es.all.").
+		Default("").Envar("ES_NODE_BALANCE_ATTRIBUTE").String()
+	esClusterHealthLevel = kingpin.Flag("es.cluster_health.level",
+		"Level of the cluster health: cluster, or indices and shards to additionally export the health of every index.").
+		Default(collector.ClusterHealthLevelCluster).Envar("ES_CLUSTER_HEALTH_LEVEL").
+		Enum(collector.ClusterHealthLevelCluster, collector.ClusterHealthLevelIndices, collector.ClusterHealthLevelShards)
 	esExportIndices = kingpin.Flag("es.indices",
 		"Export stats for indices in the cluster.").
 		Default("false").Envar("ES_INDICES").Bool()
 	esExportIndicesSettings = kingpin.Flag("es.indices_settings",
 		"Export stats for settings of all indices of the cluster.").
 		Default("false").Envar("ES_INDICES_SETTINGS").Bool()
+	esIndicesSettingsKeys = kingpin.Flag("es.indices_settings.keys",
+		"Comma separated list of index settings (e.g. number_of_replicas,blocks.read_only) to export per index. Requires --es.indices_settings.").
+		Default("").Envar("ES_INDICES_SETTINGS_KEYS").String()
 	esExportClusterSettings = kingpin.Flag("es.cluster_settings",
 		"Export stats for cluster settings.").
 		Default("false").Envar("ES_CLUSTER_SETTINGS").Bool()
+	esExportIndicesAggregationLabel = kingpin.Flag("es.indices.primaries-total-label",
+		"Export index stats with an aggregation label (primaries or total) instead of separate metric names.").
+		Default("false").Envar("ES_INDICES_PRIMARIES_TOTAL_LABEL").Bool()
+	esIndicesLabelMode = kingpin.Flag("es.indices.label-mode",
+		"How the index label of index stats is exported: full (index name), hashed (stable short hash of the index name) or drop (stats summed up across all indices).").
+		Default(collector.IndexLabelModeFull).Envar("ES_INDICES_LABEL_MODE").
+		Enum(collector.IndexLabelModeFull, collector.IndexLabelModeHashed, collector.IndexLabelModeDrop)
+	esIndicesTopN = kingpin.Flag("es.indices.top-n",
+		"Only export the stats of the N largest indices in detail and the sums of the remaining ones, for clusters with too many indices. Zero exports all indices.").
+		Default("0").Envar("ES_INDICES_TOP_N").Int()
+	esIndicesOpenOnly = kingpin.Flag("es.indices.open-only",
+		"Only export the stats of open indices. Closed indices are only exported by their status.").
+		Default("false").Envar("ES_INDICES_OPEN_ONLY").Bool()
+	esIndicesParallelFetch = kingpin.Flag("es.indices.parallel-fetch",
+		"List the indices with /_cat/indices and fetch their stats in parallel batches of es.indices.batch-size indices instead of all at once. A failed batch doesn't fail the others.").
+		Default("false").Envar("ES_INDICES_PARALLEL_FETCH").Bool()
+	esIndicesIncludeSystem = kingpin.Flag("es.indices.include-system",
+		"Export the stats of system indices, whose names start with a dot (e.g. .kibana, .tasks or .security), like those of other indices, and their health.").
+		Default("false").Envar("ES_INDICES_INCLUDE_SYSTEM").Bool()
+	esIndicesExcludeFrozen = kingpin.Flag("es.indices.exclude-frozen",
+		"Only export the health and store size of frozen and partially mounted indices instead of their stats, which are slow to fetch.").
+		Default("false").Envar("ES_INDICES_EXCLUDE_FROZEN").Bool()
+	esIndicesHealthOnly = kingpin.Flag("es.indices.health-only",
+		"Only export the health and status of every index instead of the index stats, which is much cheaper on large clusters. Ignored with --es.shards.").
+		Default("false").Envar("ES_INDICES_HEALTH_ONLY").Bool()
+	esIndicesBatchSize = kingpin.Flag("es.indices.batch-size",
+		"Number of indices whose stats are fetched with one request. Requires --es.indices.parallel-fetch.").
+		Default("100").Envar("ES_INDICES_BATCH_SIZE").Int()
+	esIndicesSearchGroups = kingpin.Flag("es.search-groups",
+		"Comma separated list of search groups (the stats groups of search requests) to export the query stats of per index. Requires --es.indices.").
+		Default("").Envar("ES_SEARCH_GROUPS").String()
 	esExportShards = kingpin.Flag("es.shards",
 		"Export stats for shards in the cluster (implies --es.indices).").
 		Default("false").Envar("ES_SHARDS").Bool()
+	esShardsAggregate = kingpin.Flag("es.shards.aggregate",
+		"With es.shards, export the number of shards per index and state instead of the stats of every shard, to bound the number of series on clusters with many shards.").
+		Default("false").Envar("ES_SHARDS_AGGREGATE").Bool()
 	esExportSnapshots = kingpin.Flag("es.snapshots",
 		"Export stats for the cluster snapshots.").
 		Default("false").Envar("ES_SNAPSHOTS").Bool()
+	esExportClusterStats = kingpin.Flag("es.cluster_stats",
+		"Export stats for the whole cluster.").
+		Default("false").Envar("ES_CLUSTER_STATS").Bool()
+	esExportCatAllocation = kingpin.Flag("es.cat_allocation",
+		"Export the disk allocation of each node.").
+		Default("false").Envar("ES_CAT_ALLOCATION").Bool()
+	esExportClusterState = kingpin.Flag("es.cluster_state",
+		"Export the cluster state version, the elected master node and the master eligible nodes.").
+		Default("false").Envar("ES_CLUSTER_STATE").Bool()
+	esClusterStateVotingConfig = kingpin.Flag("es.cluster_state.voting-config",
+		"With es.cluster_state, export the size of the voting configuration from the cluster state metadata, which the master serializes with the mappings of all indices.").
+		Default("false").Envar("ES_CLUSTER_STATE_VOTING_CONFIG").Bool()
+	esExportTemplates = kingpin.Flag("es.templates",
+		"Export the number and versions of the index and component templates.").
+		Default("false").Envar("ES_TEMPLATES").Bool()
+	esExportMappings = kingpin.Flag("es.mappings",
+		"Export the number of fields in the mapping and the total fields limit of each index.").
+		Default("false").Envar("ES_MAPPINGS").Bool()
+	esMappingsIndices = kingpin.Flag("es.mappings.indices",
+		"Comma separated list of index patterns (e.g. logs-*,-logs-debug-*) to export the mappings of. Requires --es.mappings.").
+		Default("_all").Envar("ES_MAPPINGS_INDICES").String()
+	esExportEnrich = kingpin.Flag("es.enrich",
+		"Export the enrich policy executions and the enrich lookups of the coordinating nodes.").
+		Default("false").Envar("ES_ENRICH").Bool()
+	esExportLicense = kingpin.Flag("es.license",
+		"Export the status, expiry date and maximum number of nodes of the license.").
+		Default("false").Envar("ES_LICENSE").Bool()
+	esExportPendingTasks = kingpin.Flag("es.pending_tasks",
+		"Export the number of pending cluster tasks by the kind of their source.").
+		Default("false").Envar("ES_PENDING_TASKS").Bool()
+	esExportRetention = kingpin.Flag("es.retention",
+		"Export the timestamp of the oldest document of every index matching es.retention.indices, searched at most once per es.retention.interval.").
+		Default("false").Envar("ES_RETENTION").Bool()
+	esRetentionIndices = kingpin.Flag("es.retention.indices",
+		"Comma separated list of index patterns (e.g. logs-*,-logs-debug-*) to search the oldest document of. Requires --es.retention.").
+		Default("_all").Envar("ES_RETENTION_INDICES").String()
+	esRetentionTimestampField = kingpin.Flag("es.retention.timestamp-field",
+		"Date field whose minimum is the timestamp of the oldest document of an index. Requires --es.retention.").
+		Default("@timestamp").Envar("ES_RETENTION_TIMESTAMP_FIELD").String()
+	esRetentionInterval = kingpin.Flag("es.retention.interval",
+		"Interval the oldest documents are searched in, at least 5m. Scrapes in between export the last result. Requires --es.retention.").
+		Default("1h").Envar("ES_RETENTION_INTERVAL").Duration()
+	esExportNodesUsage = kingpin.Flag("es.nodes_usage",
+		"Export how often the nodes were called by REST action and used each aggregation type.").
+		Default("false").Envar("ES_NODES_USAGE").Bool()
+	esNodesUsageActions = kingpin.Flag("es.nodes_usage.actions",
+		"Comma separated list of the REST actions (e.g. search_action,bulk_action) to export the calls of, all if empty. Requires --es.nodes_usage.").
+		Default("").Envar("ES_NODES_USAGE_ACTIONS").String()
+	esExportRemoteInfo = kingpin.Flag("es.remote_info",
+		"Export the connection state of the configured remote clusters.").
+		Default("false").Envar("ES_REMOTE_INFO").Bool()
+	esExportSecurity = kingpin.Flag("es.security",
+		"Export whether security is enabled on the cluster.").
+		Default("false").Envar("ES_SECURITY").Bool()
+	esDisableUnavailable = kingpin.Flag("es.disable-unavailable",
+		"Report the collectors of features the cluster doesn't provide (e.g. enrich or security on the OSS distribution) as down instead of exporting empty metrics.").
+		Default("false").Envar("ES_DISABLE_UNAVAILABLE").Bool()
+	esExportAsyncSearch = kingpin.Flag("es.async_search",
+		"Export stats for in-progress async searches.").
+		Default("false").Envar("ES_ASYNC_SEARCH").Bool()
+	collectorEnable = kingpin.Flag("collector.enable",
+		"Name of an optional collector to enable, in addition to the ones enabled by their es.<name> flag. Can be repeated.").
+		Envar("COLLECTOR_ENABLE").Strings()
+	collectorDisable = kingpin.Flag("collector.disable",
+		"Name of an optional collector to disable, even if enabled by its es.<name> flag or collector.enable. Can be repeated.").
+		Envar("COLLECTOR_DISABLE").Strings()
 	esClusterInfoInterval = kingpin.Flag("es.clusterinfo.interval",
-		"Cluster info update interval for the cluster label").
+		"Cluster info update interval for the cluster label and the node build info").
 		Default("5m").Envar("ES_CLUSTERINFO_INTERVAL").Duration()
 	esCA = kingpin.Flag("es.ca",
 		"Path to PEM file that contains trusted Certificate Authorities for the Elasticsearch connection.").
@@ -66,9 +214,54 @@ var (
 	esClientCert = kingpin.Flag("es.client-cert",
 		"Path to PEM file that contains the corresponding cert for the private key to connect to Elasticsearch.").
 		Default("").Envar("ES_CLIENT_CERT").String()
+	esMaxIdleConns = kingpin.Flag("es.max-idle-conns",
+		"Maximum number of idle (keep-alive) connections to Elasticsearch. Zero means no limit.").
+		Default("100").Envar("ES_MAX_IDLE_CONNS").Int()
+	esMaxConnsPerHost = kingpin.Flag("es.max-conns-per-host",
+		"Maximum number of connections to an Elasticsearch host, including connections in use. Zero means no limit.").
+		Default("0").Envar("ES_MAX_CONNS_PER_HOST").Int()
+	esIdleConnTimeout = kingpin.Flag("es.idle-conn-timeout",
+		"Time after which an idle (keep-alive) connection to Elasticsearch is closed. Zero means no limit.").
+		Default("90s").Envar("ES_IDLE_CONN_TIMEOUT").Duration()
+	esHTTP2 = kingpin.Flag("es.http2",
+		"Whether to use HTTP/2 for connections to Elasticsearch: auto leaves it to Go, true attempts HTTP/2 over TLS, false always uses HTTP/1.1.").
+		Default(http2Auto).Envar("ES_HTTP2").
+		Enum(http2Auto, http2Enabled, http2Disabled)
+	esTokenFile = kingpin.Flag("es.token-file",
+		"Path to a file with a bearer token to authenticate against Elasticsearch. The file is read for every request, so rotated tokens are used without a restart.").
+		Default("").Envar("ES_TOKEN_FILE").String()
 	esInsecureSkipVerify = kingpin.Flag("es.ssl-skip-verify",
 		"Skip SSL verification when connecting to Elasticsearch.").
 		Default("false").Envar("ES_SSL_SKIP_VERIFY").Bool()
+	esScrapeFailMode = kingpin.Flag("es.scrape.fail-mode",
+		"How failed collectors affect a scrape: partial serves the metrics of the successful collectors with a 200, strict fails the whole scrape with a 500.").
+		Default(scrapeFailModePartial).Envar("ES_SCRAPE_FAIL_MODE").
+		Enum(scrapeFailModePartial, scrapeFailModeStrict)
+	esScrapeConcurrency = kingpin.Flag("es.scrape.concurrency",
+		"Maximum number of concurrent requests of the collectors to Elasticsearch during a scrape. Zero means no limit.").
+		Default("0").Envar("ES_SCRAPE_CONCURRENCY").Int()
+	esUserAgent = kingpin.Flag("es.user-agent",
+		"User-Agent header sent with every request to Elasticsearch. Defaults to elasticsearch_exporter/<version>.").
+		Default("").Envar("ES_USER_AGENT").String()
+	esTransportChain = kingpin.Flag("es.transport-chain",
+		"Comma separated order of the wrappers of the transport to Elasticsearch, from the outermost one seeing a request first: token, useragent, concurrency, failover, instrumentation and logging. Wrappers left out aren't applied.").
+		Default(defaultTransportChain).Envar("ES_TRANSPORT_CHAIN").String()
+	esUnitsTime = kingpin.Flag("es.units.time",
+		"Unit of the time metrics: seconds, or millis for the raw values of Elasticsearch as exported by older versions. The unit in the metric names is replaced accordingly.").
+		Default(collector.TimeUnitSeconds).Envar("ES_UNITS_TIME").
+		Enum(collector.TimeUnitSeconds, collector.TimeUnitMillis)
+	esLogResponses = kingpin.Flag("es.log-responses",
+		"Log the status and body of every response from Elasticsearch at debug level (requires --log.level=debug). May leak sensitive data into the logs.").
+		Default("false").Envar("ES_LOG_RESPONSES").Bool()
+	esLogResponsesMaxBytes = kingpin.Flag("es.log-responses.max-bytes",
+		"Maximum number of bytes of a response body logged with es.log-responses. Zero means no limit.").
+		Default("4096").Envar("ES_LOG_RESPONSES_MAX_BYTES").Int()
+	esCircuitBreakerThreshold = kingpin.Flag("es.circuit-breaker.threshold",
+		"Number of consecutive failed scrapes of a target after which it isn't scraped for es.circuit-breaker.cooldown. Scrapes are answered with a 503 in the meantime. Zero disables the circuit breaker.").
+		Default("0").Envar("ES_CIRCUIT_BREAKER_THRESHOLD").Int()
+	esCircuitBreakerCooldown = kingpin.Flag("es.circuit-breaker.cooldown",
+		"Time a target isn't scraped after es.circuit-breaker.threshold consecutive failed scrapes.").
+		Default("1m").Envar("ES_CIRCUIT_BREAKER_COOLDOWN").Duration()
 	logLevel = kingpin.Flag("log.level",
 		"Sets the loglevel. Valid levels are debug, info, warn, error").
 		Default("info").Envar("LOG_LEVEL").String()
@@ -78,6 +271,24 @@ var (
 	logOutput = kingpin.Flag("log.output",
 		"Sets the log output. Valid outputs are stdout and stderr").
 		Default("stdout").Envar("LOG_OUTPUT").String()
+	pushGateway = kingpin.Flag("push.gateway",
+		"URL of a Pushgateway to push metrics to on every es.clusterinfo.interval. Pushing is disabled if empty.").
+		Default("").Envar("PUSH_GATEWAY").String()
+	pushJob = kingpin.Flag("push.job",
+		"Job name used when pushing metrics to the Pushgateway.").
+		Default("elasticsearch").Envar("PUSH_JOB").String()
+	pushGrouping = kingpin.Flag("push.grouping",
+		"Grouping label used when pushing metrics to the Pushgateway, specified as name=value. Can be repeated.").
+		Envar("PUSH_GROUPING").StringMap()
+	esMetricsInclude = kingpin.Flag("es.metrics.include",
+		"Regular expression matched against the full metric name. Only matching metrics are exported.").
+		Default("").Envar("ES_METRICS_INCLUDE").String()
+	esMetricsExclude = kingpin.Flag("es.metrics.exclude",
+		"Regular expression matched against the full metric name. Matching metrics are not exported.").
+		Default("").Envar("ES_METRICS_EXCLUDE").String()
+	metricRenameFile = kingpin.Flag("metric-rename-file",
+		"Path to a YAML file mapping current metric names to the names they are exported as, e.g. for dashboards of another exporter.").
+		Default("").Envar("METRIC_RENAME_FILE").String()
 )
 
 func main() {
@@ -87,16 +298,145 @@ func main() {
 
 	logger := getLogger(*logLevel, *logOutput, *logFormat)
 
+	collector.TimeUnit = *esUnitsTime
+
+	metricsInclude, err := compileMetricNameRegexp(*esMetricsInclude)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse es.metrics.include",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	metricsExclude, err := compileMetricNameRegexp(*esMetricsExclude)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse es.metrics.exclude",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	metricRenames, err := loadMetricRenames(*metricRenameFile)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to load metric-rename-file",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	collectors, err := resolveCollectors(legacyCollectorFlags(), *collectorEnable, *collectorDisable)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse collector.enable and collector.disable",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if collectors["retention"] && *esRetentionInterval < collector.RetentionMinInterval {
+		_ = level.Error(logger).Log(
+			"msg", "es.retention.interval must be at least "+collector.RetentionMinInterval.String(),
+			"interval", *esRetentionInterval,
+		)
+		os.Exit(1)
+	}
+
+	if *esIndicesParallelFetch && *esIndicesBatchSize < 1 {
+		_ = level.Error(logger).Log(
+			"msg", "es.indices.batch-size must be positive",
+			"batch_size", *esIndicesBatchSize,
+		)
+		os.Exit(1)
+	}
+
+	transportChain, err := parseTransportChain(*esTransportChain, transportWrapperNames())
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse es.transport-chain",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	seeds, err := parseSeedURIs(*esURI, esActiveURIIndex)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse es.uri",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	if *esTokenFile != "" {
+		if err := checkTokenAuth(*esTokenFile, seeds, *esClientCert); err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "failed to configure es.token-file",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+	}
+
+	if *esPreflight {
+		if err := preflight(logger, newHTTPClient(logger, seeds, transportChain), seeds.primary()); err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "preflight check failed",
+				"err", err,
+			)
+			if !*esPreflightSoft {
+				os.Exit(1)
+			}
+		}
+	}
+
+	labels, err := parseConstLabels(*constLabels)
+	if err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to parse const-label",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	// every registered collector is wrapped to add the const labels
+	registerer := prometheus.WrapRegistererWith(labels, prometheus.DefaultRegisterer)
+	if len(labels) > 0 {
+		wrapDefaultCollectors(registerer)
+	}
+
+	registerer.MustRegister(esRequests, esRequestDuration, esActiveURIIndex, targetCircuitOpen, collector.JSONParseErrors)
+
 	// create a context that is cancelled on SIGKILL
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if *pushGateway != "" {
+		pushRegistry := prometheus.NewRegistry()
+		if err := registerCollectors(ctx, logger, prometheus.WrapRegistererWith(labels, pushRegistry), newHTTPClient(logger, seeds, transportChain), seeds.primary(), collectors); err != nil {
+			_ = level.Error(logger).Log(
+				"msg", "failed to register collectors for the pushgateway",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		pusher := newPusher(*pushGateway, *pushJob, *pushGrouping,
+			newRenamingGatherer(newFilteredGatherer(pushRegistry, metricsInclude, metricsExclude), metricRenames))
+		_ = level.Info(logger).Log(
+			"msg", "starting to push metrics to the pushgateway",
+			"url", *pushGateway,
+			"interval", (*esClusterInfoInterval).String(),
+		)
+		go runPusher(ctx, logger, pusher, *esClusterInfoInterval)
+	}
+
 	// create a http server
 	server := &http.Server{}
 
-	handlerFunc := newPromHandler(ctx, logger)
+	breaker := newCircuitBreaker(*esCircuitBreakerThreshold, *esCircuitBreakerCooldown, targetCircuitOpen)
+	handlerFunc := newPromHandler(ctx, logger, seeds, transportChain, collectors, labels, metricsInclude, metricsExclude, metricRenames, *esScrapeFailMode, breaker)
 
-	mux := http.DefaultServeMux
-	mux.Handle(*metricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handlerFunc))
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.InstrumentMetricHandler(registerer, handlerFunc))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write([]byte(`<html>
 			<head><title>Elasticsearch Exporter</title></head>
@@ -113,11 +453,22 @@ func main() {
 		}
 	})
 
+	// optional collectors endpoint
+	mux.HandleFunc("/collectors", newCollectorsHandler(logger, collectors))
+
 	// health endpoint
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusOK), http.StatusOK)
 	})
 
+	if *webExposeConfig {
+		mux.HandleFunc("/config", newConfigHandler(logger, kingpin.CommandLine))
+	}
+
+	if *webEnablePprof {
+		registerPprof(mux)
+	}
+
 	server.Handler = mux
 	server.Addr = *listenAddress
 
@@ -127,7 +478,8 @@ func main() {
 	)
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		// the server is closed on shutdown
+		if err := listenAndServe(server, *webTLSCert, *webTLSKey, *webTLSClientCA); err != nil && err != http.ErrServerClosed {
 			_ = level.Error(logger).Log(
 				"msg", "http server quit",
 				"err", err,
@@ -139,104 +491,270 @@ func main() {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
-	// create a context for graceful http server shutdown
-	srvCtx, srvCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer srvCancel()
 	<-c
 	_ = level.Info(logger).Log("msg", "shutting down")
-	_ = server.Shutdown(srvCtx)
-	cancel()
+	shutdownServer(logger, server, *webShutdownTimeout, cancel)
 }
 
-func newPromHandler(ctx context.Context, logger log.Logger) http.HandlerFunc {
+func newPromHandler(ctx context.Context, logger log.Logger, seeds *seedURIs, transportChain []string, collectors map[string]bool, labels prometheus.Labels, metricsInclude, metricsExclude *regexp.Regexp, metricRenames map[string]string, failMode string, breaker *circuitBreaker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		registry := prometheus.NewRegistry()
 
+		esURL := seeds.primary()
 		target := r.URL.Query().Get("target")
 		if target != "" {
-			esURI = &target
+			var err error
+			esURL, err = url.Parse(target)
+			if err != nil {
+				_ = level.Error(logger).Log(
+					"msg", "failed to parse target",
+					"err", err,
+				)
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("failed to parse es.uri or target"))
+				return
+			}
 		}
 
-		esURL, err := url.Parse(*esURI)
-		if err != nil {
-			_ = level.Error(logger).Log(
-				"msg", "failed to parse es.uri",
-				"err", err,
-			)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("failed to parse es.uri or target"))
+		// a target failing repeatedly isn't scraped until the cooldown is over
+		circuit := circuitTarget(esURL)
+		if !breaker.allow(circuit) {
+			http.Error(w, "circuit open after repeated scrape failures of the target", http.StatusServiceUnavailable)
 			return
 		}
 
-		// returns nil if not provided and falls back to simple TCP.
-		tlsConfig := createTLSConfig(*esCA, *esClientCert, *esClientPrivateKey, *esInsecureSkipVerify)
-
-		httpClient := &http.Client{
-			Timeout: *esTimeout,
-			Transport: &http.Transport{
-				TLSClientConfig: tlsConfig,
-				Proxy:           http.ProxyFromEnvironment,
-			},
+		// requests which aren't done when Prometheus gives up on the scrape are cancelled
+		httpClient := newHTTPClient(logger, seeds, transportChain)
+		if deadline, ok := scrapeDeadline(r, time.Now()); ok {
+			// the scrape timeout replaces es.timeout, which may be shorter
+			httpClient.Timeout = 0
+			httpClient.Transport = newDeadlineRoundTripper(httpClient.Transport, deadline)
 		}
+		// the cluster info retriever of the scrape stops with it, instead of
+		// sending requests past the deadline
+		scrapeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-		// version metric
-		versionMetric := version.NewCollector(Name)
-		registry.MustRegister(versionMetric)
-
-		// cluster info retriever
-		clusterInfoRetriever := clusterinfo.New(logger, httpClient, esURL, *esClusterInfoInterval)
-
-		// start the cluster info retriever
-		switch runErr := clusterInfoRetriever.Run(ctx); runErr {
-		case nil:
-			_ = level.Info(logger).Log(
-				"msg", "started cluster info retriever",
-				"interval", (*esClusterInfoInterval).String(),
-			)
-		case clusterinfo.ErrInitialCallTimeout:
-			_ = level.Info(logger).Log("msg", "initial cluster info call timed out")
-		default:
-			_ = level.Error(logger).Log("msg", "failed to run cluster info retriever", "err", runErr)
+		if err := registerCollectors(scrapeCtx, logger, prometheus.WrapRegistererWith(labels, registry), httpClient, esURL, collectors); err != nil {
+			breaker.record(circuit, true)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("failed to run cluster info retriever"))
+			if failMode != scrapeFailModeStrict {
+				w.Write([]byte(err.Error()))
+			}
 			return
 		}
 
-		// register cluster info retriever as prometheus collector
-		registry.MustRegister(clusterInfoRetriever)
+		gatherers := prometheus.Gatherers{
+			prometheus.DefaultGatherer,
+			registry,
+		}
+		// gathering calls collector.Collect
+		mfs := serveMetrics(w, r, logger, gatherers, metricsInclude, metricsExclude, metricRenames, failMode)
+		breaker.record(circuit, targetDown(mfs))
+	}
+}
 
-		registry.MustRegister(collector.NewClusterHealth(logger, httpClient, esURL))
-		registry.MustRegister(collector.NewNodes(logger, httpClient, esURL, *esAllNodes, *esNode))
+// newHTTPClient creates the client to Elasticsearch, whose transport is wrapped
+// by the transport wrappers of chain
+func newHTTPClient(logger log.Logger, seeds *seedURIs, chain []string) *http.Client {
+	// returns nil if not provided and falls back to simple TCP.
+	tlsConfig := createTLSConfig(*esCA, *esClientCert, *esClientPrivateKey, *esInsecureSkipVerify)
 
-		if *esExportIndices || *esExportShards {
-			iC := collector.NewIndices(logger, httpClient, esURL, *esExportShards)
-			registry.MustRegister(iC)
-			if registerErr := clusterInfoRetriever.RegisterConsumer(iC); registerErr != nil {
-				_ = level.Error(logger).Log("msg", "failed to register indices collector in cluster info")
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte("failed to register indices collector in cluster info"))
-				return
-			}
-		}
+	transport := buildTransportChain(
+		newTransport(tlsConfig, *esMaxIdleConns, *esMaxConnsPerHost, *esIdleConnTimeout, *esHTTP2),
+		chain, transportWrappers(logger, seeds),
+	)
 
-		if *esExportSnapshots {
-			registry.MustRegister(collector.NewSnapshots(logger, httpClient, esURL))
-		}
+	return &http.Client{
+		Timeout:       *esTimeout,
+		Transport:     transport,
+		CheckRedirect: newCheckRedirect(logger, *esFollowRedirects),
+	}
+}
+
+// Values of es.http2
+const (
+	// http2Auto keeps the default of Go, which doesn't attempt HTTP/2 with a
+	// custom dialer like the one of newTransport
+	http2Auto = "auto"
+	// http2Enabled attempts HTTP/2 for TLS connections
+	http2Enabled = "true"
+	// http2Disabled uses HTTP/1.1 even if the server offers HTTP/2 over TLS
+	http2Disabled = "false"
+)
 
-		if *esExportClusterSettings {
-			registry.MustRegister(collector.NewClusterSettings(logger, httpClient, esURL))
+// newTransport creates the transport shared by all collectors of a scrape. All
+// requests go to the same Elasticsearch host, so every idle connection may be
+// kept for it.
+func newTransport(tlsConfig *tls.Config, maxIdleConns, maxConnsPerHost int, idleConnTimeout time.Duration, http2 string) *http.Transport {
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConns,
+		MaxConnsPerHost:     maxConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+	switch http2 {
+	case http2Enabled:
+		transport.ForceAttemptHTTP2 = true
+	case http2Disabled:
+		// a non-nil empty map stops the transport from upgrading to HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport
+}
+
+// registerCollectors registers the build info, the cluster info retriever, the
+// cluster health and nodes collectors and the enabled optional collectors for esURL
+// in registry
+func registerCollectors(ctx context.Context, logger log.Logger, registry prometheus.Registerer, httpClient *http.Client, esURL *url.URL, collectors map[string]bool) error {
+	// cluster info retriever
+	clusterInfoRetriever := clusterinfo.New(logger, httpClient, esURL, *esClusterInfoInterval)
+
+	// the build info is registered as consumer before the retriever is started,
+	// so it receives the initial cluster info
+	build := newBuildInfo()
+	if registerErr := clusterInfoRetriever.RegisterConsumer(build); registerErr != nil {
+		_ = level.Error(logger).Log("msg", "failed to register build info in cluster info")
+		return errors.New("failed to register build info in cluster info")
+	}
+	registry.MustRegister(build)
+
+	// the templates collector is registered as consumer before the retriever is
+	// started, so it receives the initial cluster info to choose the templates API
+	var templates *collector.Templates
+	if collectors["templates"] {
+		templates = collector.NewTemplates(logger, httpClient, esURL)
+		if registerErr := clusterInfoRetriever.RegisterConsumer(templates); registerErr != nil {
+			_ = level.Error(logger).Log("msg", "failed to register templates collector in cluster info")
+			return errors.New("failed to register templates collector in cluster info")
 		}
+	}
 
-		if *esExportIndicesSettings {
-			registry.MustRegister(collector.NewIndicesSettings(logger, httpClient, esURL))
+	// start the cluster info retriever
+	switch runErr := clusterInfoRetriever.Run(ctx); runErr {
+	case nil:
+		_ = level.Info(logger).Log(
+			"msg", "started cluster info retriever",
+			"interval", (*esClusterInfoInterval).String(),
+		)
+	case clusterinfo.ErrInitialCallTimeout:
+		_ = level.Info(logger).Log("msg", "initial cluster info call timed out")
+	default:
+		_ = level.Error(logger).Log("msg", "failed to run cluster info retriever", "err", runErr)
+		return errors.New("failed to run cluster info retriever")
+	}
+
+	// register cluster info retriever as prometheus collector
+	registry.MustRegister(clusterInfoRetriever)
+
+	registry.MustRegister(collector.NewClusterHealth(logger, httpClient, esURL, *esClusterHealthLevel))
+	registry.MustRegister(collector.NewNodes(logger, httpClient, esURL, *esAllNodes, *esNode, *esNodeResolve, *esClusterInfoInterval, *esNodeBalanceAttribute))
+
+	if collectors["indices"] && *esIndicesHealthOnly && !collectors["shards"] {
+		registry.MustRegister(collector.NewIndicesHealth(logger, httpClient, esURL, *esIndicesIncludeSystem))
+	} else if collectors["indices"] || collectors["shards"] {
+		iC := collector.NewIndices(logger, httpClient, esURL, collectors["shards"] && !*esShardsAggregate, *esExportIndicesAggregationLabel, *esIndicesLabelMode, *esIndicesTopN, *esIndicesOpenOnly, splitSettingsKeys(*esIndicesSearchGroups), indicesBatchSize(*esIndicesParallelFetch, *esIndicesBatchSize), *esIndicesIncludeSystem, *esIndicesExcludeFrozen)
+		registry.MustRegister(iC)
+		if registerErr := clusterInfoRetriever.RegisterConsumer(iC); registerErr != nil {
+			_ = level.Error(logger).Log("msg", "failed to register indices collector in cluster info")
+			return errors.New("failed to register indices collector in cluster info")
 		}
+	}
 
-		gatherers := prometheus.Gatherers{
-			prometheus.DefaultGatherer,
-			registry,
+	if collectors["snapshots"] {
+		registry.MustRegister(collector.NewSnapshots(logger, httpClient, esURL))
+	}
+
+	if collectors["cluster_settings"] {
+		registry.MustRegister(collector.NewClusterSettings(logger, httpClient, esURL))
+	}
+
+	if collectors["indices_settings"] {
+		registry.MustRegister(collector.NewIndicesSettings(logger, httpClient, esURL, splitSettingsKeys(*esIndicesSettingsKeys)))
+	}
+
+	if collectors["cluster_stats"] {
+		registry.MustRegister(collector.NewClusterStats(logger, httpClient, esURL))
+	}
+
+	if collectors["cat_allocation"] {
+		registry.MustRegister(collector.NewCatAllocation(logger, httpClient, esURL))
+	}
+
+	if collectors["shards"] {
+		registry.MustRegister(collector.NewShards(logger, httpClient, esURL, *esShardsAggregate))
+	}
+
+	if collectors["cluster_state"] {
+		registry.MustRegister(collector.NewClusterState(logger, httpClient, esURL, *esClusterStateVotingConfig))
+	}
+
+	if templates != nil {
+		registry.MustRegister(templates)
+	}
+
+	if collectors["mappings"] {
+		registry.MustRegister(collector.NewMappings(logger, httpClient, esURL, splitSettingsKeys(*esMappingsIndices)))
+	}
+
+	if collectors["enrich"] {
+		registry.MustRegister(collector.NewEnrich(logger, httpClient, esURL, *esDisableUnavailable))
+	}
+
+	if collectors["license"] {
+		registry.MustRegister(collector.NewLicense(logger, httpClient, esURL, *esDisableUnavailable))
+	}
+
+	if collectors["pending_tasks"] {
+		registry.MustRegister(collector.NewPendingTasks(logger, httpClient, esURL))
+	}
+
+	if collectors["retention"] {
+		registry.MustRegister(collector.NewRetention(logger, httpClient, esURL, splitSettingsKeys(*esRetentionIndices), *esRetentionTimestampField, *esRetentionInterval))
+	}
+
+	if collectors["nodes_usage"] {
+		registry.MustRegister(collector.NewNodesUsage(logger, httpClient, esURL, splitSettingsKeys(*esNodesUsageActions)))
+	}
+
+	if collectors["remote_info"] {
+		registry.MustRegister(collector.NewRemoteInfo(logger, httpClient, esURL))
+	}
+
+	if collectors["security"] {
+		registry.MustRegister(collector.NewSecurity(logger, httpClient, esURL, *esDisableUnavailable))
+	}
+
+	if collectors["async_search"] {
+		registry.MustRegister(collector.NewAsyncSearch(logger, httpClient, esURL))
+	}
+
+	return nil
+}
+
+// indicesBatchSize returns the number of indices whose stats the indices
+// collector fetches with one request, or 0 to fetch all at once
+func indicesBatchSize(parallelFetch bool, batchSize int) int {
+	if !parallelFetch {
+		return 0
+	}
+	return batchSize
+}
+
+// splitSettingsKeys splits a comma separated list of settings keys,
+// ignoring empty entries
+func splitSettingsKeys(list string) []string {
+	var keys []string
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
 		}
-		// Delegate http serving to Prometheus client library, which will call collector.Collect.
-		h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
-		h.ServeHTTP(w, r)
 	}
+	return keys
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewTransport(t *testing.T) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	transport := newTransport(tlsConfig, 42, 7, 30*time.Second, http2Auto)
+
+	if transport.TLSClientConfig != tlsConfig {
+		t.Errorf("Wrong TLS config")
+	}
+	if transport.MaxIdleConns != 42 {
+		t.Errorf("Wrong max idle conns: %d", transport.MaxIdleConns)
+	}
+	if transport.MaxIdleConnsPerHost != 42 {
+		t.Errorf("Wrong max idle conns per host: %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxConnsPerHost != 7 {
+		t.Errorf("Wrong max conns per host: %d", transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("Wrong idle conn timeout: %s", transport.IdleConnTimeout)
+	}
+	if transport.Proxy == nil {
+		t.Errorf("Proxy from environment should be used")
+	}
+}
+
+func TestNewTransportHTTP2(t *testing.T) {
+	for mode, want := range map[string]struct {
+		forceAttempt bool
+		nextProto    bool
+	}{
+		http2Auto:     {false, false},
+		http2Enabled:  {true, false},
+		http2Disabled: {false, true},
+	} {
+		transport := newTransport(nil, 100, 0, 90*time.Second, mode)
+		if transport.ForceAttemptHTTP2 != want.forceAttempt {
+			t.Errorf("[%s] Wrong force attempt HTTP/2: %t", mode, transport.ForceAttemptHTTP2)
+		}
+		if (transport.TLSNextProto != nil) != want.nextProto {
+			t.Errorf("[%s] Wrong TLS next proto: %v", mode, transport.TLSNextProto)
+		}
+		if len(transport.TLSNextProto) != 0 {
+			t.Errorf("[%s] Unexpected TLS next protos: %v", mode, transport.TLSNextProto)
+		}
+	}
+}
+
+func TestNewTransportHTTP2Protocol(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+	ts.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	ts.StartTLS()
+	defer ts.Close()
+
+	for mode, want := range map[string]string{
+		http2Auto:     "HTTP/1.1",
+		http2Enabled:  "HTTP/2.0",
+		http2Disabled: "HTTP/1.1",
+	} {
+		transport := newTransport(&tls.Config{InsecureSkipVerify: true}, 100, 0, 90*time.Second, mode)
+		res, err := (&http.Client{Transport: transport}).Get(ts.URL)
+		if err != nil {
+			t.Errorf("[%s] Failed to send request: %s", mode, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		transport.CloseIdleConnections()
+		if string(body) != want {
+			t.Errorf("[%s] Wrong protocol: got %s, want %s", mode, body, want)
+		}
+	}
+}
+
+// newTestPromHandler returns the metrics handler for esURL with the default
+// collectors and without the optional features
+func newTestPromHandler(ctx context.Context, t *testing.T, esURL string) http.HandlerFunc {
+	seeds, err := parseSeedURIs(esURL, prometheus.NewGauge(prometheus.GaugeOpts{Name: "active_uri_index"}))
+	if err != nil {
+		t.Fatalf("Failed to parse URI: %s", err)
+	}
+	breaker := newCircuitBreaker(0, 0, prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "target_circuit_open"}, []string{"target"}))
+	return newPromHandler(ctx, log.NewNopLogger(), seeds, nil, nil, nil, nil, nil, nil, scrapeFailModePartial, breaker)
+}
+
+func TestPromHandlerClusterNameChanges(t *testing.T) {
+	// the cluster name changes between the first and the second scrape
+	var mu sync.Mutex
+	clusterName := "elasticsearch"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, `{"name":"es01","cluster_name":"%s","cluster_uuid":"r1bT9sBrR7S9-CamE41Qqg","version":{"number":"7.10.0"}}`, clusterName)
+			clusterName = "other-cluster"
+		case "/_cluster/health":
+			fmt.Fprintln(w, `{"cluster_name":"elasticsearch","status":"green","number_of_nodes":1}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := newTestPromHandler(ctx, t, ts.URL)
+
+	var body string
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Scrape %d failed with status %d: %s", i, w.Code, w.Body)
+		}
+		body = w.Body.String()
+	}
+	if want := fmt.Sprintf(`elasticsearch_clusterinfo_cluster_name_changes_total{url="%s"} 1`, ts.URL); !strings.Contains(body, want) {
+		t.Errorf("Missing %s in the second scrape:\n%s", want, body)
+	}
+}
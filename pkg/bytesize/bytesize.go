@@ -0,0 +1,52 @@
+package bytesize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// units are the suffixes of the byte sizes Elasticsearch accepts and
+// reports, e.g. in settings like 40mb. The ib variants are accepted as well.
+// Longer suffixes come first, so that kb isn't taken for b.
+var units = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40}, {"pib", 1 << 50},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"tb", 1 << 40}, {"pb", 1 << 50},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}, {"t", 1 << 40}, {"p", 1 << 50},
+	{"b", 1},
+}
+
+// Parse converts a human-readable byte size of Elasticsearch (e.g. 40mb or
+// 1.5gb) to bytes. Like Elasticsearch, the units are powers of 1024 and the
+// decimal separator is always a dot, regardless of the locale. A size without
+// a unit is taken as bytes.
+func Parse(size string) (float64, error) {
+	s := strings.ToLower(strings.TrimSpace(size))
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSuffix(s, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	s = strings.TrimSpace(s)
+	// ParseFloat accepts more than decimal numbers, e.g. inf, 0x10 or 1_000
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' && c != 'e' {
+			return 0, fmt.Errorf("failed to parse byte size %q: invalid character %q", size, c)
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse byte size %q: %s", size, err)
+	}
+	if math.IsInf(v*multiplier, 0) {
+		return 0, fmt.Errorf("failed to parse byte size %q: out of range", size)
+	}
+	return v * multiplier, nil
+}
@@ -0,0 +1,68 @@
+package bytesize
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for size, want := range map[string]float64{
+		"0":       0,
+		"-1":      -1,
+		"512":     512,
+		"512b":    512,
+		"1kb":     1 << 10,
+		"1kib":    1 << 10,
+		"1k":      1 << 10,
+		"40mb":    40 << 20,
+		"40mib":   40 << 20,
+		"40m":     40 << 20,
+		"1.5gb":   1.5 * (1 << 30),
+		"1.5gib":  1.5 * (1 << 30),
+		"2g":      2 << 30,
+		"3tb":     3 << 40,
+		"3tib":    3 << 40,
+		"3t":      3 << 40,
+		"1pb":     1 << 50,
+		"1pib":    1 << 50,
+		"1p":      1 << 50,
+		"0.5kb":   512,
+		".5kb":    512,
+		"1e3b":    1000,
+		"1GB":     1 << 30,
+		"1MiB":    1 << 20,
+		" 20 mb ": 20 << 20,
+	} {
+		got, err := Parse(size)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", size, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Wrong bytes for %q: got %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, size := range []string{
+		"",
+		"mb",
+		"gb ",
+		"1,5gb",
+		"1.2.3mb",
+		"1 000b",
+		"1_000b",
+		"0x10b",
+		"infb",
+		"nan",
+		"12eb",
+		"10%",
+		"1zb",
+		"1e400pb",
+		"unlimited",
+	} {
+		if got, err := Parse(size); err == nil {
+			t.Errorf("Expected an error for %q, got %v", size, got)
+		}
+	}
+}
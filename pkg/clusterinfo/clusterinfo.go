@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -46,8 +47,83 @@ type Retriever struct {
 	sync                  chan struct{}
 	versionMetric         *prometheus.GaugeVec
 	up                    *prometheus.GaugeVec
-	lastUpstreamSuccessTs *prometheus.GaugeVec
-	lastUpstreamErrorTs   *prometheus.GaugeVec
+	lastUpstreamSuccessTs *prometheus.Desc
+	lastUpstreamErrorTs   *prometheus.Desc
+	failures              *prometheus.Desc
+	clusterNameChanges    *prometheus.Desc
+	retrievals            *retrievalTracker
+}
+
+// retrievalStats are the results of the cluster info retrievals of a URL
+type retrievalStats struct {
+	// clusterName is the cluster name of the last successful retrieval
+	clusterName        string
+	clusterNameChanges float64
+	failures           float64
+	// lastSuccess and lastFailure are Unix timestamps, zero if there was none
+	lastSuccess float64
+	lastFailure float64
+}
+
+// retrievalTracker keeps the retrieval stats of every URL across scrapes
+type retrievalTracker struct {
+	mu    sync.Mutex
+	stats map[string]*retrievalStats
+}
+
+// retrievals is shared by all Retrievers, as a new Retriever is created for every scrape
+var retrievals = newRetrievalTracker()
+
+func newRetrievalTracker() *retrievalTracker {
+	return &retrievalTracker{
+		stats: make(map[string]*retrievalStats),
+	}
+}
+
+// entry returns the retrieval stats of url, t.mu must be held
+func (t *retrievalTracker) entry(url string) *retrievalStats {
+	s, ok := t.stats[url]
+	if !ok {
+		s = &retrievalStats{}
+		t.stats[url] = s
+	}
+	return s
+}
+
+// observe records the successful retrieval of clusterName from url at now,
+// counts a change if it differs from the cluster name of the previous
+// successful retrieval and returns the previous one
+func (t *retrievalTracker) observe(url, clusterName string, now time.Time) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.entry(url)
+	s.lastSuccess = float64(now.Unix())
+	previous := s.clusterName
+	if previous != "" && previous != clusterName {
+		s.clusterNameChanges++
+	}
+	s.clusterName = clusterName
+	return previous
+}
+
+// observeFailure records a failed retrieval from url at now
+func (t *retrievalTracker) observeFailure(url string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.entry(url)
+	s.failures++
+	s.lastFailure = float64(now.Unix())
+}
+
+// get returns a copy of the retrieval stats of url, or false if there are none
+func (t *retrievalTracker) get(url string) (retrievalStats, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.stats[url]
+	if !ok {
+		return retrievalStats{}, false
+	}
+	return *s, true
 }
 
 // New creates a new Retriever
@@ -80,51 +156,86 @@ func New(logger log.Logger, client *http.Client, u *url.URL, interval time.Durat
 			},
 			[]string{"url"},
 		),
-		lastUpstreamSuccessTs: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: prometheus.BuildFQName(namespace, subsystem, "last_retrieval_success_ts"),
-				Help: "Timestamp of the last successful cluster info retrieval",
-			},
-			[]string{"url"},
+		lastUpstreamSuccessTs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "last_retrieval_success_ts"),
+			"Timestamp of the last successful cluster info retrieval, the cluster label is stale if it doesn't advance",
+			[]string{"url"}, nil,
 		),
-		lastUpstreamErrorTs: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: prometheus.BuildFQName(namespace, subsystem, "last_retrieval_failure_ts"),
-				Help: "Timestamp of the last failed cluster info retrieval",
-			},
-			[]string{"url"},
+		lastUpstreamErrorTs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "last_retrieval_failure_ts"),
+			"Timestamp of the last failed cluster info retrieval",
+			[]string{"url"}, nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "failures_total"),
+			"Number of failed cluster info retrievals",
+			[]string{"url"}, nil,
 		),
+		clusterNameChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "cluster_name_changes_total"),
+			"Number of times the retrieved cluster name differed from the previous successful retrieval",
+			[]string{"url"}, nil,
+		),
+		retrievals: retrievals,
 	}
 }
 
+// redactedURL returns the url label of the metrics, without credentials
+func (r *Retriever) redactedURL() string {
+	u := *r.url
+	u.User = nil
+	return u.String()
+}
+
 // Describe implements the prometheus.Collector interface
 func (r *Retriever) Describe(ch chan<- *prometheus.Desc) {
 	r.versionMetric.Describe(ch)
 	r.up.Describe(ch)
-	r.lastUpstreamSuccessTs.Describe(ch)
-	r.lastUpstreamErrorTs.Describe(ch)
+	ch <- r.lastUpstreamSuccessTs
+	ch <- r.lastUpstreamErrorTs
+	ch <- r.failures
+	ch <- r.clusterNameChanges
 }
 
 // Collect implements the prometheus.Collector interface
 func (r *Retriever) Collect(ch chan<- prometheus.Metric) {
 	r.versionMetric.Collect(ch)
 	r.up.Collect(ch)
-	r.lastUpstreamSuccessTs.Collect(ch)
-	r.lastUpstreamErrorTs.Collect(ch)
+	url := r.redactedURL()
+	stats, ok := r.retrievals.get(url)
+	if !ok {
+		return
+	}
+	if stats.lastSuccess != 0 {
+		ch <- prometheus.MustNewConstMetric(r.lastUpstreamSuccessTs, prometheus.GaugeValue, stats.lastSuccess, url)
+	}
+	if stats.lastFailure != 0 {
+		ch <- prometheus.MustNewConstMetric(r.lastUpstreamErrorTs, prometheus.GaugeValue, stats.lastFailure, url)
+	}
+	ch <- prometheus.MustNewConstMetric(r.failures, prometheus.CounterValue, stats.failures, url)
+	ch <- prometheus.MustNewConstMetric(r.clusterNameChanges, prometheus.CounterValue, stats.clusterNameChanges, url)
 }
 
 func (r *Retriever) updateMetrics(res *Response) {
-	u := *r.url
-	u.User = nil
-	url := u.String()
+	url := r.redactedURL()
 	_ = level.Debug(r.logger).Log("msg", "updating cluster info metrics")
+	now := time.Now()
 	// scrape failed, response is nil
 	if res == nil {
 		r.up.WithLabelValues(url).Set(0.0)
-		r.lastUpstreamErrorTs.WithLabelValues(url).Set(float64(time.Now().Unix()))
+		r.retrievals.observeFailure(url, now)
 		return
 	}
 	r.up.WithLabelValues(url).Set(1.0)
+	// a changing cluster name usually means the exporter talks to a
+	// different cluster than before, e.g. because of a wrong target
+	if previous := r.retrievals.observe(url, res.ClusterName, now); previous != "" && previous != res.ClusterName {
+		_ = level.Warn(r.logger).Log(
+			"msg", "cluster name changed",
+			"previous", previous,
+			"current", res.ClusterName,
+		)
+	}
 	r.versionMetric.WithLabelValues(
 		res.ClusterName,
 		res.ClusterUUID,
@@ -133,7 +244,6 @@ func (r *Retriever) updateMetrics(res *Response) {
 		res.Version.Number.String(),
 		res.Version.LuceneVersion.String(),
 	)
-	r.lastUpstreamSuccessTs.WithLabelValues(url).Set(float64(time.Now().Unix()))
 }
 
 // Update triggers an external cluster info label update
@@ -151,8 +261,9 @@ func (r *Retriever) RegisterConsumer(c consumer) error {
 }
 
 // Run starts the update loop and periodically queries the / endpoint
-// The update loop is terminated upon ctx cancellation. The call blocks until the first
-// call to the cluster info endpoint was successful
+// The update loop is terminated upon ctx cancellation, which closes the channels of
+// all consumers. The call blocks until the first call to the cluster info endpoint
+// was successful
 func (r *Retriever) Run(ctx context.Context) error {
 	startupComplete := make(chan struct{})
 	// start update routine
@@ -164,6 +275,10 @@ func (r *Retriever) Run(ctx context.Context) error {
 					"msg", "context cancelled, exiting cluster info update loop",
 					"err", ctx.Err(),
 				)
+				// the consumers stop receiving updates, only this loop sends to them
+				for _, consumerCh := range r.consumerChannels {
+					close(*consumerCh)
+				}
 				return
 			case <-r.sync:
 				_ = level.Info(r.logger).Log(
@@ -274,6 +389,10 @@ func (r *Retriever) fetchAndDecodeClusterInfo() (*Response, error) {
 	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		return nil, err
 	}
+	// a JSON null body decodes without error, but would make the consumers panic
+	if response == nil {
+		return nil, errors.New("empty cluster info response")
+	}
 
 	return response, nil
 }
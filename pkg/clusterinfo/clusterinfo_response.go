@@ -16,8 +16,11 @@ type Response struct {
 // VersionInfo is the version info retrievable from the / endpoint, embedded in Response
 type VersionInfo struct {
 	Number        semver.Version `json:"number"`
+	BuildFlavor   string         `json:"build_flavor"`
 	BuildHash     string         `json:"build_hash"`
 	BuildDate     string         `json:"build_date"`
 	BuildSnapshot bool           `json:"build_snapshot"`
 	LuceneVersion semver.Version `json:"lucene_version"`
+	// only reported by forks of Elasticsearch, e.g. opensearch
+	Distribution string `json:"distribution"`
 }
@@ -71,7 +71,10 @@ func newMockConsumer(ctx context.Context, name string, t *testing.T) *mockConsum
 	go func() {
 		for {
 			select {
-			case d := <-mc.ch:
+			case d, ok := <-mc.ch:
+				if !ok {
+					return
+				}
 				mc.data = d
 				t.Logf("consumer %s received data from channel: %+v\n", mc, mc.data)
 			case <-ctx.Done():
@@ -157,6 +160,108 @@ func TestRetriever_fetchAndDecodeClusterInfo(t *testing.T) {
 	}
 }
 
+func TestRetriever_fetchAndDecodeClusterInfoMalformed(t *testing.T) {
+	for name, body := range map[string]string{
+		"html":      `<html><head><title>502 Bad Gateway</title></head><body><h1>502 Bad Gateway</h1></body></html>`,
+		"truncated": `{"name":"test-node-","cluster_name":"test-cluster-1","version":{`,
+		"null":      `null`,
+	} {
+		body := body
+		mockES := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}))
+		defer mockES.Close()
+		u, err := url.Parse(mockES.URL)
+		if err != nil {
+			t.Skipf("internal test error: %s", err)
+		}
+		retriever := New(log.NewNopLogger(), mockES.Client(), u, 0)
+		ci, err := retriever.fetchAndDecodeClusterInfo()
+		if err == nil {
+			t.Errorf("[%s] expected an error, got cluster info %v", name, ci)
+		}
+	}
+}
+
+func TestRetriever_updateMetricsClusterNameChange(t *testing.T) {
+	names := []string{clusterName, clusterName, "test-cluster-2", "test-cluster-2"}
+	var call int
+	mockES := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name":"%s","cluster_name":"%s","cluster_uuid":"%s","version":{"number":"%s"}}`,
+			nodeName, names[call], clusterUUID, versionNumber)
+		call++
+	}))
+	defer mockES.Close()
+	u, err := url.Parse(mockES.URL)
+	if err != nil {
+		t.Fatalf("internal test error: %s", err)
+	}
+	// a new Retriever is created for every scrape
+	tracker := newRetrievalTracker()
+	for range names {
+		retriever := New(log.NewNopLogger(), mockES.Client(), u, 0)
+		retriever.retrievals = tracker
+		res, err := retriever.fetchAndDecodeClusterInfo()
+		if err != nil {
+			t.Fatalf("failed to retrieve cluster info: %s", err)
+		}
+		retriever.updateMetrics(res)
+	}
+	if stats, _ := tracker.get(mockES.URL); stats.clusterNameChanges != 1 {
+		t.Errorf("expected 1 cluster name change, got %v", stats.clusterNameChanges)
+	}
+}
+
+func TestRetriever_updateMetricsFailures(t *testing.T) {
+	var call int
+	mockES := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call++
+		// only the first retrieval succeeds
+		if call > 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"name":"%s","cluster_name":"%s","cluster_uuid":"%s","version":{"number":"%s"}}`,
+			nodeName, clusterName, clusterUUID, versionNumber)
+	}))
+	defer mockES.Close()
+	u, err := url.Parse(mockES.URL)
+	if err != nil {
+		t.Fatalf("internal test error: %s", err)
+	}
+	// a new Retriever is created for every scrape
+	tracker := newRetrievalTracker()
+	update := func() {
+		retriever := New(log.NewNopLogger(), mockES.Client(), u, 0)
+		retriever.retrievals = tracker
+		res, err := retriever.fetchAndDecodeClusterInfo()
+		if err != nil {
+			res = nil
+		}
+		retriever.updateMetrics(res)
+	}
+
+	update()
+	stats, _ := tracker.get(mockES.URL)
+	lastSuccess := stats.lastSuccess
+	if lastSuccess == 0 {
+		t.Fatal("expected the timestamp of the successful retrieval")
+	}
+	for i := 0; i < 3; i++ {
+		update()
+	}
+	stats, _ = tracker.get(mockES.URL)
+	if stats.failures != 3 {
+		t.Errorf("expected 3 failures, got %v", stats.failures)
+	}
+	if stats.lastSuccess != lastSuccess {
+		t.Errorf("expected the timestamp to stay at %v after failures, got %v", lastSuccess, stats.lastSuccess)
+	}
+	if stats.lastFailure == 0 {
+		t.Error("expected the timestamp of the failed retrievals")
+	}
+}
+
 func TestRetriever_Run(t *testing.T) {
 	// setup mock ES
 	mockES := httptest.NewServer(mockES{})
@@ -194,3 +299,49 @@ func TestRetriever_Run(t *testing.T) {
 	default:
 	}
 }
+
+func TestRetriever_RunClosesConsumers(t *testing.T) {
+	mockES := httptest.NewServer(mockES{})
+	defer mockES.Close()
+	u, err := url.Parse(mockES.URL)
+	if err != nil {
+		t.Fatalf("internal test error: %s", err)
+	}
+	retriever := New(log.NewNopLogger(), mockES.Client(), u, 0)
+	ch := make(chan *Response)
+	if err := retriever.RegisterConsumer(&channelConsumer{ch: ch}); err != nil {
+		t.Fatalf("failed to register consumer: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// receive the initial cluster info, then stop the retriever
+		<-ch
+		cancel()
+	}()
+	if err := retriever.Run(ctx); err != nil {
+		t.Fatalf("failed to run retriever: %s", err)
+	}
+
+	// the consumers of a Retriever created per scrape mustn't wait forever
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("unexpected cluster info after the context was cancelled")
+		}
+	case <-time.After(time.Second):
+		t.Error("consumer channel not closed after the context was cancelled")
+	}
+}
+
+type channelConsumer struct {
+	ch chan *Response
+}
+
+func (c *channelConsumer) String() string {
+	return "channel-consumer"
+}
+
+func (c *channelConsumer) ClusterLabelUpdates() *chan *Response {
+	return &c.ch
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// pprofPath is the prefix the profiling handlers are served under
+const pprofPath = "/debug/pprof/"
+
+// registerPprof registers the net/http/pprof handlers on mux. Importing
+// net/http/pprof also registers them on http.DefaultServeMux, which therefore
+// isn't used to serve the exporter.
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc(pprofPath, pprof.Index)
+	mux.HandleFunc(pprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(pprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(pprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(pprofPath+"trace", pprof.Trace)
+}
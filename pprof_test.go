@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterPprof(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		mux := http.NewServeMux()
+		if enabled {
+			registerPprof(mux)
+		}
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/symbol", "/debug/pprof/goroutine"} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			want := http.StatusNotFound
+			if enabled {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Errorf("Wrong status of %s with pprof enabled=%t: got %d, want %d", path, enabled, w.Code, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/blang/semver"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/justwatchcom/elasticsearch_exporter/pkg/clusterinfo"
+)
+
+// minSupportedVersion is the oldest Elasticsearch version whose APIs the
+// collectors are known to work with
+var minSupportedVersion = semver.MustParse("5.0.0")
+
+// preflight checks on startup that Elasticsearch is reachable at u and logs its
+// version, with a warning if the version isn't supported
+func preflight(logger log.Logger, client *http.Client, u *url.URL) error {
+	info := *u
+	info.Path = path.Join(u.Path, "/")
+	res, err := client.Get(info.String())
+	if err != nil {
+		return fmt.Errorf("failed to reach Elasticsearch: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	var ci clusterinfo.Response
+	if err := json.NewDecoder(res.Body).Decode(&ci); err != nil {
+		return fmt.Errorf("failed to decode cluster info: %s", err)
+	}
+
+	_ = level.Info(logger).Log(
+		"msg", "preflight check succeeded",
+		"cluster", ci.ClusterName,
+		"distribution", distribution(&ci),
+		"build_flavor", ci.Version.BuildFlavor,
+		"version", ci.Version.Number.String(),
+	)
+	if ci.Version.Number.LT(minSupportedVersion) {
+		_ = level.Warn(logger).Log(
+			"msg", "Elasticsearch version is not supported, some metrics may be missing",
+			"version", ci.Version.Number.String(),
+			"min_version", minSupportedVersion.String(),
+		)
+	}
+	return nil
+}
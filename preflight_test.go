@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestPreflight(t *testing.T) {
+	// Testcases created using:
+	//  docker run -d -p 9200:9200 elasticsearch:VERSION
+	//  curl http://localhost:9200/
+	tcs := map[string]struct {
+		out     string
+		warning bool
+	}{
+		"7.6.2": {`{"name":"es01","cluster_name":"elasticsearch","cluster_uuid":"3qps7bcWTqyzV49ApmPVfw","version":{"number":"7.6.2","build_flavor":"default","build_type":"docker","build_hash":"ef48eb35cf30adf4db14086e8aabd07ef6fb113f","build_date":"2020-03-26T06:34:37.794943Z","build_snapshot":false,"lucene_version":"8.4.0","minimum_wire_compatibility_version":"6.8.0","minimum_index_compatibility_version":"6.0.0-beta1"},"tagline":"You Know, for Search"}`, false},
+		"2.4.5": {`{"name":"Lady Killer","cluster_name":"elasticsearch","cluster_uuid":"4ETvM3FbQT2C3Uu4aO8AUw","version":{"number":"2.4.5","build_hash":"c849dd13904f53e63e88efc33b2ceeda0b6a1276","build_timestamp":"2017-04-24T16:18:17Z","build_snapshot":false,"lucene_version":"5.5.4"},"tagline":"You Know, for Search"}`, true},
+	}
+	for ver, tc := range tcs {
+		out := tc.out
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, out)
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %s", err)
+		}
+		var buf bytes.Buffer
+		if err := preflight(log.NewLogfmtLogger(&buf), http.DefaultClient, u); err != nil {
+			t.Fatalf("[%s] Preflight check failed: %s", ver, err)
+		}
+		if !strings.Contains(buf.String(), "version="+ver) {
+			t.Errorf("[%s] The version wasn't logged: %s", ver, buf.String())
+		}
+		if warning := strings.Contains(buf.String(), "min_version=5.0.0"); warning != tc.warning {
+			t.Errorf("[%s] Expected a version warning %t, got: %s", ver, tc.warning, buf.String())
+		}
+	}
+
+	// unreachable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	ts.Close()
+	if err := preflight(log.NewNopLogger(), http.DefaultClient, u); err == nil {
+		t.Errorf("Preflight check of an unreachable URI should fail")
+	}
+}
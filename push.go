@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// newPusher creates a pusher replacing the metrics of job and the grouping
+// labels on the Pushgateway at gatewayURL with the metrics gathered from g
+func newPusher(gatewayURL, job string, grouping map[string]string, g prometheus.Gatherer) *push.Pusher {
+	pusher := push.New(gatewayURL, job).Gatherer(g)
+	for name, value := range grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	return pusher
+}
+
+// runPusher pushes metrics immediately and then every interval until ctx is cancelled
+func runPusher(ctx context.Context, logger log.Logger, pusher *push.Pusher, interval time.Duration) {
+	pushMetrics(logger, pusher)
+
+	if interval <= 0 {
+		_ = level.Info(logger).Log(
+			"msg", "no periodic push to the pushgateway requested",
+		)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = level.Info(logger).Log(
+				"msg", "context cancelled, exiting pushgateway loop",
+				"err", ctx.Err(),
+			)
+			return
+		case <-ticker.C:
+			pushMetrics(logger, pusher)
+		}
+	}
+}
+
+func pushMetrics(logger log.Logger, pusher *push.Pusher) {
+	_ = level.Debug(logger).Log("msg", "pushing metrics to the pushgateway")
+	if err := pusher.Push(); err != nil {
+		_ = level.Error(logger).Log(
+			"msg", "failed to push metrics to the pushgateway",
+			"err", err,
+		)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPusher(t *testing.T) {
+	pushed := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("Wrong push method: %s", r.Method)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read pushed metrics: %s", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		select {
+		case pushed <- r.URL.Path + "\n" + string(body):
+		default:
+		}
+	}))
+	defer ts.Close()
+
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "elasticsearch_cluster_health_up",
+		Help: "Was the last scrape of the ElasticSearch cluster health endpoint successful.",
+	})
+	up.Set(1)
+	registry.MustRegister(up)
+
+	pusher := newPusher(ts.URL, "elasticsearch", map[string]string{"cluster": "batch-1"}, registry)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runPusher(ctx, log.NewNopLogger(), pusher, time.Hour)
+
+	select {
+	case req := <-pushed:
+		if !strings.HasPrefix(req, "/metrics/job/elasticsearch/cluster/batch-1\n") {
+			t.Errorf("Wrong pushgateway path: %s", req)
+		}
+		if !strings.Contains(req, "elasticsearch_cluster_health_up") {
+			t.Errorf("Pushed metrics are missing elasticsearch_cluster_health_up")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Metrics weren't pushed to the pushgateway")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// maxRedirects is the number of redirects followed per request, like the
+// default of http.Client
+const maxRedirects = 10
+
+// newCheckRedirect returns the CheckRedirect of the client to Elasticsearch.
+// Elasticsearch itself never redirects, so unless follow is true the redirect
+// response of a proxy in between is returned as is and fails the request.
+// Following a redirect to the same scheme, host and port, the credentials of
+// the original request are attached again, as Go drops those of the URL if the
+// location is absolute. A redirect to another origin, including a downgrade
+// from https to http, is sent without any credentials.
+func newCheckRedirect(logger log.Logger, follow bool) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		prev := via[len(via)-1]
+		if !follow {
+			_ = level.Warn(logger).Log(
+				"msg", "not following redirect of Elasticsearch request, see es.follow-redirects",
+				"from", redactURL(prev),
+				"to", redactURL(req),
+			)
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		_ = level.Debug(logger).Log(
+			"msg", "following redirect of Elasticsearch request",
+			"from", redactURL(prev),
+			"to", redactURL(req),
+		)
+
+		first := via[0]
+		if !sameOrigin(req.URL, first.URL) {
+			// Go keeps the Authorization header if only the scheme or port
+			// changes, and the token transport would add it again
+			req.Header.Del("Authorization")
+			*req = *req.WithContext(context.WithValue(req.Context(), crossOriginKey{}, true))
+			return nil
+		}
+		if req.Header.Get("Authorization") != "" {
+			return nil
+		}
+		if auth := first.Header.Get("Authorization"); auth != "" {
+			req.Header.Set("Authorization", auth)
+		} else if u := first.URL.User; u != nil {
+			password, _ := u.Password()
+			req.SetBasicAuth(u.Username(), password)
+		}
+		return nil
+	}
+}
+
+// crossOriginKey marks the context of a request redirected to another origin
+// than the one of the original request
+type crossOriginKey struct{}
+
+// isCrossOriginRedirect returns true if req was redirected to another origin,
+// so it must not be sent with credentials
+func isCrossOriginRedirect(req *http.Request) bool {
+	crossOrigin, _ := req.Context().Value(crossOriginKey{}).(bool)
+	return crossOrigin
+}
+
+// sameOrigin returns true if a and b have the same scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		urlPort(a) == urlPort(b)
+}
+
+// urlPort returns the port of u, or the default port of its scheme
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestCheckRedirect(t *testing.T) {
+	// a proxy redirecting to the path with a trailing slash. The location is
+	// absolute, so it doesn't have the credentials of the URL.
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_cluster/health" {
+			http.Redirect(w, r, ts.URL+"/_cluster/health/", http.StatusMovedPermanently)
+			return
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "elastic" || password != "changeme" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status":"green"}`))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL + "/_cluster/health")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	u.User = url.UserPassword("elastic", "changeme")
+
+	for follow, want := range map[bool]int{
+		false: http.StatusMovedPermanently,
+		true:  http.StatusOK,
+	} {
+		client := &http.Client{CheckRedirect: newCheckRedirect(log.NewNopLogger(), follow)}
+		res, err := client.Get(u.String())
+		if err != nil {
+			t.Fatalf("Failed to send request with follow=%t: %s", follow, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Errorf("Wrong status with follow=%t: got %d, want %d", follow, res.StatusCode, want)
+		}
+	}
+}
+
+func TestCheckRedirectOtherOrigin(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Credentials sent to the redirect target of %s: %q", r.URL.Query().Get("case"), auth)
+		}
+	}))
+	defer target.Close()
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+
+	// the same port on localhost instead of 127.0.0.1 is another host
+	otherHost := httptest.NewServer(http.RedirectHandler("http://localhost:"+targetURL.Port()+"/?case=other+host", http.StatusFound))
+	defer otherHost.Close()
+	// the same host without TLS
+	downgrade := httptest.NewTLSServer(http.RedirectHandler(target.URL+"/?case=downgrade", http.StatusFound))
+	defer downgrade.Close()
+
+	dir, err := ioutil.TempDir("", "token")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("token"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %s", err)
+	}
+
+	for _, origin := range []string{otherHost.URL, downgrade.URL} {
+		for _, token := range []bool{false, true} {
+			u, err := url.Parse(origin)
+			if err != nil {
+				t.Fatalf("Failed to parse URL: %s", err)
+			}
+			// the client of the TLS server trusts its certificate
+			transport := downgrade.Client().Transport
+			if token {
+				transport = newTokenRoundTripper(transport, tokenFile)
+			} else {
+				u.User = url.UserPassword("elastic", "changeme")
+			}
+			client := &http.Client{
+				Transport:     transport,
+				CheckRedirect: newCheckRedirect(log.NewNopLogger(), true),
+			}
+			res, err := client.Get(u.String())
+			if err != nil {
+				t.Fatalf("Failed to send request to %s with token=%t: %s", origin, token, err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("Wrong status of %s with token=%t: got %d, want %d", origin, token, res.StatusCode, http.StatusOK)
+			}
+		}
+	}
+}
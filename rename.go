@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// renamingGatherer wraps a prometheus.Gatherer and renames the metric
+// families by the current and the desired name in renames
+type renamingGatherer struct {
+	gatherer prometheus.Gatherer
+	renames  map[string]string
+}
+
+func newRenamingGatherer(g prometheus.Gatherer, renames map[string]string) prometheus.Gatherer {
+	if len(renames) == 0 {
+		return g
+	}
+	return &renamingGatherer{
+		gatherer: g,
+		renames:  renames,
+	}
+}
+
+// Gather implements the prometheus.Gatherer interface. A metric family renamed
+// to the name of a family which isn't renamed keeps its name and fails the
+// gathering, as the exposition can't hold two families of the same name.
+func (g *renamingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := g.gatherer.Gather()
+	kept := make(map[string]bool, len(mfs))
+	for _, mf := range mfs {
+		if _, ok := g.renames[mf.GetName()]; !ok {
+			kept[mf.GetName()] = true
+		}
+	}
+
+	errs := prometheus.MultiError{}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	renamed := make([]*dto.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		name, ok := g.renames[mf.GetName()]
+		if !ok {
+			renamed = append(renamed, mf)
+			continue
+		}
+		if kept[name] {
+			errs = append(errs, fmt.Errorf("metric %s renamed to %s collides with the exported metric of that name", mf.GetName(), name))
+			renamed = append(renamed, mf)
+			continue
+		}
+		// the gathered families may be shared, so they aren't modified
+		copied := *mf
+		copied.Name = proto.String(name)
+		renamed = append(renamed, &copied)
+	}
+	sort.Slice(renamed, func(i, j int) bool {
+		return renamed[i].GetName() < renamed[j].GetName()
+	})
+	return renamed, errs.MaybeUnwrap()
+}
+
+// loadMetricRenames reads the YAML mapping of current to desired metric names
+// from path. An empty path returns no renames. The desired names must be valid
+// and unique, so no two metrics are renamed to the same name.
+func loadMetricRenames(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric renames: %s", err)
+	}
+	var renames map[string]string
+	if err := yaml.UnmarshalStrict(content, &renames); err != nil {
+		return nil, fmt.Errorf("failed to parse metric renames %s: %s", path, err)
+	}
+
+	currents := make([]string, 0, len(renames))
+	for current := range renames {
+		currents = append(currents, current)
+	}
+	sort.Strings(currents)
+	renamedFrom := make(map[string]string, len(renames))
+	for _, current := range currents {
+		desired := renames[current]
+		if !model.IsValidMetricName(model.LabelValue(desired)) {
+			return nil, fmt.Errorf("invalid metric name %q to rename %s to", desired, current)
+		}
+		if other, ok := renamedFrom[desired]; ok {
+			return nil, fmt.Errorf("metrics %s and %s are both renamed to %s", other, current, desired)
+		}
+		renamedFrom[desired] = current
+	}
+	return renames, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRenamingGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{
+		"elasticsearch_cluster_health_number_of_nodes",
+		"elasticsearch_cluster_health_up",
+		"elasticsearch_jvm_memory_used_bytes",
+	} {
+		registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: name,
+			Help: name,
+		}))
+	}
+
+	renames := map[string]string{
+		"elasticsearch_cluster_health_number_of_nodes": "es_cluster_nodes",
+		"elasticsearch_os_load1":                       "es_os_load1",
+	}
+	want := `# HELP elasticsearch_cluster_health_up elasticsearch_cluster_health_up
+# TYPE elasticsearch_cluster_health_up gauge
+elasticsearch_cluster_health_up 0
+# HELP elasticsearch_jvm_memory_used_bytes elasticsearch_jvm_memory_used_bytes
+# TYPE elasticsearch_jvm_memory_used_bytes gauge
+elasticsearch_jvm_memory_used_bytes 0
+# HELP es_cluster_nodes elasticsearch_cluster_health_number_of_nodes
+# TYPE es_cluster_nodes gauge
+es_cluster_nodes 0
+`
+	// the up metric reports a failure, which doesn't matter in partial mode
+	rec := httptest.NewRecorder()
+	serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil), log.NewNopLogger(), registry, nil, nil, renames, scrapeFailModePartial)
+	body, err := ioutil.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %s", err)
+	}
+	if string(body) != want {
+		t.Errorf("Unexpected renamed metrics:\n%s", body)
+	}
+
+	// the gathered metrics keep their names
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %s", err)
+	}
+	if mfs[0].GetName() != "elasticsearch_cluster_health_number_of_nodes" {
+		t.Errorf("Gathered metric renamed to %s", mfs[0].GetName())
+	}
+
+	// a metric renamed to the name of another one isn't renamed
+	_, err = newRenamingGatherer(registry, map[string]string{
+		"elasticsearch_cluster_health_number_of_nodes": "elasticsearch_jvm_memory_used_bytes",
+	}).Gather()
+	if err == nil || !strings.Contains(err.Error(), "collides") {
+		t.Errorf("Expected a collision error, got %v", err)
+	}
+}
+
+func TestLoadMetricRenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rename")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tcs := map[string]struct {
+		content string
+		renames map[string]string
+		err     string
+	}{
+		"valid": {
+			content: "elasticsearch_cluster_health_number_of_nodes: es_cluster_nodes\nelasticsearch_os_load1: es_os_load1\n",
+			renames: map[string]string{
+				"elasticsearch_cluster_health_number_of_nodes": "es_cluster_nodes",
+				"elasticsearch_os_load1":                       "es_os_load1",
+			},
+		},
+		"collision": {
+			content: "elasticsearch_os_load1: es_load\nelasticsearch_os_load5: es_load\n",
+			err:     "metrics elasticsearch_os_load1 and elasticsearch_os_load5 are both renamed to es_load",
+		},
+		"invalid name": {
+			content: "elasticsearch_os_load1: es-load\n",
+			err:     `invalid metric name "es-load"`,
+		},
+		"duplicate key": {
+			content: "elasticsearch_os_load1: es_load1\nelasticsearch_os_load1: es_load\n",
+			err:     "already set",
+		},
+		"not a mapping": {
+			content: "- elasticsearch_os_load1\n",
+			err:     "failed to parse",
+		},
+	}
+	for name, tc := range tcs {
+		path := filepath.Join(dir, "renames.yml")
+		if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+			t.Fatalf("Failed to write rename file: %s", err)
+		}
+		renames, err := loadMetricRenames(path)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("[%s] Expected error %q, got %v", name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] Failed to load renames: %s", name, err)
+			continue
+		}
+		if len(renames) != len(tc.renames) {
+			t.Errorf("[%s] Wrong number of renames: %d", name, len(renames))
+		}
+		for current, desired := range tc.renames {
+			if renames[current] != desired {
+				t.Errorf("[%s] Wrong rename of %s: got %q, want %q", name, current, renames[current], desired)
+			}
+		}
+	}
+
+	if renames, err := loadMetricRenames(""); err != nil || renames != nil {
+		t.Errorf("No rename file should rename nothing")
+	}
+	if _, err := loadMetricRenames(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Errorf("Missing rename file should fail")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// responseLoggingRoundTripper logs the status and the (truncated) body of
+// every response from Elasticsearch at debug level
+type responseLoggingRoundTripper struct {
+	next     http.RoundTripper
+	logger   log.Logger
+	maxBytes int
+}
+
+func newResponseLoggingRoundTripper(next http.RoundTripper, logger log.Logger, maxBytes int) http.RoundTripper {
+	return &responseLoggingRoundTripper{
+		next:     next,
+		logger:   logger,
+		maxBytes: maxBytes,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *responseLoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	// the body is logged once the caller closed it, it's still streamed to
+	// the caller and only up to maxBytes are kept
+	res.Body = &loggingBody{
+		ReadCloser: res.Body,
+		logger:     rt.logger,
+		url:        redactURL(req),
+		status:     res.StatusCode,
+		maxBytes:   rt.maxBytes,
+	}
+	return res, nil
+}
+
+// loggingBody keeps the first maxBytes of a response body read by the caller
+// and logs them once it's closed
+type loggingBody struct {
+	io.ReadCloser
+	logger   log.Logger
+	url      string
+	status   int
+	maxBytes int
+
+	logged bytes.Buffer
+	read   int
+	closed bool
+}
+
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += n
+	keep := n
+	if b.maxBytes > 0 && b.logged.Len()+keep > b.maxBytes {
+		keep = b.maxBytes - b.logged.Len()
+	}
+	b.logged.Write(p[:keep])
+	return n, err
+}
+
+func (b *loggingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.closed {
+		return err
+	}
+	b.closed = true
+	_ = level.Debug(b.logger).Log(
+		"msg", "received response from Elasticsearch",
+		"url", b.url,
+		"status", b.status,
+		"bytes", b.read,
+		"truncated", b.read > b.logged.Len(),
+		"body", b.logged.String(),
+	)
+	return err
+}
+
+// redactURL returns the request URL without credentials
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestResponseLoggingRoundTripper(t *testing.T) {
+	body := `{"cluster_name":"elasticsearch","status":"green","number_of_nodes":3}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %s", err)
+	}
+	u.User = url.UserPassword("elastic", "s3cr3t")
+	u.Path = "/_cluster/health"
+
+	var buf bytes.Buffer
+	client := &http.Client{
+		Transport: newResponseLoggingRoundTripper(http.DefaultTransport, log.NewLogfmtLogger(&buf), 32),
+	}
+	res, err := client.Get(u.String())
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %s", err)
+	}
+	if string(got) != body {
+		t.Errorf("Body was modified, got %q", got)
+	}
+	// only the first 32 bytes are kept
+	if kept := res.Body.(*loggingBody).logged.Len(); kept != 32 {
+		t.Errorf("Expected 32 bytes of the body to be kept, got %d", kept)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Response logged before the body was closed: %s", buf.String())
+	}
+	res.Body.Close()
+
+	logged := buf.String()
+	if !strings.Contains(logged, `cluster_name`) || !strings.Contains(logged, "status=200") {
+		t.Errorf("Response not logged: %s", logged)
+	}
+	if strings.Contains(logged, "number_of_nodes") {
+		t.Errorf("Body not truncated to 32 bytes: %s", logged)
+	}
+	if !strings.Contains(logged, "truncated=true") {
+		t.Errorf("Truncation not logged: %s", logged)
+	}
+	if strings.Contains(logged, "s3cr3t") || strings.Contains(logged, "elastic@") {
+		t.Errorf("Credentials not redacted: %s", logged)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// scrapeTimeoutHeader is set by Prometheus to the scrape timeout in seconds
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// scrapeTimeoutBuffer is left of the scrape timeout to write the response
+const scrapeTimeoutBuffer = 500 * time.Millisecond
+
+// scrapeDeadline returns the time Prometheus gives up on the scrape r, minus
+// scrapeTimeoutBuffer. It returns false if r has no valid scrape timeout.
+func scrapeDeadline(r *http.Request, now time.Time) (time.Time, bool) {
+	header := r.Header.Get(scrapeTimeoutHeader)
+	if header == "" {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	// a very short scrape timeout isn't shortened any further
+	if timeout > 2*scrapeTimeoutBuffer {
+		timeout -= scrapeTimeoutBuffer
+	}
+	return now.Add(timeout), true
+}
+
+// deadlineRoundTripper cancels every request to Elasticsearch which isn't
+// done by the deadline of the scrape. It replaces the es.timeout of the HTTP
+// client, which only applies to scrapes without a scrape timeout.
+type deadlineRoundTripper struct {
+	next     http.RoundTripper
+	deadline time.Time
+}
+
+func newDeadlineRoundTripper(next http.RoundTripper, deadline time.Time) http.RoundTripper {
+	return &deadlineRoundTripper{
+		next:     next,
+		deadline: deadline,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *deadlineRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithDeadline(req.Context(), rt.deadline)
+	res, err := rt.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the body is read after RoundTrip returned, so the context is only
+	// cancelled once it's closed
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
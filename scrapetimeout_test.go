@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrapeDeadline(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for header, want := range map[string]time.Duration{
+		"10":    9500 * time.Millisecond,
+		"2.5":   2 * time.Second,
+		"0.8":   800 * time.Millisecond,
+		"":      0,
+		"0":     0,
+		"-1":    0,
+		"10s":   0,
+		"never": 0,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			r.Header.Set(scrapeTimeoutHeader, header)
+		}
+		deadline, ok := scrapeDeadline(r, now)
+		if want == 0 {
+			if ok {
+				t.Errorf("Unexpected deadline for %q: %s", header, deadline)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("Missing deadline for %q", header)
+			continue
+		}
+		if got := deadline.Sub(now); got != want {
+			t.Errorf("Wrong timeout for %q: got %s, want %s", header, got, want)
+		}
+	}
+}
+
+type contextRoundTripper struct {
+	ctx context.Context
+}
+
+func (rt *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.ctx = req.Context()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(`{}`)),
+	}, nil
+}
+
+func TestDeadlineRoundTripper(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	next := &contextRoundTripper{}
+	client := &http.Client{Transport: newDeadlineRoundTripper(next, deadline)}
+
+	res, err := client.Get("http://localhost:9200/_cluster/health")
+	if err != nil {
+		t.Fatalf("Failed to send request: %s", err)
+	}
+	if got, ok := next.ctx.Deadline(); !ok || !got.Equal(deadline) {
+		t.Errorf("Wrong request deadline: got %s, want %s", got, deadline)
+	}
+	if err := next.ctx.Err(); err != nil {
+		t.Errorf("Request cancelled before the body was closed: %s", err)
+	}
+	res.Body.Close()
+	if err := next.ctx.Err(); err != context.Canceled {
+		t.Errorf("Request not cancelled after the body was closed: %v", err)
+	}
+}
+
+func TestDeadlineRoundTripperExceeded(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	// the scrape deadline applies even though the client timeout is longer
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: newDeadlineRoundTripper(http.DefaultTransport, time.Now().Add(100*time.Millisecond)),
+	}
+	start := time.Now()
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Fatalf("Request past the scrape deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Request cancelled only after %s", elapsed)
+	}
+}
+
+func TestPromHandlerScrapeTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintln(w, `{"name":"es01","cluster_name":"elasticsearch","cluster_uuid":"r1bT9sBrR7S9-CamE41Qqg","version":{"number":"7.10.0"}}`)
+		case "/_cluster/health":
+			// slower than es.timeout, but within the scrape timeout
+			time.Sleep(300 * time.Millisecond)
+			fmt.Fprintln(w, `{"cluster_name":"elasticsearch","status":"green","number_of_nodes":1}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	timeout := *esTimeout
+	defer func() { *esTimeout = timeout }()
+	*esTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler := newTestPromHandler(ctx, t, ts.URL)
+	for header, want := range map[string]string{
+		"":   "elasticsearch_cluster_health_up 0",
+		"10": "elasticsearch_cluster_health_up 1",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			r.Header.Set(scrapeTimeoutHeader, header)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Missing %q with scrape timeout %q:\n%s", want, header, w.Body)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// shutdownServer stops the http server from accepting new scrapes and waits
+// up to timeout for the in-flight scrapes to finish. Only then it cancels the
+// context of the collectors, which would abort the requests to Elasticsearch
+// of the in-flight scrapes.
+func shutdownServer(logger log.Logger, server *http.Server, timeout time.Duration, cancel context.CancelFunc) {
+	defer cancel()
+
+	// the timeout starts with the shutdown, not with the exporter
+	ctx, ctxCancel := context.WithTimeout(context.Background(), timeout)
+	defer ctxCancel()
+	if err := server.Shutdown(ctx); err != nil {
+		_ = level.Warn(logger).Log(
+			"msg", "failed to wait for the in-flight scrapes",
+			"timeout", timeout.String(),
+			"err", err,
+		)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestShutdownServer(t *testing.T) {
+	for name, tc := range map[string]struct {
+		scrape   time.Duration
+		timeout  time.Duration
+		drained  bool
+		finished bool
+	}{
+		"in-flight scrape finishes": {200 * time.Millisecond, 5 * time.Second, true, true},
+		"timeout exceeded":          {5 * time.Second, 100 * time.Millisecond, false, false},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		scraped := make(chan bool, 1)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			// a slow scrape, which is aborted by the context of the collectors
+			select {
+			case <-time.After(tc.scrape):
+				scraped <- true
+			case <-ctx.Done():
+				scraped <- false
+			}
+		})}
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %s", err)
+		}
+		go func() {
+			_ = server.Serve(l)
+		}()
+
+		responded := make(chan error, 1)
+		go func() {
+			res, err := http.Get("http://" + l.Addr().String() + "/metrics")
+			if err == nil {
+				res.Body.Close()
+			}
+			responded <- err
+		}()
+		<-started
+
+		shutdownServer(log.NewNopLogger(), server, tc.timeout, cancel)
+		if ctx.Err() == nil {
+			t.Errorf("[%s] Context of the collectors not cancelled after the shutdown", name)
+		}
+		if finished := <-scraped; finished != tc.finished {
+			t.Errorf("[%s] Expected the in-flight scrape to finish %v, got %v", name, tc.finished, finished)
+		}
+		if tc.drained {
+			if err := <-responded; err != nil {
+				t.Errorf("[%s] In-flight scrape failed: %s", name, err)
+			}
+		}
+		server.Close()
+	}
+}
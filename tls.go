@@ -3,8 +3,10 @@ package main
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"io/ioutil"
 	"log"
+	"net/http"
 )
 
 func createTLSConfig(pemFile, pemCertFile, pemPrivateKeyFile string, insecureSkipVerify bool) *tls.Config {
@@ -49,3 +51,34 @@ func loadPrivateKeyFrom(pemCertFile, pemPrivateKeyFile string) (*tls.Certificate
 	}
 	return &privateKey, nil
 }
+
+// createServerTLSConfig returns the TLS config of the exporter's own web server.
+// If clientCAFile is set, clients have to present a certificate signed by it.
+func createServerTLSConfig(clientCAFile string) (*tls.Config, error) {
+	tlsConfig := tls.Config{}
+	if len(clientCAFile) > 0 {
+		clientCAs, err := loadCertificatesFrom(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tlsConfig, nil
+}
+
+// listenAndServe serves TLS if a certificate and key are given, plain HTTP otherwise
+func listenAndServe(server *http.Server, certFile, keyFile, clientCAFile string) error {
+	if len(certFile) == 0 && len(keyFile) == 0 {
+		if len(clientCAFile) > 0 {
+			return errors.New("web.tls-client-ca requires web.tls-cert and web.tls-key")
+		}
+		return server.ListenAndServe()
+	}
+	tlsConfig, err := createServerTLSConfig(clientCAFile)
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = tlsConfig
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
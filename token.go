@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// readToken reads a bearer token from a file, ignoring surrounding whitespace
+func readToken(tokenFile string) (string, error) {
+	b, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// checkTokenAuth returns an error if the bearer token of tokenFile is combined
+// with another way to authenticate against Elasticsearch, or can't be read
+func checkTokenAuth(tokenFile string, seeds *seedURIs, clientCert string) error {
+	for _, u := range seeds.uris {
+		if u.User != nil {
+			return errors.New("es.token-file can't be combined with credentials in es.uri")
+		}
+	}
+	if clientCert != "" {
+		return errors.New("es.token-file can't be combined with es.client-cert")
+	}
+	_, err := readToken(tokenFile)
+	return err
+}
+
+// tokenRoundTripper authenticates every request to Elasticsearch with the
+// bearer token of a file. The file is read for every request, so a rotated
+// token is used without a restart. Requests redirected to another origin
+// are sent without the token.
+type tokenRoundTripper struct {
+	next      http.RoundTripper
+	tokenFile string
+}
+
+func newTokenRoundTripper(next http.RoundTripper, tokenFile string) http.RoundTripper {
+	return &tokenRoundTripper{
+		next:      next,
+		tokenFile: tokenFile,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *tokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isCrossOriginRedirect(req) {
+		return rt.next.RoundTrip(req)
+	}
+	token, err := readToken(rt.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bearer token: %s", err)
+	}
+	// a RoundTripper must not modify the request
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return rt.next.RoundTrip(r)
+}
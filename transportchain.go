@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+)
+
+// defaultTransportChain is the order of the transport wrappers before it was
+// configurable, from the outermost one seeing a request first to the
+// innermost one next to the connection to Elasticsearch
+const defaultTransportChain = "token,useragent,concurrency,failover,instrumentation,logging"
+
+// transportWrapper wraps the transport to Elasticsearch, e.g. to add a header
+type transportWrapper func(next http.RoundTripper) http.RoundTripper
+
+// transportWrappers returns the transport wrappers of es.transport-chain by
+// name. Wrappers which aren't configured by their own flags, like token
+// without es.token-file, are nil.
+func transportWrappers(logger log.Logger, seeds *seedURIs) map[string]transportWrapper {
+	userAgent := *esUserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent()
+	}
+
+	wrappers := map[string]transportWrapper{
+		"token": nil,
+		"useragent": func(next http.RoundTripper) http.RoundTripper {
+			return newUserAgentRoundTripper(next, userAgent)
+		},
+		// waiting for a free slot is not part of the instrumented request
+		// duration with the default chain
+		"concurrency": nil,
+		"failover":    seeds.roundTripper,
+		"instrumentation": func(next http.RoundTripper) http.RoundTripper {
+			return newInstrumentedRoundTripper(next, esRequests, esRequestDuration)
+		},
+		"logging": nil,
+	}
+	if *esTokenFile != "" {
+		wrappers["token"] = func(next http.RoundTripper) http.RoundTripper {
+			return newTokenRoundTripper(next, *esTokenFile)
+		}
+	}
+	if *esScrapeConcurrency > 0 {
+		wrappers["concurrency"] = func(next http.RoundTripper) http.RoundTripper {
+			return newConcurrencyLimitRoundTripper(next, *esScrapeConcurrency)
+		}
+	}
+	// the responses are only logged at debug level, so they aren't wrapped otherwise
+	if *esLogResponses && strings.EqualFold(*logLevel, "debug") {
+		wrappers["logging"] = func(next http.RoundTripper) http.RoundTripper {
+			return newResponseLoggingRoundTripper(next, logger, *esLogResponsesMaxBytes)
+		}
+	}
+	return wrappers
+}
+
+// transportWrapperNames returns the sorted names of the transport wrappers
+func transportWrapperNames() []string {
+	var names []string
+	for name := range transportWrappers(nil, &seedURIs{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseTransportChain splits the comma separated names of es.transport-chain.
+// Every name has to be a known transport wrapper and may appear only once,
+// wrappers which aren't named are left out.
+func parseTransportChain(chain string, valid []string) ([]string, error) {
+	known := make(map[string]bool, len(valid))
+	for _, name := range valid {
+		known[name] = true
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(chain, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown transport wrapper %q, valid wrappers are %v", name, valid)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("transport wrapper %q is listed more than once", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// buildTransportChain wraps transport with the wrappers of chain. The first
+// wrapper is the outermost one, which sees a request first and the response
+// last. Nil wrappers are skipped.
+func buildTransportChain(transport http.RoundTripper, chain []string, wrappers map[string]transportWrapper) http.RoundTripper {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if wrap := wrappers[chain[i]]; wrap != nil {
+			transport = wrap(transport)
+		}
+	}
+	return transport
+}
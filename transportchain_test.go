@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// headerWrapper appends its name to the X-Chain header of every request
+func headerWrapper(name string) transportWrapper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			r := req.Clone(req.Context())
+			r.Header.Add("X-Chain", name)
+			return next.RoundTrip(r)
+		})
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBuildTransportChain(t *testing.T) {
+	var chain []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chain = r.Header.Values("X-Chain")
+	}))
+	defer ts.Close()
+
+	wrappers := map[string]transportWrapper{
+		"auth":     headerWrapper("auth"),
+		"headers":  headerWrapper("headers"),
+		"retry":    headerWrapper("retry"),
+		"disabled": nil,
+	}
+	for _, order := range [][]string{
+		{"auth", "headers", "retry"},
+		{"retry", "headers", "auth"},
+		{"headers", "disabled", "auth"},
+	} {
+		client := &http.Client{Transport: buildTransportChain(http.DefaultTransport, order, wrappers)}
+		res, err := client.Get(ts.URL + "/_cluster/health")
+		if err != nil {
+			t.Fatalf("Request failed: %s", err)
+		}
+		res.Body.Close()
+
+		// the outermost wrapper sees the request first and adds its header first
+		var expected []string
+		for _, name := range order {
+			if wrappers[name] != nil {
+				expected = append(expected, name)
+			}
+		}
+		if !reflect.DeepEqual(chain, expected) {
+			t.Errorf("Wrong order of the chain %v: got %v", order, chain)
+		}
+	}
+}
+
+func TestParseTransportChain(t *testing.T) {
+	valid := transportWrapperNames()
+	chain, err := parseTransportChain(defaultTransportChain, valid)
+	if err != nil {
+		t.Fatalf("Failed to parse the default chain: %s", err)
+	}
+	if len(chain) != len(valid) {
+		t.Errorf("The default chain %v doesn't contain all wrappers %v", chain, valid)
+	}
+
+	chain, err = parseTransportChain(" useragent, ,token ", valid)
+	if err != nil {
+		t.Fatalf("Failed to parse chain: %s", err)
+	}
+	if !reflect.DeepEqual(chain, []string{"useragent", "token"}) {
+		t.Errorf("Wrong chain: %v", chain)
+	}
+
+	for chain, msg := range map[string]string{
+		"token,sigv4":              "unknown transport wrapper",
+		"token,useragent,token":    "listed more than once",
+		"instrumentation,retry,gz": "unknown transport wrapper",
+	} {
+		_, err := parseTransportChain(chain, valid)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected an error containing %q for %q, got %v", msg, chain, err)
+		}
+	}
+}
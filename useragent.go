@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/common/version"
+)
+
+// defaultUserAgent identifies the exporter and its version, e.g. in the audit
+// logs of Elasticsearch
+func defaultUserAgent() string {
+	return Name + "/" + version.Version
+}
+
+// userAgentRoundTripper sets the User-Agent header of every request to Elasticsearch
+type userAgentRoundTripper struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func newUserAgentRoundTripper(next http.RoundTripper, userAgent string) http.RoundTripper {
+	return &userAgentRoundTripper{
+		next:      next,
+		userAgent: userAgent,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (rt *userAgentRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	r := req.Clone(req.Context())
+	r.Header.Set("User-Agent", rt.userAgent)
+	return rt.next.RoundTrip(r)
+}
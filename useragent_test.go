@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/common/version"
+)
+
+func TestUserAgentRoundTripper(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer ts.Close()
+
+	version.Version = "1.2.0"
+	for configured, expected := range map[string]string{
+		defaultUserAgent():  "elasticsearch_exporter/1.2.0",
+		"audit-scraper/0.1": "audit-scraper/0.1",
+	} {
+		client := &http.Client{Transport: newUserAgentRoundTripper(http.DefaultTransport, configured)}
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/_cluster/health", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %s", err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %s", err)
+		}
+		res.Body.Close()
+		if userAgent != expected {
+			t.Errorf("Expected User-Agent %q, got %q", expected, userAgent)
+		}
+		if req.Header.Get("User-Agent") != "" {
+			t.Errorf("The original request was modified")
+		}
+	}
+}